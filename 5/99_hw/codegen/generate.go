@@ -0,0 +1,4 @@
+package main
+
+// обработчики для api.go пишутся в api_handlers.go
+//go:generate go run ./handlers_gen
@@ -1,16 +1,24 @@
 // находясь в папке выше
-// go build -o ./codegen.exe gen/* && ./codegen.exe pack/unkack.go  pack/marshaller.go
-// go run pack/*
+// go build -o ./codegen.exe handlers_gen/* && ./codegen.exe api.go api_handlers.go
+// или через go generate (см. generate.go) - тогда обрабатывается весь пакет:
+// для каждого foo.go с аннотациями apigen:api пишется foo_handlers.go
+// go run ./handlers_gen -check - проверить, что сгенерированный код не устарел
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 )
@@ -340,18 +348,174 @@ func (srv *{{.StructName}}) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 `))
 
+// generatorVersion входит в хеш исходника: при изменении шаблонов его нужно
+// увеличить, чтобы все *_handlers.go считались устаревшими.
+const generatorVersion = "2"
+
+const (
+	handlersSuffix = "_handlers.go"
+	hashPrefix     = "// source-sha256: "
+)
+
+var checkMode = flag.Bool("check", false, "не писать файлы, а завершиться с ошибкой, если сгенерированный код устарел")
+
+// job - пара "что парсим" -> "куда пишем"
+type job struct {
+	src string
+	dst string
+}
+
 func main() {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, os.Args[1], nil, parser.ParseComments)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage:\n")
+		fmt.Fprintf(os.Stderr, "\thandlers_gen [-check] api.go api_handlers.go\n")
+		fmt.Fprintf(os.Stderr, "\thandlers_gen [-check] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var jobs []job
+	args := flag.Args()
+	switch {
+	case len(args) == 2:
+		// старый режим запуска: бинарник_кодогенератора что_парсим.го куда_парсим.го
+		jobs = []job{{src: args[0], dst: args[1]}}
+	case len(args) <= 1:
+		// режим go:generate - обходим весь пакет
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		var err error
+		jobs, err = packageJobs(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	stale := 0
+	for _, j := range jobs {
+		changed, err := j.run(*checkMode)
+		if err != nil {
+			log.Fatalf("%s: %v", j.src, err)
+		}
+		if !changed {
+			continue
+		}
+		if *checkMode {
+			fmt.Fprintf(os.Stderr, "%s is stale, run go generate\n", j.dst)
+			stale++
+			continue
+		}
+		fmt.Printf("%s -> %s\n", j.src, j.dst)
+	}
+	if stale > 0 {
+		os.Exit(1)
+	}
+}
+
+// packageJobs ищет в каталоге все исходники с аннотацией apigen:api.
+// Тесты и уже сгенерированные файлы пропускаются.
+func packageJobs(dir string) ([]job, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []job
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") ||
+			strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, handlersSuffix) {
+			continue
+		}
+		src := filepath.Join(dir, name)
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(data, []byte("apigen:api")) || isGenerated(data) {
+			continue
+		}
+		jobs = append(jobs, job{
+			src: src,
+			dst: filepath.Join(dir, strings.TrimSuffix(name, ".go")+handlersSuffix),
+		})
+	}
+	return jobs, nil
+}
+
+// run перегенерирует dst, если хеш исходника не совпадает с записанным в заголовке.
+// Возвращает true, если файл был (или, в режиме check, должен был быть) перезаписан.
+func (j job) run(check bool) (bool, error) {
+	data, err := os.ReadFile(j.src)
+	if err != nil {
+		return false, err
+	}
+	hash := sourceHash(data)
+	if old, err := readHash(j.dst); err == nil && old == hash {
+		return false, nil
+	}
+	if check {
+		return true, nil
+	}
+
+	code, err := generate(filepath.Base(j.src), data, hash)
 	if err != nil {
-		log.Fatal(err)
+		return false, err
+	}
+	return true, os.WriteFile(j.dst, code, 0644)
+}
+
+func sourceHash(src []byte) string {
+	h := sha256.New()
+	h.Write([]byte(generatorVersion))
+	h.Write([]byte{0})
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readHash достаёт хеш исходника из заголовка ранее сгенерированного файла
+func readHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.SplitN(string(data), "\n", 4) {
+		if strings.HasPrefix(line, hashPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, hashPrefix)), nil
+		}
+	}
+	return "", fmt.Errorf("%s: no source hash header", path)
+}
+
+// isGenerated - упрощённая проверка по https://go.dev/s/generatedcode
+func isGenerated(src []byte) bool {
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		if strings.HasPrefix(line, "// Code generated ") && strings.HasSuffix(line, " DO NOT EDIT.") {
+			return true
+		}
 	}
+	return false
+}
 
-	out, err := os.Create(os.Args[2])
+// generate строит код обработчиков для одного файла и прогоняет его через go/format
+func generate(srcName string, src []byte, hash string) ([]byte, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, srcName, src, parser.ParseComments)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "// Code generated by handlers_gen from %s. DO NOT EDIT.\n", srcName)
+	fmt.Fprintln(out, hashPrefix+hash)
+	fmt.Fprintln(out) // пустая строка
 	fmt.Fprintln(out, "package "+node.Name.Name)
 	fmt.Fprintln(out) // пустая строка
 	fmt.Fprintln(out, "import (")
@@ -364,6 +528,8 @@ func main() {
 	fmt.Fprintln(out, ")")
 	fmt.Fprintln(out) // пустая строка
 
+	// структуры в порядке появления в исходнике, чтобы вывод был стабильным
+	var structNames []string
 	apiMethods := make(map[string][]ApiInfo)
 
 	for _, decl := range node.Decls {
@@ -379,7 +545,7 @@ func main() {
 			if strings.HasPrefix(comment.Text, "// apigen:api") {
 				jsonStr := strings.TrimPrefix(comment.Text, "// apigen:api ")
 				if err := json.Unmarshal([]byte(jsonStr), &apiMeta); err != nil {
-					log.Fatalf("Ошибка разбора JSON в %s: %v", funcDecl.Name.Name, err)
+					return nil, fmt.Errorf("ошибка разбора JSON в %s: %w", funcDecl.Name.Name, err)
 				}
 			}
 		}
@@ -398,11 +564,14 @@ func main() {
 
 		// Проверяем, что метод имеет хотя бы 2 аргумента (context и params)
 		if len(funcDecl.Type.Params.List) < 2 {
-			log.Fatalf("Метод %s должен иметь 2 аргумента (context, params)", funcDecl.Name.Name)
+			return nil, fmt.Errorf("метод %s должен иметь 2 аргумента (context, params)", funcDecl.Name.Name)
 		}
 		paramsType := funcDecl.Type.Params.List[1].Type.(*ast.Ident).Name
 		resultType := funcDecl.Type.Results.List[0].Type.(*ast.StarExpr).X.(*ast.Ident).Name
 
+		if _, seen := apiMethods[structName]; !seen {
+			structNames = append(structNames, structName)
+		}
 		apiMethods[structName] = append(apiMethods[structName], ApiInfo{
 			StructName: structName,
 			Method:     funcDecl.Name.Name,
@@ -411,21 +580,27 @@ func main() {
 			ApiMeta:    apiMeta,
 		})
 	}
-	fmt.Printf("type: %T data: %+v\n", apiMethods, apiMethods)
 
 	// Генерируем код обработчиков и ServeHTTP для каждой структуры
-	for structName, methods := range apiMethods {
+	for _, structName := range structNames {
+		methods := apiMethods[structName]
 		for _, method := range methods {
-			handlerTpl.Execute(out, method)
+			if err := handlerTpl.Execute(out, method); err != nil {
+				return nil, err
+			}
 		}
-		serveHTTPTpl.Execute(out, struct {
+		err := serveHTTPTpl.Execute(out, struct {
 			StructName string
 			Methods    []ApiInfo
 		}{structName, methods})
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	fmt.Println("Code generation complete")
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("go/format: %w", err)
+	}
+	return code, nil
 }
-
-// go build gen/* && ./codegen.exe pack/unpack.go  pack/marshaller.go
-// go run pack/*
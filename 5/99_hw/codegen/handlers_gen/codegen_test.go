@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSrc = `package main
+
+import "context"
+
+type DemoApi struct{}
+
+type DemoParams struct {
+	Login string
+}
+
+type DemoResult struct{}
+
+// apigen:api {"url": "/demo", "auth": false}
+func (srv *DemoApi) Demo(ctx context.Context, in DemoParams) (*DemoResult, error) {
+	return &DemoResult{}, nil
+}
+`
+
+func TestPackageRegeneration(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "demo.go")
+	if err := os.WriteFile(src, []byte(testSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// файлы без аннотаций не должны попадать в обработку
+	if err := os.WriteFile(filepath.Join(dir, "other.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := packageJobs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].dst != filepath.Join(dir, "demo_handlers.go") {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+	j := jobs[0]
+
+	if stale, _ := j.run(true); !stale {
+		t.Error("missing output must be reported as stale")
+	}
+	if changed, err := j.run(false); err != nil || !changed {
+		t.Fatalf("first run: changed=%v err=%v", changed, err)
+	}
+	out, err := os.ReadFile(j.dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isGenerated(out) {
+		t.Errorf("output has no generated code header:\n%s", out)
+	}
+
+	// повторный прогон не трогает файл
+	if changed, err := j.run(false); err != nil || changed {
+		t.Errorf("second run: changed=%v err=%v", changed, err)
+	}
+	// после правки исходника check снова видит устаревший код
+	if err := os.WriteFile(src, []byte(testSrc+"\n// changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if stale, _ := j.run(true); !stale {
+		t.Error("changed source must be reported as stale")
+	}
+
+	// сгенерированный файл сам не становится источником
+	jobs, err = packageJobs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Errorf("generated file picked up as input: %+v", jobs)
+	}
+}
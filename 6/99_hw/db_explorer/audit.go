@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// auditTable создаётся самим explorer'ом и в схему не попадает
+	auditTable = "_explorer_audit"
+
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+	maxChangesWait      = time.Minute
+	// раз в столько перечитываем журнал при ожидании: записи могли прийти от другого процесса
+	changesPollInterval = time.Second
+)
+
+// Change - запись журнала изменений
+type Change struct {
+	ID     int64           `json:"id"`
+	Table  string          `json:"table"`
+	PK     string          `json:"pk"`
+	Op     string          `json:"op"` // create, update или delete
+	Caller string          `json:"caller"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	At     string          `json:"at"`
+}
+
+// changeNotifier будит тех, кто ждёт новых записей в `GET /_changes?wait=`
+type changeNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{ch: make(chan struct{})}
+}
+
+// wait возвращает канал, который закроется при следующем изменении
+func (n *changeNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+func (n *changeNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// EnableAudit создаёт таблицу журнала, если её нет, и включает запись в неё
+// всех изменений: вставок, обновлений и удалений.
+func (d *DbExplorer) EnableAudit(ctx context.Context) error {
+	if _, err := d.db.ExecContext(ctx, d.dialect.AuditTableSQL(auditTable)); err != nil {
+		return fmt.Errorf("create audit table: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.audit = true
+	return nil
+}
+
+func (d *DbExplorer) auditEnabled() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.audit
+}
+
+// rowImage - текущее состояние записи для журнала, nil если записи нет или журнал выключен
+func (d *DbExplorer) rowImage(ctx context.Context, q querier, tableMeta Table, pk string, id interface{}) (map[string]interface{}, error) {
+	if !d.auditEnabled() {
+		return nil, nil
+	}
+	args := &sqlArgs{dialect: d.dialect}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = %s",
+		d.dialect.QuoteIdent(tableMeta.Name), d.dialect.QuoteIdent(pk), args.add(id))
+	rows, err := q.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	records, err := readRecords(rows, tableMeta)
+	rows.Close()
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// recordChange пишет изменение в журнал через q - в той же транзакции, что и само изменение
+func (d *DbExplorer) recordChange(ctx context.Context, q querier, table, op string, id interface{}, before, after map[string]interface{}) error {
+	if !d.auditEnabled() {
+		return nil
+	}
+	beforeData, err := auditImage(before)
+	if err != nil {
+		return err
+	}
+	afterData, err := auditImage(after)
+	if err != nil {
+		return err
+	}
+
+	args := &sqlArgs{dialect: d.dialect}
+	query := fmt.Sprintf("INSERT INTO %s (table_name, pk, op, caller, before_data, after_data, created_at) VALUES (%s, %s, %s, %s, %s, %s, %s)",
+		d.dialect.QuoteIdent(auditTable),
+		args.add(table), args.add(fmt.Sprint(id)), args.add(op), args.add(callerFrom(ctx).identity()),
+		args.add(beforeData), args.add(afterData), args.add(time.Now().UTC().Format(time.RFC3339Nano)))
+	_, err = q.ExecContext(ctx, query, args.values...)
+	return err
+}
+
+func auditImage(rec map[string]interface{}) (interface{}, error) {
+	if rec == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// handleChanges обрабатывает `GET /_changes?since=<id>&table=&limit=&wait=30s`.
+// Без wait сразу отдаёт, что есть; с wait, если новых записей нет, ждёт их до указанного времени.
+// Клиент передаёт в since последний полученный last_id.
+//
+// Ограничение курсора: id записи выдаётся при вставке, а видна она становится при коммите.
+// Если транзакция с меньшим id закоммитится позже транзакции с большим, а клиент успеет
+// прочитать вторую, то первая окажется до его since и в ленту не попадёт. Журнал пишется
+// в той же транзакции, что и изменение, поэтому так бывает при параллельных записях в базу
+// из нескольких запросов или процессов. Клиентам, которым нужна полнота, стоит время от времени
+// перечитывать хвост журнала с since, отступив на несколько записей назад, и отбрасывать
+// уже виденные id.
+func (d *DbExplorer) handleChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorResponse(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+	if !d.auditEnabled() {
+		errorResponse(w, "audit is disabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	var since int64
+	if s := q.Get("since"); s != "" {
+		var err error
+		if since, err = strconv.ParseInt(s, 10, 64); err != nil || since < 0 {
+			errorResponse(w, "since must be a change id", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := defaultChangesLimit
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > maxChangesLimit {
+		limit = maxChangesLimit
+	}
+	var wait time.Duration
+	if s := q.Get("wait"); s != "" {
+		var err error
+		if wait, err = time.ParseDuration(s); err != nil || wait < 0 {
+			errorResponse(w, "wait must be a duration like 30s", http.StatusBadRequest)
+			return
+		}
+	}
+	if wait > maxChangesWait {
+		wait = maxChangesWait
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	ticker := time.NewTicker(changesPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// канал берём до запроса, чтобы не пропустить изменение между запросом и ожиданием
+		notified := d.changes.wait()
+		changes, err := d.readChanges(r.Context(), since, q.Get("table"), limit)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if len(changes) > 0 || wait == 0 {
+			writeChanges(w, changes, since)
+			return
+		}
+		select {
+		case <-notified:
+		case <-ticker.C:
+		case <-ctx.Done():
+			writeChanges(w, changes, since)
+			return
+		}
+	}
+}
+
+func writeChanges(w http.ResponseWriter, changes []Change, since int64) {
+	lastID := since
+	if len(changes) > 0 {
+		lastID = changes[len(changes)-1].ID
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	jsonResponse(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"changes": changes,
+			"last_id": lastID,
+		},
+	})
+}
+
+func (d *DbExplorer) readChanges(ctx context.Context, since int64, table string, limit int) ([]Change, error) {
+	args := &sqlArgs{dialect: d.dialect}
+	query := fmt.Sprintf("SELECT id, table_name, pk, op, caller, before_data, after_data, created_at FROM %s WHERE id > %s",
+		d.dialect.QuoteIdent(auditTable), args.add(since))
+	if table != "" {
+		query += " AND table_name = " + args.add(table)
+	}
+	query += fmt.Sprintf(" ORDER BY id LIMIT %d", limit)
+
+	rows, err := d.db.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var c Change
+		var before, after sql.NullString
+		if err := rows.Scan(&c.ID, &c.Table, &c.PK, &c.Op, &c.Caller, &before, &after, &c.At); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			c.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			c.After = json.RawMessage(after.String)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// batchOperation - одна операция из `POST /_batch`
+type batchOperation struct {
+	Op    string                 `json:"op"` // create, update или delete
+	Table string                 `json:"table"`
+	ID    interface{}            `json:"id"`
+	Data  map[string]interface{} `json:"data"`
+}
+
+// batchResult - итог операции: ok, error, rolled_back (откачена из-за ошибки в другой) или skipped
+type batchResult struct {
+	Status   string      `json:"status"`
+	Response interface{} `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// inTx выполняет fn в транзакции: всё или ничего.
+// Все изменения идут через неё, вместе с записями журнала, поэтому после коммита будим ждущих /_changes.
+func (d *DbExplorer) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if d.auditEnabled() {
+		d.changes.notify()
+	}
+	return nil
+}
+
+// handleBatch обрабатывает `POST /_batch` - упорядоченный список операций в одной транзакции
+func (d *DbExplorer) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorResponse(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := decodeJSON(r.Body, &req); err != nil {
+		errorResponse(w, "cant unpack json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 {
+		errorResponse(w, "no operations", http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchResult, len(req.Operations))
+	for i := range results {
+		results[i].Status = "skipped"
+	}
+
+	failed := -1
+	err := d.inTx(r.Context(), func(tx *sql.Tx) error {
+		for i, op := range req.Operations {
+			resp, err := d.runBatchOperation(r.Context(), tx, op)
+			if err != nil {
+				failed = i
+				return err
+			}
+			results[i] = batchResult{Status: "ok", Response: resp}
+		}
+		return nil
+	})
+	if err != nil {
+		status, msg := errorStatus(err)
+		for i := 0; i < failed; i++ {
+			results[i] = batchResult{Status: "rolled_back"}
+		}
+		if failed >= 0 {
+			results[failed] = batchResult{Status: "error", Error: msg}
+			msg = fmt.Sprintf("operation %d: %s", failed, msg)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   msg,
+			"results": results,
+		})
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"results": results,
+		},
+	})
+}
+
+func (d *DbExplorer) runBatchOperation(ctx context.Context, tx *sql.Tx, op batchOperation) (interface{}, error) {
+	tableMeta, ok := d.table(op.Table)
+	if !ok {
+		return nil, &apiError{status: http.StatusNotFound, msg: "unknown table"}
+	}
+
+	switch op.Op {
+	case "create":
+		return d.insertRow(ctx, tx, tableMeta, op.Data)
+	case "update", "delete":
+		id, err := batchID(op.ID)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "delete" {
+			deleted, err := d.deleteRow(ctx, tx, tableMeta, id)
+			return map[string]interface{}{"deleted": deleted}, err
+		}
+		updated, err := d.updateRow(ctx, tx, tableMeta, id, op.Data)
+		return map[string]interface{}{"updated": updated}, err
+	}
+	return nil, badRequest("unknown operation %s", op.Op)
+}
+
+// batchID приводит id из JSON (число или строка) к виду, в котором он приходит в пути
+func batchID(id interface{}) (string, error) {
+	switch v := id.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return "", badRequest("id is required")
+}
+
+// handlePutMany вставляет массив записей `PUT /{table}` одной транзакцией
+func (d *DbExplorer) handlePutMany(w http.ResponseWriter, r *http.Request, tableMeta Table, body []byte) {
+	var records []map[string]interface{}
+	if err := decodeJSON(bytes.NewReader(body), &records); err != nil {
+		errorResponse(w, "cant unpack json", http.StatusBadRequest)
+		return
+	}
+
+	inserted := make([]map[string]interface{}, 0, len(records))
+	failed := -1
+	err := d.inTx(r.Context(), func(tx *sql.Tx) error {
+		for i, data := range records {
+			res, err := d.insertRow(r.Context(), tx, tableMeta, data)
+			if err != nil {
+				failed = i
+				return err
+			}
+			inserted = append(inserted, res)
+		}
+		return nil
+	})
+	if err != nil {
+		status, msg := errorStatus(err)
+		if failed >= 0 {
+			msg = fmt.Sprintf("record %d: %s", failed, msg)
+		}
+		errorResponse(w, msg, status)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"inserted": inserted,
+		},
+	})
+}
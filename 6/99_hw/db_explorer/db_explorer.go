@@ -1,114 +1,126 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
 )
 
 // тут вы пишете код
 // обращаю ваше внимание - в этом задании запрещены глобальные переменные
 
 type Column struct {
-	Name     string
-	Type     string
-	Nullable bool
-	Key      string // например, "PRI" для первичного ключа
-	Extra    string
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Key      string `json:"key,omitempty"` // например, "PRI" для первичного ключа
+	Extra    string `json:"extra,omitempty"`
+	// intBits - разрядность целых, если база задаёт её не по имени типа; 0 - по имени
+	intBits int
 }
 
 type Table struct {
-	Name    string
-	Columns []Column
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+}
+
+func (t Table) hasColumn(name string) bool {
+	_, ok := t.column(name)
+	return ok
+}
+
+func (t Table) column(name string) (Column, bool) {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return Column{}, false
 }
 
 type DbExplorer struct {
-	db     *sql.DB
+	db      *sql.DB
+	dialect Dialect
+
+	// tables целиком подменяется при перечитывании схемы и после этого не меняется,
+	// поэтому полученную под RLock карту можно читать без блокировки
+	mu     sync.RWMutex
 	tables map[string]Table
+	policy *Policy
+
+	// reloadMu - перечитывания схемы идут по одному, иначе более раннее чтение
+	// могло бы закончиться позже и подменить свежую схему устаревшей
+	reloadMu sync.Mutex
+
+	// exportPageSize - сколько строк выбирается за один запрос при выгрузке
+	exportPageSize int
+
+	// audit - писать ли изменения в журнал, включается EnableAudit
+	audit   bool
+	changes *changeNotifier
 }
 
+// NewDbExplorer определяет диалект по драйверу базы
 func NewDbExplorer(db *sql.DB) (*DbExplorer, error) {
-	ctx := context.Background()
-	// Получаем выделенное соединение
-	conn, err := db.Conn(ctx)
+	dialect, err := detectDialect(db)
 	if err != nil {
 		return nil, err
 	}
-	// После завершения инициализации возвращаем соединение в пул
-	defer conn.Close()
+	return NewDbExplorerWithDialect(db, dialect)
+}
 
+func NewDbExplorerWithDialect(db *sql.DB, dialect Dialect) (*DbExplorer, error) {
 	explorer := &DbExplorer{
-		db:     db,
-		tables: make(map[string]Table),
+		db:             db,
+		dialect:        dialect,
+		exportPageSize: defaultExportPageSize,
+		changes:        newChangeNotifier(),
 	}
-
-	// Сначала полностью читаем список таблиц
-	rows, err := conn.QueryContext(ctx, "SHOW TABLES;")
+	tables, err := explorer.loadSchema(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	var tableNames []string
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			rows.Close() // закрываем, если ошибка
-			return nil, err
-		}
-		tableNames = append(tableNames, tableName)
-	}
-	rows.Close() // закрываем результаты запроса
-
-	// Теперь, для каждой таблицы, получаем метаданные, используя то же соединение
-	for _, tableName := range tableNames {
-		columns, err := getTableColumnsConn(conn, tableName)
-		if err != nil {
-			log.Printf("Ошибка получения структуры таблицы %s: %v", tableName, err)
-			continue
-		}
-		explorer.tables[tableName] = Table{
-			Name:    tableName,
-			Columns: columns,
-		}
-	}
-
+	explorer.tables = tables
 	return explorer, nil
 }
 
-func getTableColumnsConn(conn *sql.Conn, tableName string) ([]Column, error) {
-	ctx := context.Background()
-	query := fmt.Sprintf("SHOW FULL COLUMNS FROM `%s`;", tableName)
-	rows, err := conn.QueryContext(ctx, query)
+func (d *DbExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := d.authenticate(r)
 	if err != nil {
-		return nil, err
+		writeError(w, err)
+		return
 	}
-	defer rows.Close()
+	r = r.WithContext(withCaller(r.Context(), c))
 
-	var columns []Column
-	for rows.Next() {
-		var field, colType, nullable, key, extra string
-		var tmp interface{}
-		// SHOW FULL COLUMNS возвращает 9 столбцов: Field, Type, Collation, Null, Key, Default, Extra, Privileges, Comment.
-		if err := rows.Scan(&field, &colType, &tmp, &nullable, &key, &tmp, &extra, &tmp, &tmp); err != nil {
-			return nil, err
+	// служебные эндпоинты начинаются с подчёркивания
+	switch r.URL.Path {
+	case "/_schema", "/_admin/reload", "/_changes":
+		if !c.isAdmin() {
+			errorResponse(w, "access denied", http.StatusForbidden)
+			return
 		}
-		columns = append(columns, Column{
-			Name:     field,
-			Type:     colType,
-			Nullable: nullable == "YES",
-			Key:      key,
-			Extra:    extra,
-		})
-	}
-	return columns, nil
-}
+		switch r.URL.Path {
+		case "/_schema":
+			d.handleSchema(w, r)
+		case "/_admin/reload":
+			d.handleReload(w, r)
+		default:
+			d.handleChanges(w, r)
+		}
+		return
+	case "/_batch":
+		d.handleBatch(w, r)
+		return
+	}
 
-func (d *DbExplorer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		d.handleGet(w, r)
@@ -133,12 +145,16 @@ func splitPath(path string) []string {
 
 func (d *DbExplorer) handleGet(w http.ResponseWriter, r *http.Request) {
 	pathParts := splitPath(r.URL.Path)
+	c := callerFrom(r.Context())
 
 	if len(pathParts) == 0 {
-		// Возвращаем список таблиц
-		tableNames := make([]string, 0, len(d.tables))
-		for name := range d.tables {
-			tableNames = append(tableNames, name)
+		// Возвращаем список таблиц, доступных на чтение
+		tables := d.schema()
+		tableNames := make([]string, 0, len(tables))
+		for name := range tables {
+			if c.canSee(name) {
+				tableNames = append(tableNames, name)
+			}
 		}
 		sort.Strings(tableNames)
 		response := map[string]interface{}{
@@ -153,116 +169,80 @@ func (d *DbExplorer) handleGet(w http.ResponseWriter, r *http.Request) {
 	tableName := pathParts[0]
 
 	// Проверяем, есть ли такая таблица
-	if _, exists := d.tables[tableName]; !exists {
+	if _, exists := d.table(tableName); !exists {
 		// Возвращаем JSON с ошибкой вместо простого текста
 		errorResponse(w, "unknown table", http.StatusNotFound)
 		return
 	}
+	if err := c.allow(tableName, opRead); err != nil {
+		writeError(w, err)
+		return
+	}
 
 	// Если запрашивается конкретная запись `GET /table/id`
 	if len(pathParts) == 2 {
-		d.handleGetRow(w, tableName, pathParts[1])
+		d.handleGetRow(w, r, tableName, pathParts[1])
 		return
 	}
 
-	// Обрабатываем `GET /table?limit=5&offset=0`
+	// Записи другой таблицы, ссылающиеся на эту `GET /users/5/items`
+	if len(pathParts) == 3 {
+		d.handleGetRelated(w, r, tableName, pathParts[1], pathParts[2])
+		return
+	}
+
+	// Обрабатываем `GET /table?limit=5&offset=0&order_by=-id&fields=id,title&id__gt=1`
 	d.handleGetTable(w, r, tableName)
 }
 
 func (d *DbExplorer) handleGetTable(w http.ResponseWriter, r *http.Request, table string) {
-	limit := 5
-	offset := 0
-
-	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil {
-		limit = l
+	tableMeta, _ := d.table(table)
+	view := callerFrom(r.Context()).view(tableMeta)
+	lq, err := parseListQuery(d.dialect, view, r.URL.Query())
+	if err != nil {
+		errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil {
-		offset = o
+	// `GET /table?format=csv` - выгрузка всей таблицы потоком
+	if format := r.URL.Query().Get("format"); format != "" {
+		d.handleExport(w, r, view, lq, format)
+		return
 	}
+	d.writeRecords(w, r, tableMeta, lq)
+}
 
-	query := fmt.Sprintf("SELECT * FROM `%s` LIMIT %d OFFSET %d", table, limit, offset)
-	rows, err := d.db.Query(query)
-	if err != nil {
+// writeRecords выполняет разобранный листинг и отдаёт страницу записей с общим количеством
+func (d *DbExplorer) writeRecords(w http.ResponseWriter, r *http.Request, tableMeta Table, lq *listQuery) {
+	var total int64
+	if err := d.db.QueryRowContext(r.Context(), lq.countSQL(tableMeta.Name), lq.args.values...).Scan(&total); err != nil {
 		errorResponse(w, "failed to query table", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	columns, err := rows.Columns()
+	rows, err := d.db.QueryContext(r.Context(), lq.selectSQL(tableMeta.Name), lq.args.values...)
 	if err != nil {
-		errorResponse(w, "failed to get columns", http.StatusInternalServerError)
+		errorResponse(w, "failed to query table", http.StatusInternalServerError)
 		return
 	}
-
-	columnTypes, err := rows.ColumnTypes()
+	records, err := readRecords(rows, tableMeta)
+	// закрываем до expand, чтобы не держать два соединения сразу
+	rows.Close()
 	if err != nil {
-		errorResponse(w, "failed to get column types", http.StatusInternalServerError)
+		errorResponse(w, "failed to scan row", http.StatusInternalServerError)
 		return
 	}
+	callerFrom(r.Context()).strip(tableMeta.Name, records)
 
-	var records []map[string]interface{}
-
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			errorResponse(w, "failed to scan row", http.StatusInternalServerError)
-			return
-		}
-
-		record := make(map[string]interface{})
-		for i, colName := range columns {
-			val := values[i]
-
-			// Обрабатываем NULL-значения
-			if val == nil {
-				record[colName] = nil
-				continue
-			}
-
-			// Обрабатываем числовые и строковые значения
-			switch columnTypes[i].DatabaseTypeName() {
-			case "INT", "BIGINT", "TINYINT", "SMALLINT", "MEDIUMINT":
-				// Если число, конвертируем в int
-				if b, ok := val.([]byte); ok {
-					num, err := strconv.Atoi(string(b))
-					if err == nil {
-						record[colName] = num
-						continue
-					}
-				}
-			case "FLOAT", "DOUBLE", "DECIMAL":
-				// Если число с плавающей точкой, конвертируем в float64
-				if b, ok := val.([]byte); ok {
-					num, err := strconv.ParseFloat(string(b), 64)
-					if err == nil {
-						record[colName] = num
-						continue
-					}
-				}
-			default:
-				// Оставляем строкой
-				if b, ok := val.([]byte); ok {
-					record[colName] = string(b)
-				} else {
-					record[colName] = val
-				}
-			}
-		}
-
-		records = append(records, record)
+	if err := d.expandRecords(r.Context(), records, lq.expand); err != nil {
+		writeError(w, err)
+		return
 	}
 
 	// Возвращаем JSON в правильном формате
 	response := map[string]interface{}{
 		"response": map[string]interface{}{
 			"records": records,
+			"total":   total,
 		},
 	}
 	jsonResponse(w, response)
@@ -272,29 +252,26 @@ func (d *DbExplorer) handleDelete(w http.ResponseWriter, r *http.Request) {
 	// Ожидаем путь вида /table/id
 	pathParts := splitPath(r.URL.Path)
 	if len(pathParts) != 2 {
-		http.Error(w, "invalid path", http.StatusBadRequest)
+		errorResponse(w, "invalid path", http.StatusBadRequest)
 		return
 	}
 	tableName := pathParts[0]
 	recordID := pathParts[1]
 
 	// Проверяем, существует ли такая таблица
-	if _, exists := d.tables[tableName]; !exists {
+	tableMeta, exists := d.table(tableName)
+	if !exists {
 		errorResponse(w, "unknown table", http.StatusNotFound)
 		return
 	}
 
-	// Формируем и выполняем DELETE-запрос
-	query := fmt.Sprintf("DELETE FROM `%s` WHERE id = ?", tableName)
-	result, err := d.db.Exec(query, recordID)
-	if err != nil {
-		http.Error(w, "database error: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	rowsAffected, err := result.RowsAffected()
+	var rowsAffected int64
+	err := d.inTx(r.Context(), func(tx *sql.Tx) (err error) {
+		rowsAffected, err = d.deleteRow(r.Context(), tx, tableMeta, recordID)
+		return err
+	})
 	if err != nil {
-		http.Error(w, "error retrieving affected rows: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -311,225 +288,86 @@ func (d *DbExplorer) handlePut(w http.ResponseWriter, r *http.Request) {
 	// Извлекаем имя таблицы из пути, например, "/users/" → "users"
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) < 1 {
-		http.Error(w, `{"error": "invalid path"}`, http.StatusBadRequest)
+		errorResponse(w, "invalid path", http.StatusBadRequest)
 		return
 	}
 	table := parts[0]
 
-	tableMeta, ok := d.tables[table]
+	tableMeta, ok := d.table(table)
 	if !ok {
-		http.Error(w, `{"error": "unknown table"}`, http.StatusNotFound)
+		errorResponse(w, "unknown table", http.StatusNotFound)
 		return
 	}
 
-	// Определяем первичный ключ и автоинкрементное свойство
-	pk := ""
-	isAutoIncrement := false
-	for _, col := range tableMeta.Columns {
-		if col.Key == "PRI" {
-			pk = col.Name
-			if strings.Contains(strings.ToLower(col.Extra), "auto_increment") {
-				isAutoIncrement = true
-			}
-			break
-		}
-	}
-	if pk == "" {
-		http.Error(w, `{"error": "table has no primary key"}`, http.StatusInternalServerError)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		errorResponse(w, "cant read body", http.StatusBadRequest)
 		return
 	}
 
-	// Распаковываем JSON-тело запроса в map
-	var data map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, `{"error": "cant unpack json"}`, http.StatusBadRequest)
+	// Массив записей вставляем одной транзакцией
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		d.handlePutMany(w, r, tableMeta, trimmed)
 		return
 	}
 
-	// Если первичный ключ является автоинкрементным, удаляем его из данных
-	if isAutoIncrement {
-		delete(data, pk)
-	}
-	// Если для неавтоинкрементного pk значение передано, его оставляем (так как ожидается, что для таблицы users pk = user_id передаётся)
-
-	// Формируем списки для INSERT, обходя все столбцы из метаданных
-	var cols []string
-	var placeholders []string
-	var values []interface{}
-
-	// Для каждого столбца, кроме автоинкрементного pk, если значение передано — используем его,
-	// иначе, если поле НЕ NULL, подставляем значение по умолчанию, зависящее от типа.
-	for _, col := range tableMeta.Columns {
-		// Пропускаем автоинкрементный pk
-		if isAutoIncrement && col.Name == pk {
-			continue
-		}
-		cols = append(cols, fmt.Sprintf("`%s`", col.Name))
-		if val, exists := data[col.Name]; exists {
-			// Если значение передано, проверяем тип
-			if val == nil {
-				// Если поле не допускает NULL — ошибка
-				if !col.Nullable {
-					http.Error(w, fmt.Sprintf(`{"error": "field %s have invalid type"}`, col.Name), http.StatusBadRequest)
-					return
-				}
-				placeholders = append(placeholders, "NULL")
-			} else {
-				if !isValidType(val, col.Type) {
-					http.Error(w, fmt.Sprintf(`{"error": "field %s have invalid type"}`, col.Name), http.StatusBadRequest)
-					return
-				}
-				placeholders = append(placeholders, "?")
-				values = append(values, val)
-			}
-		} else {
-			// Значение не передано в JSON.
-			// Если поле допускает NULL, можно подставить NULL.
-			// Если поле НЕ NULL, подставляем значение по умолчанию:
-			// для текстовых типов — пустая строка, для числовых — 0.
-			if col.Nullable {
-				placeholders = append(placeholders, "NULL")
-			} else {
-				colTypeLower := strings.ToLower(col.Type)
-				if strings.Contains(colTypeLower, "char") ||
-					strings.Contains(colTypeLower, "text") ||
-					strings.Contains(colTypeLower, "varchar") {
-					placeholders = append(placeholders, "?")
-					values = append(values, "")
-				} else if strings.Contains(colTypeLower, "int") ||
-					strings.Contains(colTypeLower, "float") ||
-					strings.Contains(colTypeLower, "double") ||
-					strings.Contains(colTypeLower, "decimal") {
-					placeholders = append(placeholders, "?")
-					values = append(values, 0)
-				} else {
-					// Если тип не распознан, подставляем пустую строку
-					placeholders = append(placeholders, "?")
-					values = append(values, "")
-				}
-			}
-		}
-	}
-
-	// Если никаких полей для вставки не осталось — ошибка
-	if len(cols) == 0 {
-		http.Error(w, `{"error": "no valid fields provided"}`, http.StatusBadRequest)
+	// Распаковываем JSON-тело запроса в map
+	var data map[string]interface{}
+	if err := decodeJSON(bytes.NewReader(body), &data); err != nil {
+		errorResponse(w, "cant unpack json", http.StatusBadRequest)
 		return
 	}
 
-	query := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(placeholders, ", "))
-	result, err := d.db.Exec(query, values...)
+	var inserted map[string]interface{}
+	err = d.inTx(r.Context(), func(tx *sql.Tx) (err error) {
+		inserted, err = d.insertRow(r.Context(), tx, tableMeta, data)
+		return err
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf(`{"error": "database error: %s"}`, err.Error()), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	var insertedID int64
-	if isAutoIncrement {
-		insertedID, err = result.LastInsertId()
-		if err != nil {
-			http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
-			return
-		}
-	} else {
-		// Если pk не автоинкрементный, берем его значение из запроса
-		if idVal, ok := data[pk]; ok {
-			switch v := idVal.(type) {
-			case float64:
-				insertedID = int64(v)
-			default:
-				insertedID = 0
-			}
-		}
-	}
-
 	jsonResponse(w, map[string]interface{}{
-		"response": map[string]interface{}{pk: insertedID},
+		"response": inserted,
 	})
 }
 
 func (d *DbExplorer) handlePost(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	if len(parts) < 2 {
-		http.Error(w, `{"error": "invalid path"}`, http.StatusBadRequest)
+		errorResponse(w, "invalid path", http.StatusBadRequest)
 		return
 	}
 
 	table := parts[0]
 	id := parts[1]
 
-	tableMeta, ok := d.tables[table]
+	tableMeta, ok := d.table(table)
 	if !ok {
-		http.Error(w, `{"error": "unknown table"}`, http.StatusNotFound)
-		return
-	}
-
-	pk := "id"
-	for _, col := range tableMeta.Columns {
-		if col.Key == "PRI" {
-			pk = col.Name
-			break
-		}
-	}
-
-	var data map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, `{"error": "cant unpack json"}`, http.StatusBadRequest)
+		errorResponse(w, "unknown table", http.StatusNotFound)
 		return
 	}
 
-	if _, exists := data[pk]; exists {
-		http.Error(w, fmt.Sprintf(`{"error": "field %s have invalid type"}`, pk), http.StatusBadRequest)
+	// `POST /table/_import` - загрузка CSV или NDJSON
+	if id == importPath {
+		d.handleImport(w, r, tableMeta)
 		return
 	}
 
-	setParts := []string{}
-	values := []interface{}{}
-
-	for key, val := range data {
-		colType := ""
-		isNullable := false
-		for _, col := range tableMeta.Columns {
-			if col.Name == key {
-				colType = col.Type
-				isNullable = col.Nullable
-				break
-			}
-		}
-
-		if colType == "" {
-			http.Error(w, fmt.Sprintf(`{"error": "unknown field %s"}`, key), http.StatusBadRequest)
-			return
-		}
-
-		if val == nil {
-			if !isNullable {
-				http.Error(w, fmt.Sprintf(`{"error": "field %s have invalid type"}`, key), http.StatusBadRequest)
-				return
-			}
-			setParts = append(setParts, fmt.Sprintf("`%s` = NULL", key))
-		} else {
-			if !isValidType(val, colType) {
-				http.Error(w, fmt.Sprintf(`{"error": "field %s have invalid type"}`, key), http.StatusBadRequest)
-				return
-			}
-			setParts = append(setParts, fmt.Sprintf("`%s` = ?", key))
-			values = append(values, val)
-		}
-	}
-
-	values = append(values, id)
-	query := fmt.Sprintf("UPDATE `%s` SET %s WHERE `%s` = ?", table, strings.Join(setParts, ", "), pk)
-
-	result, err := d.db.Exec(query, values...)
-	if err != nil {
-		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
+	var data map[string]interface{}
+	if err := decodeJSON(r.Body, &data); err != nil {
+		errorResponse(w, "cant unpack json", http.StatusBadRequest)
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	var rowsAffected int64
+	err := d.inTx(r.Context(), func(tx *sql.Tx) (err error) {
+		rowsAffected, err = d.updateRow(r.Context(), tx, tableMeta, id, data)
+		return err
+	})
 	if err != nil {
-		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
@@ -538,6 +376,21 @@ func (d *DbExplorer) handlePost(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// decodeJSON разбирает JSON-тело запроса. Числа остаются json.Number, а не float64,
+// чтобы BIGINT больше 2^53 доходил до проверки типа колонки без потери точности.
+func decodeJSON(r io.Reader, v interface{}) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// как и json.Unmarshal, не принимаем ничего после значения
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after json value")
+	}
+	return nil
+}
+
 func jsonResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(data)
@@ -551,36 +404,9 @@ func errorResponse(w http.ResponseWriter, message string, status int) {
 	})
 }
 
-// Функция получения структуры таблицы
-func getTableColumns(db *sql.DB, tableName string) ([]Column, error) {
-	rows, err := db.Query(fmt.Sprintf("SHOW FULL COLUMNS FROM `%s`;", tableName))
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var columns []Column
-	for rows.Next() {
-		var field, colType, nullable string
-		var tmp interface{} // временная переменная для пропуска лишних столбцов
-		// SHOW FULL COLUMNS возвращает 9 столбцов: Field, Type, Collation, Null, Key, Default, Extra, Privileges, Comment.
-		if err := rows.Scan(&field, &colType, &tmp, &nullable, &tmp, &tmp, &tmp, &tmp, &tmp); err != nil {
-			return nil, err
-		}
-
-		columns = append(columns, Column{
-			Name:     field,
-			Type:     colType,
-			Nullable: nullable == "YES",
-		})
-	}
-
-	return columns, nil
-}
-
-func (d *DbExplorer) handleGetRow(w http.ResponseWriter, table, id string) {
+func (d *DbExplorer) handleGetRow(w http.ResponseWriter, r *http.Request, table, id string) {
 	// Получаем метаданные таблицы
-	tableMeta, ok := d.tables[table]
+	tableMeta, ok := d.table(table)
 	if !ok || len(tableMeta.Columns) == 0 {
 		http.Error(w, "Error getting table structure", http.StatusInternalServerError)
 		return
@@ -593,46 +419,42 @@ func (d *DbExplorer) handleGetRow(w http.ResponseWriter, table, id string) {
 		return
 	}
 
-	// Формируем SQL-запрос
-	query := fmt.Sprintf("SELECT * FROM `%s` WHERE `%s` = ? LIMIT 1", table, pk)
-	row := d.db.QueryRow(query, id)
-
-	// Подготавливаем слайсы для сканирования
-	values := make([]interface{}, len(tableMeta.Columns))
-	valuePtrs := make([]interface{}, len(tableMeta.Columns))
-	for i := range values {
-		valuePtrs[i] = &values[i]
+	c := callerFrom(r.Context())
+	expand, err := parseExpand(c.view(tableMeta), r.URL.Query().Get("expand"))
+	if err != nil {
+		errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	err := row.Scan(valuePtrs...)
-	if err == sql.ErrNoRows {
-		http.Error(w, `{"error": "record not found"}`, http.StatusNotFound)
+	// Формируем SQL-запрос
+	args := &sqlArgs{dialect: d.dialect}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = %s LIMIT 1",
+		d.dialect.QuoteIdent(table), d.dialect.QuoteIdent(pk), args.add(id))
+	rows, err := d.db.QueryContext(r.Context(), query, args.values...)
+	if err != nil {
+		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
 		return
-	} else if err != nil {
+	}
+	records, err := readRecords(rows, tableMeta)
+	rows.Close()
+	if err != nil {
 		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
 		return
 	}
+	if len(records) == 0 {
+		http.Error(w, `{"error": "record not found"}`, http.StatusNotFound)
+		return
+	}
+	c.strip(table, records)
 
-	// Формируем JSON-ответ
-	record := make(map[string]interface{})
-	for i, col := range tableMeta.Columns {
-		val := values[i]
-		if b, ok := val.([]byte); ok {
-			if strings.HasPrefix(strings.ToLower(col.Type), "int") {
-				if num, err := strconv.Atoi(string(b)); err == nil {
-					record[col.Name] = num
-					continue
-				}
-			}
-			record[col.Name] = string(b)
-		} else {
-			record[col.Name] = val
-		}
+	if err := d.expandRecords(r.Context(), records, expand); err != nil {
+		writeError(w, err)
+		return
 	}
 
 	response := map[string]interface{}{
 		"response": map[string]interface{}{
-			"record": record,
+			"record": records[0],
 		},
 	}
 	jsonResponse(w, response)
@@ -647,56 +469,38 @@ func getPrimaryKey(tableMeta Table) string {
 	return ""
 }
 
-func (d *DbExplorer) getTableColumns(table string) ([]string, error) {
-	if tableInfo, exists := d.tables[table]; exists {
-		columnNames := make([]string, len(tableInfo.Columns))
-		for i, col := range tableInfo.Columns {
-			columnNames[i] = col.Name
-		}
-		return columnNames, nil
-	}
-
-	query := fmt.Sprintf("SHOW FULL COLUMNS FROM `%s`", table)
-	rows, err := d.db.Query(query)
+// readRecords вычитывает все строки результата, приводя значения по метаданным колонок:
+// драйверы разных баз отдают одни и те же типы по-разному ([]byte, string, int64)
+func readRecords(rows *sql.Rows, tableMeta Table) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var columns []Column
-	for rows.Next() {
-		var field, colType, colNull, colKey, colDefault, extra string
-		if err := rows.Scan(&field, &colType, &colNull, &colKey, &colDefault, &extra); err != nil {
-			return nil, err
-		}
-		columns = append(columns, Column{Name: field, Type: colType})
+	// типы разбираем один раз на весь результат
+	types := make([]columnType, len(columns))
+	for i, colName := range columns {
+		col, _ := tableMeta.column(colName)
+		types[i] = parseColumnType(col.Type)
 	}
 
-	d.tables[table] = Table{Name: table, Columns: columns}
-
-	// Конвертируем []Column в []string перед возвратом
-	columnNames := make([]string, len(columns))
-	for i, col := range columns {
-		columnNames[i] = col.Name
-	}
+	var records []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
 
-	return columnNames, nil
-}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
 
-func isValidType(value interface{}, sqlType string) bool {
-	switch value.(type) {
-	case float64:
-		return strings.Contains(strings.ToLower(sqlType), "int") ||
-			strings.Contains(strings.ToLower(sqlType), "float") ||
-			strings.Contains(strings.ToLower(sqlType), "double") ||
-			strings.Contains(strings.ToLower(sqlType), "decimal")
-	case string:
-		return strings.Contains(strings.ToLower(sqlType), "char") ||
-			strings.Contains(strings.ToLower(sqlType), "text") ||
-			strings.Contains(strings.ToLower(sqlType), "varchar")
-	case nil:
-		return true
-	default:
-		return false
+		record := make(map[string]interface{}, len(columns))
+		for i, colName := range columns {
+			record[colName] = types[i].readValue(values[i])
+		}
+		records = append(records, record)
 	}
+	return records, rows.Err()
 }
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// querier - общее у *sql.DB, *sql.Conn и *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Dialect скрывает различия между СУБД: чтение схемы, экранирование
+// идентификаторов, стиль плейсхолдеров и получение id вставленной записи.
+// Колонки возвращаются в терминах MySQL: Key == "PRI" для первичного ключа,
+// Extra содержит "auto_increment" для автоинкрементных полей.
+type Dialect interface {
+	Name() string
+	Tables(ctx context.Context, q querier) ([]string, error)
+	Columns(ctx context.Context, q querier, table string) ([]Column, error)
+	ForeignKeys(ctx context.Context, q querier, table string) ([]ForeignKey, error)
+	QuoteIdent(name string) string
+	// Placeholder возвращает плейсхолдер для n-го (с единицы) аргумента запроса
+	Placeholder(n int) string
+	// InsertID выполняет INSERT и возвращает значение автоинкрементного ключа pk
+	InsertID(ctx context.Context, q querier, query, pk string, args []interface{}) (int64, error)
+	// AuditTableSQL - CREATE TABLE IF NOT EXISTS для журнала изменений
+	AuditTableSQL(table string) string
+}
+
+// auditColumns - колонки журнала после автоинкрементного id, одинаковые для всех баз
+const auditColumns = `table_name VARCHAR(255) NOT NULL,
+	pk VARCHAR(255) NOT NULL,
+	op VARCHAR(16) NOT NULL,
+	caller VARCHAR(255) NOT NULL,
+	before_data TEXT,
+	after_data TEXT,
+	created_at VARCHAR(40) NOT NULL`
+
+// detectDialect выбирает диалект по типу драйвера, чтобы не тянуть сюда импорты драйверов
+func detectDialect(db *sql.DB) (Dialect, error) {
+	driver := fmt.Sprintf("%T", db.Driver())
+	switch {
+	case strings.Contains(driver, "mysql"):
+		return MySQLDialect{}, nil
+	case strings.Contains(driver, "sqlite"):
+		return SQLiteDialect{}, nil
+	case strings.HasPrefix(driver, "*pq."), strings.HasPrefix(driver, "*stdlib."):
+		return PostgresDialect{}, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %s", driver)
+}
+
+// sqlArgs копит аргументы запроса и выдаёт для них плейсхолдеры диалекта
+type sqlArgs struct {
+	dialect Dialect
+	values  []interface{}
+}
+
+func (a *sqlArgs) add(val interface{}) string {
+	a.values = append(a.values, val)
+	return a.dialect.Placeholder(len(a.values))
+}
+
+func lastInsertID(ctx context.Context, q querier, query string, args []interface{}) (int64, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ----------------
+
+type MySQLDialect struct{}
+
+func (MySQLDialect) Name() string {
+	return "mysql"
+}
+
+func (MySQLDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return queryStrings(ctx, q, "SHOW TABLES")
+}
+
+func (d MySQLDialect) Columns(ctx context.Context, q querier, table string) ([]Column, error) {
+	rows, err := q.QueryContext(ctx, "SHOW FULL COLUMNS FROM "+d.QuoteIdent(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var field, colType, nullable, key, extra string
+		var tmp interface{}
+		// SHOW FULL COLUMNS возвращает 9 столбцов: Field, Type, Collation, Null, Key, Default, Extra, Privileges, Comment.
+		if err := rows.Scan(&field, &colType, &tmp, &nullable, &key, &tmp, &extra, &tmp, &tmp); err != nil {
+			return nil, err
+		}
+		columns = append(columns, Column{
+			Name:     field,
+			Type:     colType,
+			Nullable: nullable == "YES",
+			Key:      key,
+			Extra:    extra,
+		})
+	}
+	return columns, rows.Err()
+}
+
+func (MySQLDialect) ForeignKeys(ctx context.Context, q querier, table string) ([]ForeignKey, error) {
+	return queryForeignKeys(ctx, q, `SELECT COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY ORDINAL_POSITION`, table)
+}
+
+func (MySQLDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (MySQLDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (MySQLDialect) InsertID(ctx context.Context, q querier, query, _ string, args []interface{}) (int64, error) {
+	return lastInsertID(ctx, q, query, args)
+}
+
+func (d MySQLDialect) AuditTableSQL(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, %s) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		d.QuoteIdent(table), strings.ReplaceAll(auditColumns, "TEXT", "LONGTEXT"))
+}
+
+// ----------------
+
+type PostgresDialect struct{}
+
+func (PostgresDialect) Name() string {
+	return "postgres"
+}
+
+func (PostgresDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return queryStrings(ctx, q, `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+}
+
+func (PostgresDialect) Columns(ctx context.Context, q querier, table string) ([]Column, error) {
+	rows, err := q.QueryContext(ctx, `SELECT c.column_name, c.data_type, c.character_maximum_length,
+			c.is_nullable, COALESCE(c.column_default, ''), c.is_identity,
+			EXISTS (
+				SELECT 1 FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage kcu
+					ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+				WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema
+					AND tc.table_name = c.table_name AND kcu.column_name = c.column_name
+			)
+		FROM information_schema.columns c
+		WHERE c.table_schema = current_schema() AND c.table_name = $1
+		ORDER BY c.ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var name, dataType, nullable, colDefault, identity string
+		var maxLen sql.NullInt64
+		var isPK bool
+		if err := rows.Scan(&name, &dataType, &maxLen, &nullable, &colDefault, &identity, &isPK); err != nil {
+			return nil, err
+		}
+		col := Column{
+			Name:     name,
+			Type:     dataType,
+			Nullable: nullable == "YES",
+		}
+		if maxLen.Valid {
+			col.Type += "(" + strconv.FormatInt(maxLen.Int64, 10) + ")"
+		}
+		if isPK {
+			col.Key = "PRI"
+		}
+		if identity == "YES" || strings.HasPrefix(colDefault, "nextval(") {
+			col.Extra = "auto_increment"
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+func (PostgresDialect) ForeignKeys(ctx context.Context, q querier, table string) ([]ForeignKey, error) {
+	return queryForeignKeys(ctx, q, `SELECT kcu.column_name, ccu.table_name, ccu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+		JOIN information_schema.constraint_column_usage ccu
+			ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1
+		ORDER BY kcu.ordinal_position`, table)
+}
+
+func (PostgresDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (PostgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// InsertID - в postgres нет LastInsertId, ключ возвращаем через RETURNING
+func (d PostgresDialect) InsertID(ctx context.Context, q querier, query, pk string, args []interface{}) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, query+" RETURNING "+d.QuoteIdent(pk), args...).Scan(&id)
+	return id, err
+}
+
+func (d PostgresDialect) AuditTableSQL(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL PRIMARY KEY, %s)", d.QuoteIdent(table), auditColumns)
+}
+
+// ----------------
+
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) Name() string {
+	return "sqlite"
+}
+
+func (SQLiteDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return queryStrings(ctx, q, `SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name`)
+}
+
+func (d SQLiteDialect) Columns(ctx context.Context, q querier, table string) ([]Column, error) {
+	rows, err := q.QueryContext(ctx, "PRAGMA table_info("+d.QuoteIdent(table)+")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	pkCount := 0
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return nil, err
+		}
+		col := Column{
+			Name:     name,
+			Type:     colType,
+			Nullable: notNull == 0 && pk == 0,
+		}
+		// в SQLite любой тип с INT в названии хранит 64-битные целые, что бы ни было написано в скобках
+		if strings.Contains(strings.ToLower(colType), "int") {
+			col.intBits = 64
+		}
+		if pk > 0 {
+			col.Key = "PRI"
+			pkCount++
+		}
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// INTEGER PRIMARY KEY - синоним rowid, значения для него выдаёт сама база
+	if pkCount == 1 {
+		for i, col := range columns {
+			if col.Key == "PRI" && strings.EqualFold(col.Type, "integer") {
+				columns[i].Extra = "auto_increment"
+			}
+		}
+	}
+	return columns, nil
+}
+
+func (d SQLiteDialect) ForeignKeys(ctx context.Context, q querier, table string) ([]ForeignKey, error) {
+	rows, err := q.QueryContext(ctx, "PRAGMA foreign_key_list("+d.QuoteIdent(table)+")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var id, seq int
+		var refTable, from string
+		var to sql.NullString
+		var tmp interface{}
+		// id, seq, table, from, to, on_update, on_delete, match
+		if err := rows.Scan(&id, &seq, &refTable, &from, &to, &tmp, &tmp, &tmp); err != nil {
+			return nil, err
+		}
+		// to пустой, если ссылка объявлена на первичный ключ без указания колонки;
+		// его подставляем после загрузки всех таблиц
+		fks = append(fks, ForeignKey{
+			Column:    from,
+			RefTable:  refTable,
+			RefColumn: to.String,
+		})
+	}
+	return fks, rows.Err()
+}
+
+func (SQLiteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (SQLiteDialect) Placeholder(int) string {
+	return "?"
+}
+
+func (SQLiteDialect) InsertID(ctx context.Context, q querier, query, _ string, args []interface{}) (int64, error) {
+	return lastInsertID(ctx, q, query, args)
+}
+
+func (d SQLiteDialect) AuditTableSQL(table string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, %s)", d.QuoteIdent(table), auditColumns)
+}
+
+// queryStrings читает первую колонку каждой строки результата
+func queryStrings(ctx context.Context, q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+func queryForeignKeys(ctx context.Context, q querier, query string, args ...interface{}) ([]ForeignKey, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var fk ForeignKey
+		if err := rows.Scan(&fk.Column, &fk.RefTable, &fk.RefColumn); err != nil {
+			return nil, err
+		}
+		fks = append(fks, fk)
+	}
+	return fks, rows.Err()
+}
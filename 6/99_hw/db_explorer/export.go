@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+
+	defaultExportPageSize = 500
+)
+
+// recordWriter пишет выгрузку построчно
+type recordWriter interface {
+	writeHeader(columns []string) error
+	writeRecord(columns []string, rec map[string]interface{}) error
+	flush() error
+}
+
+func newRecordWriter(w http.ResponseWriter, format string) (recordWriter, error) {
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		return &csvRecordWriter{w: csv.NewWriter(w)}, nil
+	case formatNDJSON:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		return &ndjsonRecordWriter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, badRequest("unknown format %s", format)
+}
+
+// csvRecordWriter - первая строка с именами колонок, NULL выгружается пустым полем
+type csvRecordWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRecordWriter) writeHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvRecordWriter) writeRecord(columns []string, rec map[string]interface{}) error {
+	line := make([]string, len(columns))
+	for i, name := range columns {
+		line[i] = csvValue(rec[name])
+	}
+	return c.w.Write(line)
+}
+
+func (c *csvRecordWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func csvValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.RawMessage:
+		return string(v)
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(val)
+}
+
+// ndjsonRecordWriter - по JSON-объекту на строку
+type ndjsonRecordWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonRecordWriter) writeHeader([]string) error {
+	return nil
+}
+
+func (n *ndjsonRecordWriter) writeRecord(columns []string, rec map[string]interface{}) error {
+	return n.enc.Encode(rec)
+}
+
+func (n *ndjsonRecordWriter) flush() error {
+	return nil
+}
+
+// handleExport отдаёт все записи таблицы, подходящие под фильтры.
+// Страницы выбираются по первичному ключу (WHERE pk > последний), а не через OFFSET,
+// так что в памяти держится не больше одной страницы, а запросы не замедляются к концу таблицы.
+func (d *DbExplorer) handleExport(w http.ResponseWriter, r *http.Request, view Table, lq *listQuery, format string) {
+	q := r.URL.Query()
+	for _, param := range []string{"limit", "offset", "order_by", "expand"} {
+		if _, ok := q[param]; ok {
+			errorResponse(w, fmt.Sprintf("%s is not supported with format", param), http.StatusBadRequest)
+			return
+		}
+	}
+
+	pk := getPrimaryKey(view)
+	if pk == "" {
+		errorResponse(w, "table has no primary key", http.StatusBadRequest)
+		return
+	}
+
+	var columns []string
+	if fields := q.Get("fields"); fields != "" {
+		for _, name := range strings.Split(fields, ",") {
+			columns = append(columns, strings.TrimSpace(name))
+		}
+	} else {
+		for _, col := range view.Columns {
+			columns = append(columns, col.Name)
+		}
+	}
+	// ключ нужен для курсора, даже если его не просили в fields
+	selected := columns
+	if !contains(selected, pk) {
+		selected = append(append([]string{}, columns...), pk)
+	}
+	quoted := make([]string, len(selected))
+	for i, name := range selected {
+		quoted[i] = d.dialect.QuoteIdent(name)
+	}
+
+	rw, err := newRecordWriter(w, format)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", view.Name+"."+format))
+
+	started := false
+	var cursor interface{}
+	for {
+		args := &sqlArgs{dialect: d.dialect, values: append([]interface{}{}, lq.args.values...)}
+		where := append([]string{}, lq.where...)
+		if cursor != nil {
+			where = append(where, fmt.Sprintf("%s > %s", d.dialect.QuoteIdent(pk), args.add(cursor)))
+		}
+		query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), d.dialect.QuoteIdent(view.Name))
+		if len(where) > 0 {
+			query += " WHERE " + strings.Join(where, " AND ")
+		}
+		query += fmt.Sprintf(" ORDER BY %s ASC LIMIT %d", d.dialect.QuoteIdent(pk), d.exportPageSize)
+
+		rows, err := d.db.QueryContext(r.Context(), query, args.values...)
+		if err != nil {
+			exportFailed(w, started, err)
+			return
+		}
+		records, err := readRecords(rows, view)
+		rows.Close()
+		if err != nil {
+			exportFailed(w, started, err)
+			return
+		}
+
+		if !started {
+			if err := rw.writeHeader(columns); err != nil {
+				return
+			}
+			started = true
+		}
+		for _, rec := range records {
+			cursor = rec[pk]
+			if !contains(columns, pk) {
+				delete(rec, pk)
+			}
+			if err := rw.writeRecord(columns, rec); err != nil {
+				// клиент отключился
+				return
+			}
+		}
+		if err := rw.flush(); err != nil {
+			return
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if len(records) < d.exportPageSize {
+			return
+		}
+	}
+}
+
+// exportFailed - до первой строки ещё можно ответить ошибкой,
+// после неё статус уже отправлен и остаётся только оборвать поток
+func exportFailed(w http.ResponseWriter, started bool, err error) {
+	if !started {
+		writeError(w, err)
+		return
+	}
+	log.Printf("Ошибка выгрузки: %v", err)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	importPath = "_import"
+
+	// после стольких ошибок дальше файл не читаем
+	maxImportErrors = 100
+	// максимальная длина строки NDJSON
+	maxImportLine = 1 << 20
+)
+
+// importError - ошибка в конкретной строке загружаемого файла
+type importError struct {
+	Line int    `json:"line"`
+	Msg  string `json:"error"`
+}
+
+func (e *importError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// importReader отдаёт записи файла по одной вместе с номером строки.
+// Ошибка разбора одной записи возвращается как *importError, читать можно дальше.
+type importReader interface {
+	next() (line int, data map[string]interface{}, err error)
+}
+
+// importFormat определяет формат по ?format= или Content-Type
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonlines":
+		return formatNDJSON
+	}
+	return ""
+}
+
+// handleImport обрабатывает `POST /{table}/_import`.
+// Каждая запись проверяется так же, как при PUT, всё вставляется одной транзакцией:
+// если хоть одна строка с ошибкой - не вставляется ничего, а в ответе список ошибок по строкам.
+func (d *DbExplorer) handleImport(w http.ResponseWriter, r *http.Request, tableMeta Table) {
+	c := callerFrom(r.Context())
+	if err := c.allow(tableMeta.Name, opCreate); err != nil {
+		writeError(w, err)
+		return
+	}
+	view := c.view(tableMeta)
+
+	var reader importReader
+	switch format := importFormat(r); format {
+	case formatCSV:
+		cr, err := newCSVImportReader(r.Body, view)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		reader = cr
+	case formatNDJSON:
+		reader = newNDJSONImportReader(r.Body)
+	case "":
+		errorResponse(w, "unknown format, use text/csv or application/x-ndjson", http.StatusBadRequest)
+		return
+	default:
+		errorResponse(w, fmt.Sprintf("unknown format %s", format), http.StatusBadRequest)
+		return
+	}
+
+	var lineErrors []importError
+	imported := 0
+	errFailed := errors.New("import failed")
+	err := d.inTx(r.Context(), func(tx *sql.Tx) error {
+		for len(lineErrors) < maxImportErrors {
+			line, data, err := reader.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				var lineErr *importError
+				if !errors.As(err, &lineErr) {
+					return badRequest("line %d: %s", line, err)
+				}
+				lineErrors = append(lineErrors, *lineErr)
+				continue
+			}
+			if err := d.importRecord(r.Context(), tx, tableMeta, data); err != nil {
+				status, msg := errorStatus(err)
+				if status == http.StatusInternalServerError {
+					return err
+				}
+				lineErrors = append(lineErrors, importError{Line: line, Msg: msg})
+				continue
+			}
+			imported++
+		}
+		if len(lineErrors) > 0 {
+			return errFailed
+		}
+		return nil
+	})
+
+	if err == errFailed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  fmt.Sprintf("%d lines have errors", len(lineErrors)),
+			"errors": lineErrors,
+		})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	jsonResponse(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"imported": imported,
+		},
+	})
+}
+
+// importRecord вставляет одну запись, сохраняя переданный первичный ключ
+func (d *DbExplorer) importRecord(ctx context.Context, q querier, tableMeta Table, data map[string]interface{}) error {
+	for name := range data {
+		if !tableMeta.hasColumn(name) {
+			return badRequest("unknown field %s", name)
+		}
+	}
+	_, err := d.insert(ctx, q, tableMeta, data, true)
+	return err
+}
+
+// ----------------
+
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONImportReader(body io.Reader) *ndjsonImportReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	return &ndjsonImportReader{scanner: scanner}
+}
+
+func (n *ndjsonImportReader) next() (int, map[string]interface{}, error) {
+	for n.scanner.Scan() {
+		n.line++
+		text := bytes.TrimSpace(n.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var data map[string]interface{}
+		if err := decodeJSON(bytes.NewReader(text), &data); err != nil {
+			return n.line, nil, &importError{Line: n.line, Msg: "cant unpack json"}
+		}
+		return n.line, data, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return n.line + 1, nil, err
+	}
+	return n.line, nil, io.EOF
+}
+
+// ----------------
+
+// csvImportReader - первая строка с именами колонок.
+// Значения приводятся к типу колонки, пустое поле в nullable-колонке - NULL.
+type csvImportReader struct {
+	r       *csv.Reader
+	columns []Column
+}
+
+func newCSVImportReader(body io.Reader, tableMeta Table) (*csvImportReader, error) {
+	r := csv.NewReader(body)
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, badRequest("empty csv")
+	}
+	if err != nil {
+		return nil, badRequest("line 1: %s", err)
+	}
+	cr := &csvImportReader{r: r}
+	for _, name := range header {
+		col, ok := tableMeta.column(strings.TrimSpace(name))
+		if !ok {
+			return nil, badRequest("unknown field %s", name)
+		}
+		cr.columns = append(cr.columns, col)
+	}
+	return cr, nil
+}
+
+func (c *csvImportReader) next() (int, map[string]interface{}, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return 0, nil, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			return 0, nil, err
+		}
+		if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			return parseErr.StartLine, nil, &importError{Line: parseErr.StartLine, Msg: "wrong number of fields"}
+		}
+		// после испорченных кавычек дальше файл не разобрать
+		return parseErr.StartLine, nil, parseErr.Err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	data := make(map[string]interface{}, len(record))
+	for i, col := range c.columns {
+		val, ok := csvField(col, record[i])
+		if !ok {
+			return line, nil, &importError{Line: line, Msg: fmt.Sprintf("field %s have invalid type", col.Name)}
+		}
+		data[col.Name] = val
+	}
+	return line, data, nil
+}
+
+// csvField приводит строку из CSV к тому, что пришло бы в JSON-теле (см. decodeJSON),
+// чтобы дальше работала та же проверка coerceValue
+func csvField(col Column, s string) (interface{}, bool) {
+	if s == "" && col.Nullable {
+		return nil, true
+	}
+	switch parseColumnType(col.Type).kind {
+	case kindInt:
+		// целое в обычной записи отдаём как есть: BIGINT не должен проходить через float64,
+		// а диапазон проверит coerceValue
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil || errors.Is(err, strconv.ErrRange) {
+			return json.Number(s), true
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	case kindFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		return f, err == nil
+	case kindBool:
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	case kindJSON:
+		var v interface{}
+		err := decodeJSON(strings.NewReader(s), &v)
+		return v, err == nil
+	}
+	return s, true
+}
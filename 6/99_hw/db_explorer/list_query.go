@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listReservedParam - служебный ли параметр GET /{table}, всё остальное в query - фильтры по колонкам
+func listReservedParam(name string) bool {
+	switch name {
+	case "limit", "offset", "order_by", "fields", "expand", "format":
+		return true
+	}
+	return false
+}
+
+// filterOperator переводит оператор фильтра в SQL: ?age__gt=18 -> "age" > ?
+func filterOperator(op string) (string, bool) {
+	switch op {
+	case "eq":
+		return "=", true
+	case "ne":
+		return "<>", true
+	case "gt":
+		return ">", true
+	case "gte":
+		return ">=", true
+	case "lt":
+		return "<", true
+	case "lte":
+		return "<=", true
+	}
+	return "", false
+}
+
+// listQuery - разобранные и проверенные по метаданным таблицы параметры листинга
+type listQuery struct {
+	dialect Dialect
+	limit   int
+	offset  int
+	fields  []string
+	where   []string
+	args    *sqlArgs
+	orderBy []string
+	expand  []ForeignKey
+}
+
+// parseListQuery разбирает limit/offset, fields, order_by и фильтры.
+// Имена колонок берутся только из метаданных, значения уходят в плейсхолдеры.
+func parseListQuery(dialect Dialect, tableMeta Table, q url.Values) (*listQuery, error) {
+	lq := &listQuery{
+		dialect: dialect,
+		limit:   5,
+		offset:  0,
+		args:    &sqlArgs{dialect: dialect},
+	}
+
+	// если пришло не число - оставляем значения по-умолчанию
+	if l, err := strconv.Atoi(q.Get("limit")); err == nil {
+		lq.limit = l
+	}
+	if o, err := strconv.Atoi(q.Get("offset")); err == nil {
+		lq.offset = o
+	}
+
+	expand, err := parseExpand(tableMeta, q.Get("expand"))
+	if err != nil {
+		return nil, err
+	}
+	lq.expand = expand
+
+	if fields := q.Get("fields"); fields != "" {
+		selected := map[string]bool{}
+		for _, name := range strings.Split(fields, ",") {
+			name = strings.TrimSpace(name)
+			if !tableMeta.hasColumn(name) {
+				return nil, fmt.Errorf("unknown field %s", name)
+			}
+			selected[name] = true
+			lq.fields = append(lq.fields, dialect.QuoteIdent(name))
+		}
+		for _, fk := range lq.expand {
+			if !selected[fk.Column] {
+				return nil, fmt.Errorf("field %s is required to expand %s", fk.Column, fk.relationName())
+			}
+		}
+	}
+
+	if orderBy := q.Get("order_by"); orderBy != "" {
+		for _, name := range strings.Split(orderBy, ",") {
+			name = strings.TrimSpace(name)
+			dir := "ASC"
+			if strings.HasPrefix(name, "-") {
+				name = name[1:]
+				dir = "DESC"
+			}
+			if !tableMeta.hasColumn(name) {
+				return nil, fmt.Errorf("unknown field %s", name)
+			}
+			lq.orderBy = append(lq.orderBy, dialect.QuoteIdent(name)+" "+dir)
+		}
+	} else if pk := getPrimaryKey(tableMeta); pk != "" {
+		// без явной сортировки пагинация по limit/offset не стабильна
+		lq.orderBy = []string{dialect.QuoteIdent(pk) + " ASC"}
+	}
+
+	// сортируем ключи, чтобы порядок условий и аргументов не зависел от map
+	keys := make([]string, 0, len(q))
+	for key := range q {
+		if !listReservedParam(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, op := key, "eq"
+		if idx := strings.LastIndex(key, "__"); idx > 0 && !tableMeta.hasColumn(key) {
+			name, op = key[:idx], key[idx+2:]
+		}
+		if !tableMeta.hasColumn(name) {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		sqlOp, ok := filterOperator(op)
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %s", op)
+		}
+		for _, val := range q[key] {
+			lq.addFilter(name, sqlOp, val)
+		}
+	}
+
+	return lq, nil
+}
+
+// addFilter добавляет условие column op value, имя колонки должно быть уже проверено
+func (lq *listQuery) addFilter(column, op string, val interface{}) {
+	lq.where = append(lq.where, fmt.Sprintf("%s %s %s", lq.dialect.QuoteIdent(column), op, lq.args.add(val)))
+}
+
+// whereClause возвращает " WHERE ..." или пустую строку, если фильтров нет
+func (lq *listQuery) whereClause() string {
+	if len(lq.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(lq.where, " AND ")
+}
+
+// selectSQL строит запрос на страницу записей
+func (lq *listQuery) selectSQL(table string) string {
+	fields := "*"
+	if len(lq.fields) > 0 {
+		fields = strings.Join(lq.fields, ", ")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s%s", fields, lq.dialect.QuoteIdent(table), lq.whereClause())
+	if len(lq.orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(lq.orderBy, ", ")
+	}
+	return query + fmt.Sprintf(" LIMIT %d OFFSET %d", lq.limit, lq.offset)
+}
+
+// countSQL строит запрос на общее число записей с теми же фильтрами
+func (lq *listQuery) countSQL(table string) string {
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s%s", lq.dialect.QuoteIdent(table), lq.whereClause())
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -11,9 +12,16 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // CaseResponse
@@ -26,16 +34,43 @@ type Case struct {
 	Status int
 	Result interface{}
 	Body   interface{}
+	// Headers - дополнительные заголовки запроса, например X-Api-Key
+	Headers map[string]string
 }
 
 var (
 	client = &http.Client{Timeout: time.Second}
 )
 
-func PrepareTestApis(db *sql.DB) {
-	qs := []string{
-		`DROP TABLE IF EXISTS items;`,
+// testDriver - база для тестов, по-умолчанию sqlite во временном файле,
+// DB_EXPLORER_DRIVER=mysql гоняет тесты на базе из DSN
+func testDriver() string {
+	if driver := os.Getenv("DB_EXPLORER_DRIVER"); driver != "" {
+		return driver
+	}
+	return "sqlite3"
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	driver := testDriver()
+	dsn := DSN
+	if driver == "sqlite3" {
+		dsn = filepath.Join(t.TempDir(), "explorer.db")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
 
+// схемы отличаются только объявлением автоинкремента
+var testSchemas = map[string][]string{
+	"mysql": {
 		`CREATE TABLE items (
   id int(11) NOT NULL AUTO_INCREMENT,
   title varchar(255) NOT NULL,
@@ -44,12 +79,6 @@ func PrepareTestApis(db *sql.DB) {
   PRIMARY KEY (id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
 
-		`INSERT INTO items (id, title, description, updated) VALUES
-(1,	'database/sql',	'Рассказать про базы данных',	'rvasily'),
-(2,	'memcache',	'Рассказать про мемкеш с примером использования',	NULL);`,
-
-		`DROP TABLE IF EXISTS users;`,
-
 		`CREATE TABLE users (
 			user_id int(11) NOT NULL AUTO_INCREMENT,
   login varchar(255) NOT NULL,
@@ -59,6 +88,40 @@ func PrepareTestApis(db *sql.DB) {
   updated varchar(255) DEFAULT NULL,
   PRIMARY KEY (user_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+	},
+	"sqlite3": {
+		`CREATE TABLE items (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title varchar(255) NOT NULL,
+  description text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+
+		`CREATE TABLE users (
+  user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  login varchar(255) NOT NULL,
+  password varchar(255) NOT NULL,
+  email varchar(255) NOT NULL,
+  info text NOT NULL,
+  updated varchar(255) DEFAULT NULL
+);`,
+	},
+}
+
+func PrepareTestApis(db *sql.DB) {
+	schema := testSchemas[testDriver()]
+	qs := []string{
+		`DROP TABLE IF EXISTS items;`,
+
+		schema[0],
+
+		`INSERT INTO items (id, title, description, updated) VALUES
+(1,	'database/sql',	'Рассказать про базы данных',	'rvasily'),
+(2,	'memcache',	'Рассказать про мемкеш с примером использования',	NULL);`,
+
+		`DROP TABLE IF EXISTS users;`,
+
+		schema[1],
 
 		`INSERT INTO users (user_id, login, password, email, info, updated) VALUES
 (1,	'rvasily',	'love',	'rvasily@example.com',	'none',	NULL);`,
@@ -86,11 +149,7 @@ func CleanupTestApis(db *sql.DB) {
 }
 
 func TestApis(t *testing.T) {
-	db, err := sql.Open("mysql", DSN)
-	err = db.Ping()
-	if err != nil {
-		panic(err)
-	}
+	db := openTestDB(t)
 
 	PrepareTestApis(db)
 
@@ -124,6 +183,7 @@ func TestApis(t *testing.T) {
 			Path: "/items",
 			Result: CR{
 				"response": CR{
+					"total": 2,
 					"records": []CR{
 						CR{
 							"id":          1,
@@ -146,6 +206,7 @@ func TestApis(t *testing.T) {
 			Query: "limit=1",
 			Result: CR{
 				"response": CR{
+					"total": 2,
 					"records": []CR{
 						CR{
 							"id":          1,
@@ -162,6 +223,44 @@ func TestApis(t *testing.T) {
 			Query: "limit=1&offset=1",
 			Result: CR{
 				"response": CR{
+					"total": 2,
+					"records": []CR{
+						CR{
+							"id":          2,
+							"title":       "memcache",
+							"description": "Рассказать про мемкеш с примером использования",
+							"updated":     nil,
+						},
+					},
+				},
+			},
+		},
+		// фильтры, сортировка и выбор полей
+		Case{
+			Path:  "/items",
+			Query: "fields=id,title&order_by=-id",
+			Result: CR{
+				"response": CR{
+					"total": 2,
+					"records": []CR{
+						CR{
+							"id":    2,
+							"title": "memcache",
+						},
+						CR{
+							"id":    1,
+							"title": "database/sql",
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "id__gt=1&title=memcache",
+			Result: CR{
+				"response": CR{
+					"total": 1,
 					"records": []CR{
 						CR{
 							"id":          2,
@@ -173,6 +272,44 @@ func TestApis(t *testing.T) {
 				},
 			},
 		},
+		Case{
+			Path:  "/items",
+			Query: "title__ne=memcache&limit=1&fields=id",
+			Result: CR{
+				"response": CR{
+					"total": 1,
+					"records": []CR{
+						CR{
+							"id": 1,
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "password=love",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field password",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "order_by=-id%60",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field id`",
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "id__like=1",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown operator like",
+			},
+		},
 		Case{
 			Path: "/items/1",
 			Result: CR{
@@ -474,6 +611,7 @@ func TestApis(t *testing.T) {
 			Query: "limit=1'&offset=1\"",
 			Result: CR{
 				"response": CR{
+					"total": 2,
 					"records": []CR{
 						CR{
 							"user_id":  1,
@@ -500,35 +638,1496 @@ func TestApis(t *testing.T) {
 	runCases(t, ts, db, cases)
 }
 
-func runCases(t *testing.T, ts *httptest.Server, db *sql.DB, cases []Case) {
-	for idx, item := range cases {
-		var (
-			err      error
-			result   interface{}
-			expected interface{}
-			req      *http.Request
-		)
-		/*if idx != 26 {
-			continue
-		}*/
-		caseName := fmt.Sprintf("case %d: [%s] %s %s", idx, item.Method, item.Path, item.Query)
+var testRelationSchemas = map[string][]string{
+	"mysql": {
+		`CREATE TABLE authors (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  name varchar(255) NOT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
 
-		// если у вас случилась это ошибка - значит вы не делаете где-то rows.Close и у вас текут соединения с базой
-		// если такое случилось на первом тесте - значит вы не закрываете коннект где-то при иницаилизации в NewDbExplorer
-		if db.Stats().OpenConnections != 1 {
-			t.Fatalf("[%s] you have %d open connections, must be 1", caseName, db.Stats().OpenConnections)
+		`CREATE TABLE articles (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  title varchar(255) NOT NULL,
+  author_id int(11) NOT NULL,
+  PRIMARY KEY (id),
+  FOREIGN KEY (author_id) REFERENCES authors (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+	},
+	"sqlite3": {
+		`CREATE TABLE authors (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name varchar(255) NOT NULL
+);`,
+
+		`CREATE TABLE articles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  title varchar(255) NOT NULL,
+  author_id int(11) NOT NULL REFERENCES authors (id)
+);`,
+	},
+}
+
+func PrepareRelationApis(db *sql.DB) {
+	schema := testRelationSchemas[testDriver()]
+	qs := []string{
+		`DROP TABLE IF EXISTS articles;`,
+		`DROP TABLE IF EXISTS authors;`,
+		schema[0],
+		schema[1],
+		`INSERT INTO authors (id, name) VALUES (1, 'rvasily'), (2, 'nobody');`,
+		`INSERT INTO articles (id, title, author_id) VALUES (1, 'database/sql', 1), (2, 'memcache', 1);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
 		}
+	}
+}
 
-		if item.Method == "" || item.Method == http.MethodGet {
-			req, err = http.NewRequest(item.Method, ts.URL+item.Path+"?"+item.Query, nil)
-		} else {
-			data, err := json.Marshal(item.Body)
-			if err != nil {
-				panic(err)
-			}
-			reqBody := bytes.NewReader(data)
-			req, err = http.NewRequest(item.Method, ts.URL+item.Path, reqBody)
-			req.Header.Add("Content-Type", "application/json")
+func TestForeignKeys(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareRelationApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	rvasily := CR{"id": 1, "name": "rvasily"}
+
+	cases := []Case{
+		Case{
+			Path:  "/articles/1",
+			Query: "expand=author",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":        1,
+						"title":     "database/sql",
+						"author_id": 1,
+						"author":    rvasily,
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/articles",
+			Query: "expand=author&fields=id,author_id",
+			Result: CR{
+				"response": CR{
+					"total": 2,
+					"records": []CR{
+						CR{"id": 1, "author_id": 1, "author": rvasily},
+						CR{"id": 2, "author_id": 1, "author": rvasily},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/articles",
+			Query:  "expand=author&fields=id",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "field author_id is required to expand author",
+			},
+		},
+		Case{
+			Path:   "/articles/1",
+			Query:  "expand=editor",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown relation editor",
+			},
+		},
+		// обратная связь
+		Case{
+			Path:  "/authors/1/articles",
+			Query: "order_by=-id&limit=1",
+			Result: CR{
+				"response": CR{
+					"total": 2,
+					"records": []CR{
+						CR{"id": 2, "title": "memcache", "author_id": 1},
+					},
+				},
+			},
+		},
+		Case{
+			Path: "/authors/2/articles",
+			Result: CR{
+				"response": CR{
+					"total":   0,
+					"records": nil,
+				},
+			},
+		},
+		Case{
+			Path:   "/authors/100500/articles",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "record not found",
+			},
+		},
+		Case{
+			Path:   "/articles/1/authors",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "unknown relation",
+			},
+		},
+		// нарушение внешнего ключа - 400, а не ошибка драйвера
+		Case{
+			Path:   "/articles/",
+			Method: http.MethodPut,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"title":     "orphan",
+				"author_id": 100500,
+			},
+			Result: CR{
+				"error": "field author_id references unknown authors record",
+			},
+		},
+		Case{
+			Path:   "/articles/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"author_id": 100500,
+			},
+			Result: CR{
+				"error": "field author_id references unknown authors record",
+			},
+		},
+		Case{
+			Path:   "/articles/1",
+			Method: http.MethodPost,
+			Body: CR{
+				"author_id": 2,
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path:  "/authors/2/articles",
+			Query: "fields=id",
+			Result: CR{
+				"response": CR{
+					"total": 1,
+					"records": []CR{
+						CR{"id": 1},
+					},
+				},
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestSchemaReload(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path:   "/tags",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "unknown table",
+			},
+		},
+	})
+
+	// схема поменялась мимо explorer'а
+	for _, q := range []string{
+		`CREATE TABLE tags (name varchar(255) NOT NULL, PRIMARY KEY (name));`,
+		`ALTER TABLE items ADD COLUMN views int NULL;`,
+		`DROP TABLE users;`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path:   "/_admin/reload",
+			Method: http.MethodPost,
+			Result: CR{
+				"response": CR{
+					"added":   []string{"tags"},
+					"dropped": []string{"users"},
+					"changed": []string{"items"},
+				},
+			},
+		},
+		Case{
+			Path: "/",
+			Result: CR{
+				"response": CR{
+					"tables": []string{"items", "tags"},
+				},
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "views__gt=0",
+			Result: CR{
+				"response": CR{
+					"total":   0,
+					"records": nil,
+				},
+			},
+		},
+		Case{
+			Path:   "/_admin/reload",
+			Method: http.MethodPost,
+			Result: CR{
+				"response": CR{
+					"added":   []string{},
+					"dropped": []string{},
+					"changed": []string{},
+				},
+			},
+		},
+		Case{
+			Path:   "/_admin/reload",
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "bad method",
+			},
+		},
+	})
+
+	// в /_schema колонки, типы, nullable и ключи
+	resp, err := client.Get(ts.URL + "/_schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var schema struct {
+		Response struct {
+			Tables map[string]Table `json:"tables"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil {
+		t.Fatal(err)
+	}
+	tags, ok := schema.Response.Tables["tags"]
+	if !ok || len(tags.Columns) != 1 {
+		t.Fatalf("bad tags schema: %+v", schema.Response.Tables)
+	}
+	if col := tags.Columns[0]; col.Name != "name" || col.Key != "PRI" || col.Nullable {
+		t.Errorf("bad tags.name column: %+v", col)
+	}
+	views, ok := schema.Response.Tables["items"].column("views")
+	if !ok || !views.Nullable || parseColumnType(views.Type).kind != kindInt {
+		t.Errorf("bad items.views column: %+v", views)
+	}
+}
+
+// reloadDialect - диалект, у которого можно сломать чтение колонок и посчитать одновременные чтения схемы
+type reloadDialect struct {
+	Dialect
+	failColumns int32
+	active      int32
+	maxActive   int32
+}
+
+func (d *reloadDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	n := atomic.AddInt32(&d.active, 1)
+	defer atomic.AddInt32(&d.active, -1)
+	for {
+		max := atomic.LoadInt32(&d.maxActive)
+		if n <= max || atomic.CompareAndSwapInt32(&d.maxActive, max, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return d.Dialect.Tables(ctx, q)
+}
+
+func (d *reloadDialect) Columns(ctx context.Context, q querier, table string) ([]Column, error) {
+	if table == "items" && atomic.LoadInt32(&d.failColumns) == 1 {
+		return nil, fmt.Errorf("columns unavailable")
+	}
+	return d.Dialect.Columns(ctx, q, table)
+}
+
+func TestSchemaReloadErrors(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	base, err := detectDialect(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialect := &reloadDialect{Dialect: base}
+	handler, err := NewDbExplorerWithDialect(db, dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// одновременные перечитывания выполняются по одному
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := handler.Reload(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if max := atomic.LoadInt32(&dialect.maxActive); max != 1 {
+		t.Errorf("expected reloads to run one at a time, got %d at once", max)
+	}
+
+	// не прочитались колонки одной таблицы - перечитывание не удалось целиком, схема прежняя
+	atomic.StoreInt32(&dialect.failColumns, 1)
+	if _, err := handler.Reload(context.Background()); err == nil {
+		t.Error("expected reload error")
+	}
+	if _, ok := handler.table("items"); !ok {
+		t.Error("items dropped from schema after failed reload")
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	runCases(t, ts, db, []Case{
+		Case{
+			Path:   "/_admin/reload",
+			Method: http.MethodPost,
+			Status: http.StatusInternalServerError,
+			Result: CR{
+				"error": "failed to reload schema",
+			},
+		},
+		Case{
+			Path: "/",
+			Result: CR{
+				"response": CR{
+					"tables": []string{"items", "users"},
+				},
+			},
+		},
+	})
+}
+
+func TestBatch(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareRelationApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []Case{
+		// операции видят результаты предыдущих в той же транзакции
+		Case{
+			Path:   "/_batch",
+			Method: http.MethodPost,
+			Body: CR{
+				"operations": []CR{
+					CR{"op": "create", "table": "authors", "data": CR{"name": "batch"}},
+					CR{"op": "create", "table": "articles", "data": CR{"title": "new", "author_id": 3}},
+					CR{"op": "update", "table": "articles", "id": 1, "data": CR{"title": "updated"}},
+					CR{"op": "delete", "table": "articles", "id": "2"},
+				},
+			},
+			Result: CR{
+				"response": CR{
+					"results": []CR{
+						CR{"status": "ok", "response": CR{"id": 3}},
+						CR{"status": "ok", "response": CR{"id": 3}},
+						CR{"status": "ok", "response": CR{"updated": 1}},
+						CR{"status": "ok", "response": CR{"deleted": 1}},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/articles",
+			Query: "fields=id,title",
+			Result: CR{
+				"response": CR{
+					"total": 2,
+					"records": []CR{
+						CR{"id": 1, "title": "updated"},
+						CR{"id": 3, "title": "new"},
+					},
+				},
+			},
+		},
+		// несколько записей одним PUT
+		Case{
+			Path:   "/authors/",
+			Method: http.MethodPut,
+			Body: []CR{
+				CR{"name": "first"},
+				CR{"name": "second"},
+			},
+			Result: CR{
+				"response": CR{
+					"inserted": []CR{
+						CR{"id": 4},
+						CR{"id": 5},
+					},
+				},
+			},
+		},
+		// ошибка в середине откатывает всё
+		Case{
+			Path:   "/_batch",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"operations": []CR{
+					CR{"op": "create", "table": "authors", "data": CR{"name": "ghost"}},
+					CR{"op": "update", "table": "articles", "id": 1, "data": CR{"title": 42}},
+					CR{"op": "delete", "table": "articles", "id": 3},
+				},
+			},
+			Result: CR{
+				"error": "operation 1: field title have invalid type",
+				"results": []CR{
+					CR{"status": "rolled_back"},
+					CR{"status": "error", "error": "field title have invalid type"},
+					CR{"status": "skipped"},
+				},
+			},
+		},
+		Case{
+			Path:   "/_batch",
+			Method: http.MethodPost,
+			Status: http.StatusNotFound,
+			Body: CR{
+				"operations": []CR{
+					CR{"op": "delete", "table": "nope", "id": 1},
+				},
+			},
+			Result: CR{
+				"error": "operation 0: unknown table",
+				"results": []CR{
+					CR{"status": "error", "error": "unknown table"},
+				},
+			},
+		},
+		Case{
+			Path:   "/authors/",
+			Method: http.MethodPut,
+			Status: http.StatusBadRequest,
+			Body: []CR{
+				CR{"name": "third"},
+				CR{"name": nil},
+			},
+			Result: CR{
+				"error": "record 1: field name have invalid type",
+			},
+		},
+		Case{
+			Path:  "/authors",
+			Query: "fields=name&order_by=-id&limit=1",
+			Result: CR{
+				"response": CR{
+					"total": 5,
+					"records": []CR{
+						CR{"name": "second"},
+					},
+				},
+			},
+		},
+		Case{
+			Path: "/articles/3",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":        3,
+						"title":     "new",
+						"author_id": 3,
+					},
+				},
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestPolicy(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+	handler.SetPolicy(&Policy{
+		Keys: map[string]APIKey{
+			"reader-key": {Name: "dashboard", Role: "reader"},
+			"admin-key":  {Name: "ops", Role: "admin"},
+		},
+		Anonymous: "guest",
+		Roles: map[string]Role{
+			"guest": {Tables: map[string]TablePolicy{
+				"items": {Operations: []string{opRead}, Columns: []string{"id", "title"}},
+			}},
+			"reader": {Tables: map[string]TablePolicy{
+				"users": {Operations: []string{opRead, opUpdate}, Hidden: []string{"password"}, ReadOnly: []string{"login"}},
+				"*":     {Operations: []string{opRead, opCreate}},
+			}},
+			"admin": {Admin: true, Tables: map[string]TablePolicy{
+				"*": {Operations: []string{opRead, opCreate, opUpdate, opDelete}},
+			}},
+		},
+	})
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	reader := map[string]string{"X-Api-Key": "reader-key"}
+	admin := map[string]string{"X-Api-Key": "admin-key"}
+
+	cases := []Case{
+		Case{
+			Path:    "/",
+			Headers: map[string]string{"X-Api-Key": "bad"},
+			Status:  http.StatusUnauthorized,
+			Result:  CR{"error": "unauthorized"},
+		},
+		// анонимный доступ - только часть колонок items
+		Case{
+			Path: "/",
+			Result: CR{
+				"response": CR{"tables": []string{"items"}},
+			},
+		},
+		Case{
+			Path: "/items/1",
+			Result: CR{
+				"response": CR{"record": CR{"id": 1, "title": "database/sql"}},
+			},
+		},
+		Case{
+			Path:   "/items",
+			Query:  "description=Рассказать",
+			Status: http.StatusBadRequest,
+			Result: CR{"error": "unknown field description"},
+		},
+		Case{
+			Path:   "/users",
+			Status: http.StatusForbidden,
+			Result: CR{"error": "access denied"},
+		},
+		Case{
+			Path:   "/_schema",
+			Status: http.StatusForbidden,
+			Result: CR{"error": "access denied"},
+		},
+		// reader не видит пароль и не может менять login
+		Case{
+			Path:    "/users/1",
+			Headers: reader,
+			Result: CR{
+				"response": CR{"record": CR{
+					"user_id": 1,
+					"login":   "rvasily",
+					"email":   "rvasily@example.com",
+					"info":    "none",
+					"updated": nil,
+				}},
+			},
+		},
+		Case{
+			Path:    "/users",
+			Query:   "order_by=password",
+			Headers: reader,
+			Status:  http.StatusBadRequest,
+			Result:  CR{"error": "unknown field password"},
+		},
+		Case{
+			Path:    "/users/1",
+			Method:  http.MethodPost,
+			Headers: reader,
+			Body:    CR{"login": "admin"},
+			Status:  http.StatusForbidden,
+			Result:  CR{"error": "field login is read only"},
+		},
+		Case{
+			Path:    "/users/1",
+			Method:  http.MethodPost,
+			Headers: reader,
+			Body:    CR{"password": "secret"},
+			Status:  http.StatusForbidden,
+			Result:  CR{"error": "field password is read only"},
+		},
+		Case{
+			Path:    "/users/1",
+			Method:  http.MethodPost,
+			Headers: reader,
+			Body:    CR{"info": "updated"},
+			Result:  CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:    "/users/1",
+			Method:  http.MethodDelete,
+			Headers: reader,
+			Status:  http.StatusForbidden,
+			Result:  CR{"error": "access denied"},
+		},
+		Case{
+			Path:    "/items/",
+			Method:  http.MethodPut,
+			Headers: reader,
+			Body:    CR{"title": "db_explorer", "description": ""},
+			Result:  CR{"response": CR{"id": 3}},
+		},
+		Case{
+			Path:    "/items/3",
+			Method:  http.MethodDelete,
+			Headers: reader,
+			Status:  http.StatusForbidden,
+			Result:  CR{"error": "access denied"},
+		},
+		// права проверяются и внутри /_batch: вся пачка откатывается
+		Case{
+			Path:    "/_batch",
+			Method:  http.MethodPost,
+			Headers: reader,
+			Body: CR{"operations": []CR{
+				CR{"op": "create", "table": "items", "data": CR{"title": "x", "description": ""}},
+				CR{"op": "delete", "table": "items", "id": 1},
+			}},
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": "operation 1: access denied",
+				"results": []CR{
+					CR{"status": "rolled_back"},
+					CR{"status": "error", "error": "access denied"},
+				},
+			},
+		},
+		Case{
+			Path:    "/items/3",
+			Method:  http.MethodDelete,
+			Headers: admin,
+			Result:  CR{"response": CR{"deleted": 1}},
+		},
+		Case{
+			Path:    "/users/1",
+			Headers: admin,
+			Result: CR{
+				"response": CR{"record": CR{
+					"user_id":  1,
+					"login":    "rvasily",
+					"password": "love",
+					"email":    "rvasily@example.com",
+					"info":     "updated",
+					"updated":  nil,
+				}},
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestExportImport(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+	// по строке за запрос, чтобы проверить переход между страницами
+	handler.exportPageSize = 1
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	do := func(method, path, contentType, body string) (int, string) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	exports := []struct {
+		Query  string
+		Status int
+		Body   string
+	}{
+		{
+			Query:  "format=csv",
+			Status: http.StatusOK,
+			Body: "id,title,description,updated\n" +
+				"1,database/sql,Рассказать про базы данных,rvasily\n" +
+				"2,memcache,Рассказать про мемкеш с примером использования,\n",
+		},
+		{
+			Query:  "format=ndjson&fields=title&id__gt=1",
+			Status: http.StatusOK,
+			Body:   "{\"title\":\"memcache\"}\n",
+		},
+		{
+			Query:  "format=ndjson&updated=nobody",
+			Status: http.StatusOK,
+			Body:   "",
+		},
+		{
+			Query:  "format=xml",
+			Status: http.StatusBadRequest,
+			Body:   "{\"error\":\"unknown format xml\"}\n",
+		},
+		{
+			Query:  "format=csv&limit=1",
+			Status: http.StatusBadRequest,
+			Body:   "{\"error\":\"limit is not supported with format\"}\n",
+		},
+	}
+	for _, item := range exports {
+		status, body := do(http.MethodGet, "/items?"+item.Query, "", "")
+		if status != item.Status || body != item.Body {
+			t.Errorf("[GET /items?%s] expected %d %q, got %d %q", item.Query, item.Status, item.Body, status, body)
+		}
+	}
+
+	imports := []struct {
+		Name        string
+		ContentType string
+		Body        string
+		Status      int
+		Result      interface{}
+	}{
+		{
+			Name:        "csv",
+			ContentType: "text/csv",
+			Body:        "id,title,description,updated\n10,csv,\"многострочное\nописание\",\n11,csv2,,x\n",
+			Status:      http.StatusOK,
+			Result:      CR{"response": CR{"imported": 2}},
+		},
+		{
+			Name:        "ndjson with errors",
+			ContentType: "application/x-ndjson",
+			Body: `{"title": "ok", "description": ""}` + "\n" +
+				`{"title": 42, "description": ""}` + "\n" +
+				"\n" +
+				`{"title": "broken"` + "\n" +
+				`{"title": "x", "description": "", "author": "me"}` + "\n",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "3 lines have errors",
+				"errors": []CR{
+					CR{"line": 2, "error": "field title have invalid type"},
+					CR{"line": 4, "error": "cant unpack json"},
+					CR{"line": 5, "error": "unknown field author"},
+				},
+			},
+		},
+		{
+			Name:        "csv with errors",
+			ContentType: "text/csv; charset=utf-8",
+			Body:        "id,title,description\nabc,x,y\n21,x\n",
+			Status:      http.StatusBadRequest,
+			Result: CR{
+				"error": "2 lines have errors",
+				"errors": []CR{
+					CR{"line": 2, "error": "field id have invalid type"},
+					CR{"line": 3, "error": "wrong number of fields"},
+				},
+			},
+		},
+		{
+			Name:        "csv unknown column",
+			ContentType: "text/csv",
+			Body:        "id,name\n1,x\n",
+			Status:      http.StatusBadRequest,
+			Result:      CR{"error": "unknown field name"},
+		},
+		{
+			Name:   "no format",
+			Body:   "{}",
+			Status: http.StatusBadRequest,
+			Result: CR{"error": "unknown format, use text/csv or application/x-ndjson"},
+		},
+	}
+	for _, item := range imports {
+		status, body := do(http.MethodPost, "/items/_import", item.ContentType, item.Body)
+		var result, expected interface{}
+		json.Unmarshal([]byte(body), &result)
+		data, _ := json.Marshal(item.Result)
+		json.Unmarshal(data, &expected)
+		if status != item.Status || !reflect.DeepEqual(result, expected) {
+			t.Errorf("[import %s] expected %d %#v, got %d %s", item.Name, item.Status, expected, status, body)
+		}
+	}
+
+	// ключи из файла сохранились, а из неудачных загрузок не вставилось ничего
+	_, body := do(http.MethodGet, "/items?format=csv&fields=id,description,updated&id__gte=10", "", "")
+	expected := "id,description,updated\n10,\"многострочное\nописание\",\n11,,x\n"
+	if body != expected {
+		t.Errorf("expected %q after import, got %q", expected, body)
+	}
+
+	if db.Stats().OpenConnections != 1 {
+		t.Fatalf("you have %d open connections, must be 1", db.Stats().OpenConnections)
+	}
+}
+
+func TestCSVField(t *testing.T) {
+	cases := []struct {
+		Type   string
+		Value  string
+		Result interface{}
+		Error  string
+	}{
+		// BIGINT из CSV не проходит через float64
+		{Type: "bigint", Value: "9007199254740993", Result: int64(9007199254740993)},
+		{Type: "bigint", Value: "9223372036854775808", Error: "field f must be between -9223372036854775808 and 9223372036854775807"},
+		{Type: "bigint(20) unsigned", Value: "18446744073709551615", Result: uint64(18446744073709551615)},
+		{Type: "int(11)", Value: "42.0", Result: int64(42)},
+		{Type: "int(11)", Value: "x", Error: "field f have invalid type"},
+		{Type: "double", Value: "0.25", Result: 0.25},
+		{Type: "json", Value: `{"n":12345678901234567890}`, Result: `{"n":12345678901234567890}`},
+	}
+	for _, item := range cases {
+		col := Column{Name: "f", Type: item.Type}
+		var got interface{}
+		var err error
+		if val, ok := csvField(col, item.Value); ok {
+			got, err = coerceValue(col, val)
+		} else {
+			err = fieldError(col, "have invalid type")
+		}
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+		}
+		if errText != item.Error || (err == nil && !reflect.DeepEqual(got, item.Result)) {
+			t.Errorf("[%s %s] expected %#v %q, got %#v %q", item.Type, item.Value, item.Result, item.Error, got, errText)
+		}
+	}
+}
+
+var testTypedSchemas = map[string]string{
+	"mysql": `CREATE TABLE typed (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  price decimal(10,2) NOT NULL,
+  active tinyint(1) NOT NULL,
+  born date DEFAULT NULL,
+  seen datetime DEFAULT NULL,
+  code varchar(5) DEFAULT NULL,
+  meta json DEFAULT NULL,
+  data blob,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+	"sqlite3": `CREATE TABLE typed (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  price DECIMAL(10,2) NOT NULL,
+  active BOOLEAN NOT NULL,
+  born DATE,
+  seen DATETIME,
+  code VARCHAR(5),
+  meta JSON,
+  data BLOB
+);`,
+}
+
+func TestTypes(t *testing.T) {
+	db := openTestDB(t)
+
+	for _, q := range []string{`DROP TABLE IF EXISTS typed;`, testTypedSchemas[testDriver()]} {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS typed;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []Case{
+		Case{
+			Path:   "/typed/",
+			Method: http.MethodPut,
+			Body: CR{
+				"price":  "12.5",
+				"active": true,
+				"born":   "1990-05-17",
+				"seen":   "2020-01-02T06:04:05+03:00",
+				"code":   "abc",
+				"meta":   CR{"tags": []string{"x"}},
+				"data":   "aGVsbG8=",
+			},
+			Result: CR{"response": CR{"id": 1}},
+		},
+		Case{
+			Path: "/typed/1",
+			Result: CR{
+				"response": CR{"record": CR{
+					"id":     1,
+					"price":  12.5,
+					"active": true,
+					"born":   "1990-05-17",
+					"seen":   "2020-01-02T03:04:05Z",
+					"code":   "abc",
+					"meta":   CR{"tags": []string{"x"}},
+					"data":   "aGVsbG8=",
+				}},
+			},
+		},
+		Case{
+			Path:   "/typed/1",
+			Method: http.MethodPost,
+			Body:   CR{"active": false, "meta": []int{1, 2}, "data": nil},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path: "/typed/1",
+			Result: CR{
+				"response": CR{"record": CR{
+					"id":     1,
+					"price":  12.5,
+					"active": false,
+					"born":   "1990-05-17",
+					"seen":   "2020-01-02T03:04:05Z",
+					"code":   "abc",
+					"meta":   []int{1, 2},
+					"data":   nil,
+				}},
+			},
+		},
+	}
+
+	// ошибки валидации - по каждому полю своё сообщение
+	invalid := []struct {
+		Body  CR
+		Error string
+	}{
+		{CR{"price": 123456789.5}, "field price must have at most 8 digits before the decimal point"},
+		{CR{"price": "1.234"}, "field price must have at most 2 digits after the decimal point"},
+		{CR{"price": true}, "field price have invalid type"},
+		{CR{"active": "yes"}, "field active have invalid type"},
+		{CR{"born": "17.05.1990"}, "field born must be a date in format YYYY-MM-DD"},
+		{CR{"seen": "yesterday"}, "field seen must be a date and time in RFC3339 format"},
+		{CR{"code": "toolong"}, "field code is longer than 5 characters"},
+		{CR{"data": "not base64!"}, "field data must be base64 encoded"},
+	}
+	for _, item := range invalid {
+		cases = append(cases, Case{
+			Path:   "/typed/1",
+			Method: http.MethodPost,
+			Body:   item.Body,
+			Status: http.StatusBadRequest,
+			Result: CR{"error": item.Error},
+		})
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestColumnTypes(t *testing.T) {
+	types := []struct {
+		SQLType string
+		Type    columnType
+	}{
+		{"int(11) unsigned", columnType{kind: kindInt, unsigned: true, bits: 32}},
+		{"tinyint(1)", columnType{kind: kindBool}},
+		{"tinyint(4)", columnType{kind: kindInt, bits: 8}},
+		{"year", columnType{kind: kindInt}},
+		{"character varying(255)", columnType{kind: kindString, length: 255}},
+		{"decimal(10,2) unsigned", columnType{kind: kindDecimal, unsigned: true, precision: 10, scale: 2}},
+		{"timestamp without time zone", columnType{kind: kindDateTime}},
+		{"double precision", columnType{kind: kindFloat}},
+		{"enum('New','in ''progress''')", columnType{kind: kindEnum, members: []string{"New", "in 'progress'"}}},
+		{"point", columnType{kind: kindOther}},
+	}
+	for _, item := range types {
+		if got := parseColumnType(item.SQLType); !reflect.DeepEqual(got, item.Type) {
+			t.Errorf("[%s] expected %+v, got %+v", item.SQLType, item.Type, got)
+		}
+	}
+
+	values := []struct {
+		Type   string
+		Value  interface{}
+		Result interface{}
+		Error  string
+	}{
+		{Type: "enum('a','b')", Value: "b", Result: "b"},
+		{Type: "enum('a','b')", Value: "c", Error: "field f must be one of a, b"},
+		{Type: "set('a','b','c')", Value: []interface{}{"a", "c"}, Result: "a,c"},
+		{Type: "set('a','b','c')", Value: "a,d", Error: "field f must contain only a, b, c"},
+		{Type: "int(11) unsigned", Value: -1.0, Error: "field f must not be negative"},
+		{Type: "int(11)", Value: 1.5, Error: "field f must be an integer"},
+		{Type: "bigint", Value: 42.0, Result: int64(42)},
+		{Type: "tinyint(4)", Value: 127.0, Result: int64(127)},
+		{Type: "tinyint(4)", Value: 128.0, Error: "field f must be between -128 and 127"},
+		{Type: "tinyint(3) unsigned", Value: 255.0, Result: int64(255)},
+		{Type: "tinyint(3) unsigned", Value: 256.0, Error: "field f must be between 0 and 255"},
+		{Type: "smallint", Value: -32769.0, Error: "field f must be between -32768 and 32767"},
+		{Type: "int(11)", Value: 2147483648.0, Error: "field f must be between -2147483648 and 2147483647"},
+		{Type: "int(10) unsigned", Value: 4294967295.0, Result: int64(4294967295)},
+		// тело запроса разбирается с UseNumber: BIGINT проверяется точно, без float64
+		{Type: "bigint", Value: json.Number("9007199254740993"), Result: int64(9007199254740993)},
+		{Type: "bigint", Value: json.Number("-9223372036854775808"), Result: int64(-9223372036854775808)},
+		{Type: "bigint", Value: json.Number("9223372036854775808"), Error: "field f must be between -9223372036854775808 and 9223372036854775807"},
+		{Type: "bigint(20) unsigned", Value: json.Number("18446744073709551615"), Result: uint64(18446744073709551615)},
+		{Type: "bigint(20) unsigned", Value: json.Number("18446744073709551616"), Error: "field f must be between 0 and 18446744073709551615"},
+		{Type: "bigint(20) unsigned", Value: json.Number("-1"), Error: "field f must not be negative"},
+		{Type: "int(11)", Value: json.Number("2147483648"), Error: "field f must be between -2147483648 and 2147483647"},
+		{Type: "int(11)", Value: json.Number("4.2e1"), Result: int64(42)},
+		{Type: "int(11)", Value: json.Number("1e400"), Error: "field f must be between -2147483648 and 2147483647"},
+		{Type: "int(11)", Value: json.Number("1.5"), Error: "field f must be an integer"},
+		{Type: "bigint", Value: json.Number("9.007199254740993e15"), Error: "field f must be an integer"},
+		{Type: "tinyint(1)", Value: json.Number("1"), Result: true},
+		{Type: "decimal(30,2)", Value: json.Number("123456789012345678901234.56"), Result: "123456789012345678901234.56"},
+		{Type: "double", Value: json.Number("0.5"), Result: 0.5},
+		{Type: "decimal(10,2)", Value: "-12.50", Result: "-12.50"},
+		{Type: "decimal(10,2)", Value: "NaN", Error: "field f have invalid type"},
+		{Type: "decimal(10,2)", Value: "Inf", Error: "field f have invalid type"},
+		{Type: "decimal(10,2)", Value: "0x1p-2", Error: "field f have invalid type"},
+		{Type: "decimal(10,2)", Value: "1e3", Error: "field f have invalid type"},
+		{Type: "decimal(10,2)", Value: "+1", Error: "field f have invalid type"},
+		{Type: "decimal(10,2)", Value: "1.", Error: "field f have invalid type"},
+		{Type: "decimal", Value: " 1", Error: "field f have invalid type"},
+		{Type: "time", Value: "25:00:00", Error: "field f must be a time in format HH:MM:SS"},
+		{Type: "json", Value: "text", Result: `"text"`},
+		{Type: "point", Value: "POINT(1 2)", Result: "POINT(1 2)"},
+	}
+	for _, item := range values {
+		got, err := coerceValue(Column{Name: "f", Type: item.Type}, item.Value)
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+		}
+		if errText != item.Error || (err == nil && !reflect.DeepEqual(got, item.Result)) {
+			t.Errorf("[%s %v] expected %#v %q, got %#v %q", item.Type, item.Value, item.Result, item.Error, got, errText)
+		}
+	}
+
+	// в SQLite INTEGER всегда 64-битный, как бы тип ни был записан
+	if _, err := coerceValue(Column{Name: "f", Type: "int(11)", intBits: 64}, 1e12); err != nil {
+		t.Errorf("sqlite int: unexpected error %v", err)
+	}
+
+	// у дат, времени и JSON пустого значения нет - NOT NULL поле без значения обязательно
+	for _, sqlType := range []string{"date", "datetime", "time", "json"} {
+		if _, ok := parseColumnType(sqlType).zeroValue(); ok {
+			t.Errorf("[%s] expected no zero value", sqlType)
+		}
+	}
+	for _, sqlType := range []string{"varchar(5)", "int", "decimal(10,2)", "tinyint(1)", "set('a')"} {
+		if _, ok := parseColumnType(sqlType).zeroValue(); !ok {
+			t.Errorf("[%s] expected zero value", sqlType)
+		}
+	}
+}
+
+// pgSyntaxDialect - запросы строятся как для postgres, а схема читается из SQLite:
+// SQLite понимает "кавычки", плейсхолдеры $n и RETURNING, так что синтаксис postgres
+// проверяется без сервера postgres
+type pgSyntaxDialect struct {
+	PostgresDialect
+	schema SQLiteDialect
+}
+
+func (d pgSyntaxDialect) Tables(ctx context.Context, q querier) ([]string, error) {
+	return d.schema.Tables(ctx, q)
+}
+
+func (d pgSyntaxDialect) Columns(ctx context.Context, q querier, table string) ([]Column, error) {
+	return d.schema.Columns(ctx, q, table)
+}
+
+func (d pgSyntaxDialect) ForeignKeys(ctx context.Context, q querier, table string) ([]ForeignKey, error) {
+	return d.schema.ForeignKeys(ctx, q, table)
+}
+
+func TestPostgresDialect(t *testing.T) {
+	d := PostgresDialect{}
+	for name, quoted := range map[string]string{
+		"items":       `"items"`,
+		"Mixed Case":  `"Mixed Case"`,
+		`we"ird`:      `"we""ird"`,
+		`"; DROP --`:  `"""; DROP --"`,
+		"user_id":     `"user_id"`,
+		"таблица":     `"таблица"`,
+		"":            `""`,
+		`""`:          `""""""`,
+		"a`b":         "\"a`b\"",
+		"back\\slash": `"back\slash"`,
+	} {
+		if got := d.QuoteIdent(name); got != quoted {
+			t.Errorf("QuoteIdent(%q): expected %s, got %s", name, quoted, got)
+		}
+	}
+	for n, placeholder := range map[int]string{1: "$1", 2: "$2", 10: "$10", 123: "$123"} {
+		if got := d.Placeholder(n); got != placeholder {
+			t.Errorf("Placeholder(%d): expected %s, got %s", n, placeholder, got)
+		}
+	}
+
+	// плейсхолдеры нумеруются в порядке аргументов, имена колонок в двойных кавычках
+	items := Table{Name: "items", Columns: []Column{
+		{Name: "id", Type: "integer", Key: "PRI", Extra: "auto_increment"},
+		{Name: "title", Type: "character varying(255)"},
+	}}
+	lq, err := parseListQuery(d, items, url.Values{
+		"fields":   {"id,title"},
+		"id__gt":   {"1"},
+		"title":    {"a", "b"},
+		"order_by": {"-title"},
+		"limit":    {"2"},
+		"offset":   {"4"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSelect := `SELECT "id", "title" FROM "items" WHERE "id" > $1 AND "title" = $2 AND "title" = $3 ORDER BY "title" DESC LIMIT 2 OFFSET 4`
+	if got := lq.selectSQL("items"); got != expectedSelect {
+		t.Errorf("select:\nexpected %s\ngot      %s", expectedSelect, got)
+	}
+	expectedCount := `SELECT COUNT(*) FROM "items" WHERE "id" > $1 AND "title" = $2 AND "title" = $3`
+	if got := lq.countSQL("items"); got != expectedCount {
+		t.Errorf("count:\nexpected %s\ngot      %s", expectedCount, got)
+	}
+	if !reflect.DeepEqual(lq.args.values, []interface{}{"1", "a", "b"}) {
+		t.Errorf("unexpected args %v", lq.args.values)
+	}
+
+	if got := d.AuditTableSQL(auditTable); !strings.HasPrefix(got, `CREATE TABLE IF NOT EXISTS "`+auditTable+`" (id BIGSERIAL PRIMARY KEY, `) {
+		t.Errorf("unexpected audit table sql %s", got)
+	}
+
+	// типы из information_schema.columns
+	for sqlType, expected := range map[string]columnType{
+		"integer":                  {kind: kindInt, bits: 32},
+		"smallint":                 {kind: kindInt, bits: 16},
+		"bigint":                   {kind: kindInt, bits: 64},
+		"numeric":                  {kind: kindDecimal},
+		"boolean":                  {kind: kindBool},
+		"real":                     {kind: kindFloat},
+		"text":                     {kind: kindString},
+		"character(3)":             {kind: kindString, length: 3},
+		"date":                     {kind: kindDate},
+		"timestamp with time zone": {kind: kindDateTime},
+		"time without time zone":   {kind: kindTime},
+		"jsonb":                    {kind: kindJSON},
+		"bytea":                    {kind: kindBlob},
+		"uuid":                     {kind: kindOther},
+	} {
+		if got := parseColumnType(sqlType); !reflect.DeepEqual(got, expected) {
+			t.Errorf("[%s] expected %+v, got %+v", sqlType, expected, got)
+		}
+	}
+
+	if testDriver() != "sqlite3" {
+		return
+	}
+
+	// INSERT ... RETURNING, UPDATE и DELETE с плейсхолдерами $n на настоящей базе
+	db := openTestDB(t)
+	PrepareTestApis(db)
+	handler, err := NewDbExplorerWithDialect(db, pgSyntaxDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path:   "/items/",
+			Method: http.MethodPut,
+			Body:   CR{"title": "postgres", "description": "dollar"},
+			Result: CR{"response": CR{"id": 3}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Body:   CR{"title": "pg", "updated": "x"},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:  "/items",
+			Query: "id__gte=2&title__ne=memcache&fields=id,title,updated",
+			Result: CR{"response": CR{
+				"total":   1,
+				"records": []CR{CR{"id": 3, "title": "pg", "updated": "x"}},
+			}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 1}},
+		},
+	})
+}
+
+func TestAudit(t *testing.T) {
+	db := openTestDB(t)
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+	defer db.Exec(`DROP TABLE IF EXISTS ` + auditTable)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+	if err := handler.EnableAudit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	handler.SetPolicy(&Policy{
+		Keys: map[string]APIKey{"admin-key": {Name: "ops", Role: "admin"}},
+		Roles: map[string]Role{
+			"admin": {Admin: true, Tables: map[string]TablePolicy{
+				"*": {Operations: []string{opRead, opCreate, opUpdate, opDelete}},
+			}},
+		},
+	})
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	admin := map[string]string{"X-Api-Key": "admin-key"}
+
+	// журнал не виден как обычная таблица
+	cases := []Case{
+		Case{
+			Path:    "/",
+			Headers: admin,
+			Result:  CR{"response": CR{"tables": []string{"items", "users"}}},
+		},
+		Case{
+			Path:    "/items/",
+			Method:  http.MethodPut,
+			Headers: admin,
+			Body:    CR{"title": "audit", "description": "log"},
+			Result:  CR{"response": CR{"id": 3}},
+		},
+		Case{
+			Path:    "/items/3",
+			Method:  http.MethodPost,
+			Headers: admin,
+			Body:    CR{"title": "audited"},
+			Result:  CR{"response": CR{"updated": 1}},
+		},
+		// ничего не изменилось - в журнал не попадает
+		Case{
+			Path:    "/items/100",
+			Method:  http.MethodPost,
+			Headers: admin,
+			Body:    CR{"title": "nothing"},
+			Result:  CR{"response": CR{"updated": 0}},
+		},
+		Case{
+			Path:    "/items/3",
+			Method:  http.MethodDelete,
+			Headers: admin,
+			Result:  CR{"response": CR{"deleted": 1}},
+		},
+		// откаченная пачка - тоже
+		Case{
+			Path:    "/_batch",
+			Method:  http.MethodPost,
+			Headers: admin,
+			Body: CR{"operations": []CR{
+				CR{"op": "delete", "table": "items", "id": 1},
+				CR{"op": "update", "table": "items", "id": 2, "data": CR{"title": 1}},
+			}},
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "operation 1: field title have invalid type",
+				"results": []CR{
+					CR{"status": "rolled_back"},
+					CR{"status": "error", "error": "field title have invalid type"},
+				},
+			},
+		},
+	}
+	runCases(t, ts, db, cases)
+
+	getChanges := func(query string) (int, []Change, int64) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/_changes?"+query, nil)
+		req.Header.Set("X-Api-Key", "admin-key")
+		resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			Response struct {
+				Changes []Change `json:"changes"`
+				LastID  int64    `json:"last_id"`
+			} `json:"response"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Response.Changes, result.Response.LastID
+	}
+
+	status, changes, lastID := getChanges("")
+	if status != http.StatusOK || len(changes) != 3 || lastID != 3 {
+		t.Fatalf("expected 3 changes, got %d %+v last_id %d", status, changes, lastID)
+	}
+	expected := []struct {
+		Op, Before, After string
+	}{
+		{opCreate, "null", `{"description":"log","id":3,"title":"audit","updated":null}`},
+		{opUpdate, `{"description":"log","id":3,"title":"audit","updated":null}`, `{"description":"log","id":3,"title":"audited","updated":null}`},
+		{opDelete, `{"description":"log","id":3,"title":"audited","updated":null}`, "null"},
+	}
+	for i, c := range changes {
+		e := expected[i]
+		if c.Table != "items" || c.PK != "3" || c.Caller != "ops" || c.At == "" ||
+			c.Op != e.Op || string(c.Before) != e.Before || string(c.After) != e.After {
+			t.Errorf("change %d: expected %+v, got %+v (before %s, after %s)", i, e, c, c.Before, c.After)
+		}
+	}
+
+	if _, changes, lastID := getChanges("since=3"); len(changes) != 0 || lastID != 3 {
+		t.Errorf("expected no changes since 3, got %+v last_id %d", changes, lastID)
+	}
+	if _, changes, _ := getChanges("since=0&table=users"); len(changes) != 0 {
+		t.Errorf("expected no changes in users, got %+v", changes)
+	}
+	if status, _, _ := getChanges("since=abc"); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for bad since, got %d", status)
+	}
+
+	// long polling: запрос ждёт, пока не появится следующее изменение
+	done := make(chan []Change)
+	go func() {
+		_, changes, _ := getChanges("since=3&wait=5s")
+		done <- changes
+	}()
+	time.Sleep(100 * time.Millisecond)
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/items/2", nil)
+	req.Header.Set("X-Api-Key", "admin-key")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	select {
+	case changes := <-done:
+		if len(changes) != 1 || changes[0].ID != 4 || changes[0].Op != opDelete || changes[0].PK != "2" {
+			t.Errorf("expected delete of items/2, got %+v", changes)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("long polling request was not woken up by the change")
+	}
+
+	// без ключа журнал недоступен
+	handler.SetPolicy(&Policy{
+		Anonymous: "guest",
+		Roles:     map[string]Role{"guest": {Tables: map[string]TablePolicy{"*": {Operations: []string{opRead}}}}},
+	})
+	resp, err = client.Get(ts.URL + "/_changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for guest, got %d", resp.StatusCode)
+	}
+
+	// без политики ключа нет, в журнал пишется адрес клиента
+	handler.SetPolicy(nil)
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/items/1", nil)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, changes, _ := getChanges("since=4"); len(changes) != 1 || changes[0].PK != "1" || changes[0].Caller != "127.0.0.1" {
+		t.Errorf("expected delete of items/1 by 127.0.0.1, got %+v", changes)
+	}
+}
+
+func runCases(t *testing.T, ts *httptest.Server, db *sql.DB, cases []Case) {
+	for idx, item := range cases {
+		var (
+			err      error
+			result   interface{}
+			expected interface{}
+			req      *http.Request
+		)
+		/*if idx != 26 {
+			continue
+		}*/
+		caseName := fmt.Sprintf("case %d: [%s] %s %s", idx, item.Method, item.Path, item.Query)
+
+		// если у вас случилась это ошибка - значит вы не делаете где-то rows.Close и у вас текут соединения с базой
+		// если такое случилось на первом тесте - значит вы не закрываете коннект где-то при иницаилизации в NewDbExplorer
+		if db.Stats().OpenConnections != 1 {
+			t.Fatalf("[%s] you have %d open connections, must be 1", caseName, db.Stats().OpenConnections)
+		}
+
+		if item.Method == "" || item.Method == http.MethodGet {
+			req, err = http.NewRequest(item.Method, ts.URL+item.Path+"?"+item.Query, nil)
+		} else {
+			data, err := json.Marshal(item.Body)
+			if err != nil {
+				panic(err)
+			}
+			reqBody := bytes.NewReader(data)
+			req, err = http.NewRequest(item.Method, ts.URL+item.Path, reqBody)
+			req.Header.Add("Content-Type", "application/json")
+		}
+		for k, v := range item.Headers {
+			req.Header.Set(k, v)
 		}
 
 		resp, err := client.Do(req)
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// операции, на которые выдаются права
+const (
+	opRead   = "read"
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// Policy - права доступа к таблицам и колонкам, читается из JSON-файла:
+//
+//	{
+//	  "keys": {"secret": {"name": "dashboard", "role": "reader"}},
+//	  "anonymous": "",
+//	  "roles": {
+//	    "reader": {"tables": {
+//	      "users": {"operations": ["read"], "hidden": ["password"]},
+//	      "*": {"operations": ["read", "update"], "read_only": ["updated"]}
+//	    }},
+//	    "admin": {"admin": true, "tables": {"*": {"operations": ["read", "create", "update", "delete"]}}}
+//	  }
+//	}
+//
+// Ключ передаётся в заголовке X-Api-Key. Запросы без ключа получают роль anonymous,
+// если она задана, иначе 401.
+type Policy struct {
+	Keys      map[string]APIKey `json:"keys"`
+	Roles     map[string]Role   `json:"roles"`
+	Anonymous string            `json:"anonymous"`
+}
+
+type APIKey struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type Role struct {
+	// Admin разрешает служебные эндпоинты /_schema и /_admin/*
+	Admin bool `json:"admin"`
+	// Tables - права по таблицам, "*" - для всех остальных
+	Tables map[string]TablePolicy `json:"tables"`
+}
+
+type TablePolicy struct {
+	Operations []string `json:"operations"`
+	// Columns - если задано, видны только эти колонки
+	Columns []string `json:"columns"`
+	// Hidden - колонки, которые не отдаются и не принимаются
+	Hidden []string `json:"hidden"`
+	// ReadOnly - колонки, которые можно читать, но не писать
+	ReadOnly []string `json:"read_only"`
+}
+
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("bad policy %s: %w", path, err)
+	}
+	return p, p.validate()
+}
+
+func (p *Policy) validate() error {
+	for key, k := range p.Keys {
+		if _, ok := p.Roles[k.Role]; !ok {
+			return fmt.Errorf("key %q: unknown role %q", k.Name, k.Role)
+		}
+		if k.Name == "" {
+			k.Name = k.Role
+			p.Keys[key] = k
+		}
+	}
+	if _, ok := p.Roles[p.Anonymous]; p.Anonymous != "" && !ok {
+		return fmt.Errorf("anonymous: unknown role %q", p.Anonymous)
+	}
+	for roleName, role := range p.Roles {
+		for table, tp := range role.Tables {
+			for _, op := range tp.Operations {
+				switch op {
+				case opRead, opCreate, opUpdate, opDelete:
+				default:
+					return fmt.Errorf("role %s, table %s: unknown operation %q", roleName, table, op)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// caller - тот, от чьего имени выполняется запрос
+type caller struct {
+	Name string
+	Role string
+	// Addr - адрес клиента без порта, по нему журнал узнаёт того, кто пришёл без ключа
+	Addr string
+	// key - вызывающий предъявил ключ, Name - имя ключа
+	key bool
+	// role == nil - политика не настроена, можно всё
+	role *Role
+}
+
+type callerKey struct{}
+
+func withCaller(ctx context.Context, c *caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+func callerFrom(ctx context.Context) *caller {
+	if c, ok := ctx.Value(callerKey{}).(*caller); ok {
+		return c
+	}
+	return &caller{}
+}
+
+// SetPolicy включает проверку прав, nil - выключает
+func (d *DbExplorer) SetPolicy(p *Policy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policy = p
+}
+
+// authenticate определяет вызывающего по X-Api-Key
+func (d *DbExplorer) authenticate(r *http.Request) (*caller, error) {
+	d.mu.RLock()
+	p := d.policy
+	d.mu.RUnlock()
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if p == nil {
+		return &caller{Addr: addr}, nil
+	}
+
+	key := r.Header.Get("X-Api-Key")
+	if key == "" {
+		if p.Anonymous == "" {
+			return nil, &apiError{status: http.StatusUnauthorized, msg: "unauthorized"}
+		}
+		role := p.Roles[p.Anonymous]
+		return &caller{Name: "anonymous", Role: p.Anonymous, Addr: addr, role: &role}, nil
+	}
+	k, ok := p.Keys[key]
+	if !ok {
+		return nil, &apiError{status: http.StatusUnauthorized, msg: "unauthorized"}
+	}
+	role := p.Roles[k.Role]
+	return &caller{Name: k.Name, Role: k.Role, Addr: addr, key: true, role: &role}, nil
+}
+
+// identity - кто сделал изменение, для журнала: имя ключа, а без ключа - адрес клиента
+func (c *caller) identity() string {
+	if c.key {
+		return c.Name
+	}
+	return c.Addr
+}
+
+func (c *caller) tablePolicy(table string) (TablePolicy, bool) {
+	if tp, ok := c.role.Tables[table]; ok {
+		return tp, true
+	}
+	tp, ok := c.role.Tables["*"]
+	return tp, ok
+}
+
+func (c *caller) isAdmin() bool {
+	return c.role == nil || c.role.Admin
+}
+
+// canSee - видна ли таблица в списке `GET /`
+func (c *caller) canSee(table string) bool {
+	if c.role == nil {
+		return true
+	}
+	tp, ok := c.tablePolicy(table)
+	return ok && contains(tp.Operations, opRead)
+}
+
+func (c *caller) allow(table, op string) error {
+	if c.role == nil {
+		return nil
+	}
+	if tp, ok := c.tablePolicy(table); ok && contains(tp.Operations, op) {
+		return nil
+	}
+	return &apiError{status: http.StatusForbidden, msg: "access denied"}
+}
+
+func (c *caller) columnVisible(tp TablePolicy, column string) bool {
+	if len(tp.Columns) > 0 && !contains(tp.Columns, column) {
+		return false
+	}
+	return !contains(tp.Hidden, column)
+}
+
+// view - метаданные таблицы без скрытых колонок: по ним проверяются fields, фильтры и сортировка,
+// так что по скрытой колонке нельзя ни выбрать, ни отфильтровать
+func (c *caller) view(tableMeta Table) Table {
+	if c.role == nil {
+		return tableMeta
+	}
+	tp, _ := c.tablePolicy(tableMeta.Name)
+	view := Table{Name: tableMeta.Name}
+	for _, col := range tableMeta.Columns {
+		if c.columnVisible(tp, col.Name) {
+			view.Columns = append(view.Columns, col)
+		}
+	}
+	for _, fk := range tableMeta.ForeignKeys {
+		if c.columnVisible(tp, fk.Column) {
+			view.ForeignKeys = append(view.ForeignKeys, fk)
+		}
+	}
+	return view
+}
+
+// strip убирает из записей скрытые колонки, вызывается до expand
+func (c *caller) strip(table string, records []map[string]interface{}) {
+	if c.role == nil {
+		return
+	}
+	tp, _ := c.tablePolicy(table)
+	for _, rec := range records {
+		for name := range rec {
+			if !c.columnVisible(tp, name) {
+				delete(rec, name)
+			}
+		}
+	}
+}
+
+// checkWrite проверяет право на операцию и на запись каждой переданной колонки
+func (c *caller) checkWrite(table, op string, data map[string]interface{}) error {
+	if err := c.allow(table, op); err != nil {
+		return err
+	}
+	if c.role == nil {
+		return nil
+	}
+	tp, _ := c.tablePolicy(table)
+	for name := range data {
+		if !c.columnVisible(tp, name) || contains(tp.ReadOnly, name) {
+			return &apiError{status: http.StatusForbidden, msg: fmt.Sprintf("field %s is read only", name)}
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ForeignKey - ссылка Column этой таблицы на RefTable.RefColumn
+type ForeignKey struct {
+	Column    string `json:"column"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
+}
+
+// relationName - имя связи для ?expand=: author_id -> author.
+// Если у колонки нет суффикса _id, связанная запись подставляется вместо значения колонки.
+func (fk ForeignKey) relationName() string {
+	return strings.TrimSuffix(fk.Column, "_id")
+}
+
+// parseExpand проверяет список связей из ?expand=author,editor
+func parseExpand(tableMeta Table, expand string) ([]ForeignKey, error) {
+	if expand == "" {
+		return nil, nil
+	}
+	var fks []ForeignKey
+	for _, name := range strings.Split(expand, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, fk := range tableMeta.ForeignKeys {
+			if fk.relationName() == name || fk.Column == name {
+				fks = append(fks, fk)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown relation %s", name)
+		}
+	}
+	return fks, nil
+}
+
+// expandRecords подставляет в записи связанные строки.
+// На каждую связь уходит один запрос с IN, а не по запросу на запись.
+func (d *DbExplorer) expandRecords(ctx context.Context, records []map[string]interface{}, fks []ForeignKey) error {
+	c := callerFrom(ctx)
+	for _, fk := range fks {
+		refMeta, ok := d.table(fk.RefTable)
+		if !ok {
+			return fmt.Errorf("unknown table %s", fk.RefTable)
+		}
+		if err := c.allow(fk.RefTable, opRead); err != nil {
+			return err
+		}
+
+		args := &sqlArgs{dialect: d.dialect}
+		seen := map[string]bool{}
+		var placeholders []string
+		for _, rec := range records {
+			val, ok := rec[fk.Column]
+			if !ok {
+				return fmt.Errorf("field %s is required to expand %s", fk.Column, fk.relationName())
+			}
+			key := fmt.Sprint(val)
+			if val == nil || seen[key] {
+				continue
+			}
+			seen[key] = true
+			placeholders = append(placeholders, args.add(val))
+		}
+
+		related := map[string]map[string]interface{}{}
+		if len(placeholders) > 0 {
+			query := fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s)",
+				d.dialect.QuoteIdent(fk.RefTable), d.dialect.QuoteIdent(fk.RefColumn), strings.Join(placeholders, ", "))
+			rows, err := d.db.QueryContext(ctx, query, args.values...)
+			if err != nil {
+				return err
+			}
+			refRecords, err := readRecords(rows, refMeta)
+			rows.Close()
+			if err != nil {
+				return err
+			}
+			for _, ref := range refRecords {
+				related[fmt.Sprint(ref[fk.RefColumn])] = ref
+			}
+			c.strip(fk.RefTable, refRecords)
+		}
+
+		for _, rec := range records {
+			val := rec[fk.Column]
+			if val == nil {
+				rec[fk.relationName()] = nil
+				continue
+			}
+			if ref, ok := related[fmt.Sprint(val)]; ok {
+				rec[fk.relationName()] = ref
+			} else {
+				rec[fk.relationName()] = nil
+			}
+		}
+	}
+	return nil
+}
+
+// handleGetRelated обрабатывает `GET /users/5/items` - записи items, ссылающиеся на users/5
+func (d *DbExplorer) handleGetRelated(w http.ResponseWriter, r *http.Request, table, id, child string) {
+	childMeta, ok := d.table(child)
+	if !ok {
+		errorResponse(w, "unknown table", http.StatusNotFound)
+		return
+	}
+	c := callerFrom(r.Context())
+	if err := c.allow(child, opRead); err != nil {
+		writeError(w, err)
+		return
+	}
+	childView := c.view(childMeta)
+
+	var refs []ForeignKey
+	for _, fk := range childView.ForeignKeys {
+		if fk.RefTable == table {
+			refs = append(refs, fk)
+		}
+	}
+	switch len(refs) {
+	case 0:
+		errorResponse(w, "unknown relation", http.StatusNotFound)
+		return
+	case 1:
+	default:
+		errorResponse(w, fmt.Sprintf("ambiguous relation between %s and %s", child, table), http.StatusBadRequest)
+		return
+	}
+	fk := refs[0]
+
+	// сначала убеждаемся, что родительская запись существует
+	exists, err := d.recordExists(r.Context(), d.db, table, fk.RefColumn, id)
+	if err != nil {
+		errorResponse(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		errorResponse(w, "record not found", http.StatusNotFound)
+		return
+	}
+
+	lq, err := parseListQuery(d.dialect, childView, r.URL.Query())
+	if err != nil {
+		errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lq.addFilter(fk.Column, "=", id)
+	d.writeRecords(w, r, childMeta, lq)
+}
+
+func (d *DbExplorer) recordExists(ctx context.Context, q querier, table, column string, val interface{}) (bool, error) {
+	args := &sqlArgs{dialect: d.dialect}
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE %s = %s LIMIT 1",
+		d.dialect.QuoteIdent(table), d.dialect.QuoteIdent(column), args.add(val))
+	rows, err := q.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	return rows.Next(), rows.Err()
+}
+
+// checkForeignKeys проверяет, что значения внешних ключей ссылаются на существующие записи,
+// чтобы вместо ошибки драйвера вернуть понятную 400
+func (d *DbExplorer) checkForeignKeys(ctx context.Context, q querier, tableMeta Table, values map[string]interface{}) error {
+	for _, fk := range tableMeta.ForeignKeys {
+		val, ok := values[fk.Column]
+		if !ok || val == nil {
+			continue
+		}
+		exists, err := d.recordExists(ctx, q, fk.RefTable, fk.RefColumn, val)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return badRequest("field %s references unknown %s record", fk.Column, fk.RefTable)
+		}
+	}
+	return nil
+}
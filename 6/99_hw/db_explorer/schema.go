@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// SchemaChanges - отличия перечитанной схемы от предыдущей
+type SchemaChanges struct {
+	Added   []string `json:"added"`
+	Dropped []string `json:"dropped"`
+	Changed []string `json:"changed"`
+}
+
+func (c SchemaChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Dropped) == 0 && len(c.Changed) == 0
+}
+
+// table возвращает метаданные таблицы из текущей версии схемы
+func (d *DbExplorer) table(name string) (Table, bool) {
+	t, ok := d.schema()[name]
+	return t, ok
+}
+
+func (d *DbExplorer) schema() map[string]Table {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.tables
+}
+
+// loadSchema читает список таблиц, колонки и внешние ключи на одном соединении
+func (d *DbExplorer) loadSchema(ctx context.Context) (map[string]Table, error) {
+	// Получаем выделенное соединение
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// После чтения схемы возвращаем соединение в пул
+	defer conn.Close()
+
+	// Сначала полностью читаем список таблиц
+	tableNames, err := d.dialect.Tables(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Теперь, для каждой таблицы, получаем метаданные, используя то же соединение
+	tables := make(map[string]Table, len(tableNames))
+	for _, tableName := range tableNames {
+		if tableName == auditTable {
+			continue
+		}
+		// таблицу без метаданных не пропускаем: иначе после перечитывания она бы молча пропала
+		columns, err := d.dialect.Columns(ctx, conn, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("columns of %s: %w", tableName, err)
+		}
+		fks, err := d.dialect.ForeignKeys(ctx, conn, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("foreign keys of %s: %w", tableName, err)
+		}
+		tables[tableName] = Table{
+			Name:        tableName,
+			Columns:     columns,
+			ForeignKeys: fks,
+		}
+	}
+
+	// ссылки без явной колонки указывают на первичный ключ
+	for _, table := range tables {
+		for i, fk := range table.ForeignKeys {
+			if fk.RefColumn == "" {
+				table.ForeignKeys[i].RefColumn = getPrimaryKey(tables[fk.RefTable])
+			}
+		}
+	}
+
+	return tables, nil
+}
+
+// Reload перечитывает схему и атомарно подменяет её, запросы в процессе дорабатывают на старой.
+// Если схему не удалось прочитать целиком, остаётся прежняя.
+func (d *DbExplorer) Reload(ctx context.Context) (SchemaChanges, error) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	tables, err := d.loadSchema(ctx)
+	if err != nil {
+		return SchemaChanges{}, err
+	}
+
+	d.mu.Lock()
+	old := d.tables
+	d.tables = tables
+	d.mu.Unlock()
+
+	return diffSchema(old, tables), nil
+}
+
+// StartSchemaRefresher перечитывает схему раз в interval, пока не отменён ctx
+func (d *DbExplorer) StartSchemaRefresher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				changes, err := d.Reload(ctx)
+				if err != nil {
+					log.Printf("Ошибка перечитывания схемы: %v", err)
+					continue
+				}
+				if !changes.Empty() {
+					log.Printf("Схема изменилась: added %v, dropped %v, changed %v",
+						changes.Added, changes.Dropped, changes.Changed)
+				}
+			}
+		}
+	}()
+}
+
+func diffSchema(old, cur map[string]Table) SchemaChanges {
+	changes := SchemaChanges{
+		Added:   []string{},
+		Dropped: []string{},
+		Changed: []string{},
+	}
+	for name, table := range cur {
+		prev, ok := old[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, name)
+		case !reflect.DeepEqual(prev, table):
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			changes.Dropped = append(changes.Dropped, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Dropped)
+	sort.Strings(changes.Changed)
+	return changes
+}
+
+// handleSchema отдаёт `GET /_schema` - колонки, типы, nullable и ключи всех таблиц
+func (d *DbExplorer) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorResponse(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+	jsonResponse(w, map[string]interface{}{
+		"response": map[string]interface{}{
+			"tables": d.schema(),
+		},
+	})
+}
+
+// handleReload обрабатывает `POST /_admin/reload`
+func (d *DbExplorer) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errorResponse(w, "bad method", http.StatusMethodNotAllowed)
+		return
+	}
+	changes, err := d.Reload(r.Context())
+	if err != nil {
+		errorResponse(w, "failed to reload schema", http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, map[string]interface{}{
+		"response": changes,
+	})
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// typeKind - к чему сводится SQL-тип колонки
+type typeKind int
+
+const (
+	// kindOther - тип, который мы не знаем: принимаем строки и числа как есть
+	kindOther typeKind = iota
+	kindString
+	kindInt
+	kindFloat
+	kindDecimal
+	kindBool
+	kindDate
+	kindDateTime
+	kindTime
+	kindEnum
+	kindSet
+	kindJSON
+	kindBlob
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+	timeLayout     = "15:04:05"
+)
+
+// columnType - разобранный тип колонки: varchar(255), decimal(10,2), enum('a','b'), int(11) unsigned
+type columnType struct {
+	kind     typeKind
+	unsigned bool
+	// bits - разрядность целого типа, 0 - неизвестна, проверяется как 64-битная
+	bits int
+	// length - максимальная длина строки в символах, 0 - не ограничена
+	length int
+	// precision и scale для DECIMAL, 0 - не проверяются
+	precision int
+	scale     int
+	// members - допустимые значения ENUM и SET
+	members []string
+}
+
+func parseColumnType(sqlType string) columnType {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	base, params, rest := t, "", ""
+	if open := strings.Index(t, "("); open >= 0 {
+		if end := strings.LastIndex(t, ")"); end > open {
+			base, params, rest = strings.TrimSpace(t[:open]), t[open+1:end], t[end+1:]
+		}
+	}
+	// модификаторы после имени: "int unsigned", "timestamp without time zone"
+	fields := strings.Fields(base)
+	if len(fields) == 0 {
+		return columnType{}
+	}
+	ct := parseColumnTypeName(fields[0], sqlType, params)
+	ct.unsigned = contains(fields[1:], "unsigned") || contains(strings.Fields(rest), "unsigned")
+	return ct
+}
+
+// intTypeBits - разрядность целых типов MySQL и PostgreSQL, 0 - неизвестна;
+// у SQLite она своя, см. SQLiteDialect.Columns
+func intTypeBits(name string) int {
+	switch name {
+	case "smallint", "int2", "smallserial":
+		return 16
+	case "mediumint":
+		return 24
+	case "int", "integer", "int4", "serial":
+		return 32
+	case "bigint", "int8", "bigserial":
+		return 64
+	}
+	return 0
+}
+
+func parseColumnTypeName(name, sqlType, params string) columnType {
+	switch name {
+	case "tinyint":
+		if params == "1" {
+			return columnType{kind: kindBool}
+		}
+		return columnType{kind: kindInt, bits: 8}
+	case "bool", "boolean":
+		return columnType{kind: kindBool}
+	case "int", "integer", "smallint", "mediumint", "bigint", "int2", "int4", "int8",
+		"serial", "smallserial", "bigserial", "year":
+		return columnType{kind: kindInt, bits: intTypeBits(name)}
+	case "float", "float4", "float8", "double", "real": // double precision тоже сюда
+		return columnType{kind: kindFloat}
+	case "decimal", "numeric", "dec", "fixed":
+		ct := columnType{kind: kindDecimal}
+		parts := strings.Split(params, ",")
+		ct.precision, _ = strconv.Atoi(strings.TrimSpace(parts[0]))
+		if len(parts) > 1 {
+			ct.scale, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
+		}
+		return ct
+	case "date":
+		return columnType{kind: kindDate}
+	case "datetime", "timestamp", "timestamptz":
+		return columnType{kind: kindDateTime}
+	case "time", "timetz":
+		return columnType{kind: kindTime}
+	case "enum", "set":
+		ct := columnType{kind: kindEnum, members: parseMembers(enumParams(sqlType))}
+		if name == "set" {
+			ct.kind = kindSet
+		}
+		return ct
+	case "json", "jsonb":
+		return columnType{kind: kindJSON}
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "bytea":
+		return columnType{kind: kindBlob}
+	case "char", "varchar", "nchar", "nvarchar", "character", "varying":
+		ct := columnType{kind: kindString}
+		ct.length, _ = strconv.Atoi(strings.TrimSpace(params))
+		return ct
+	case "text", "tinytext", "mediumtext", "longtext", "clob", "citext":
+		return columnType{kind: kindString}
+	}
+	return columnType{kind: kindOther}
+}
+
+// enumParams достаёт значения из исходного типа, а не из приведённого к нижнему регистру
+func enumParams(sqlType string) string {
+	open, end := strings.Index(sqlType, "("), strings.LastIndex(sqlType, ")")
+	if open < 0 || end <= open {
+		return ""
+	}
+	return sqlType[open+1 : end]
+}
+
+// parseMembers разбирает 'a','b”c' -> [a b'c]
+func parseMembers(params string) []string {
+	var members []string
+	var cur strings.Builder
+	inQuote := false
+	for i := 0; i < len(params); i++ {
+		ch := params[i]
+		switch {
+		case ch == '\'' && inQuote && i+1 < len(params) && params[i+1] == '\'':
+			cur.WriteByte('\'')
+			i++
+		case ch == '\'':
+			if inQuote {
+				members = append(members, cur.String())
+				cur.Reset()
+			}
+			inQuote = !inQuote
+		case inQuote:
+			cur.WriteByte(ch)
+		}
+	}
+	return members
+}
+
+// ----------------
+
+// readValue приводит значение из базы к тому, что отдаётся в JSON
+func (ct columnType) readValue(val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	if ct.kind == kindBlob {
+		if b, ok := val.([]byte); ok {
+			return base64.StdEncoding.EncodeToString(b)
+		}
+		if s, ok := val.(string); ok {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		}
+		return val
+	}
+	if b, ok := val.([]byte); ok {
+		val = string(b)
+	}
+
+	switch ct.kind {
+	case kindInt:
+		switch v := val.(type) {
+		case string:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return n
+			}
+		case float64:
+			return int64(v)
+		}
+	case kindFloat:
+		if s, ok := val.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+		}
+	case kindDecimal:
+		// отдаём числом, но без потери точности через float64
+		switch v := val.(type) {
+		case string:
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				return json.Number(v)
+			}
+		case float64:
+			prec := -1
+			if ct.scale > 0 {
+				prec = ct.scale
+			}
+			return json.Number(strconv.FormatFloat(v, 'f', prec, 64))
+		case int64:
+			return json.Number(strconv.FormatInt(v, 10))
+		}
+	case kindBool:
+		switch v := val.(type) {
+		case int64:
+			return v != 0
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+	case kindDate:
+		switch v := val.(type) {
+		case time.Time:
+			return v.Format(dateLayout)
+		case string:
+			if len(v) >= len(dateLayout) {
+				if t, err := time.Parse(dateLayout, v[:len(dateLayout)]); err == nil {
+					return t.Format(dateLayout)
+				}
+			}
+		}
+	case kindDateTime:
+		switch v := val.(type) {
+		case time.Time:
+			return v.Format(time.RFC3339)
+		case string:
+			if t, ok := parseDateTime(v); ok {
+				return t.Format(time.RFC3339)
+			}
+		}
+	case kindSet:
+		if s, ok := val.(string); ok {
+			if s == "" {
+				return []string{}
+			}
+			return strings.Split(s, ",")
+		}
+	case kindJSON:
+		if s, ok := val.(string); ok && json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+	}
+	return val
+}
+
+// parseDateTime принимает DATETIME и TIMESTAMP в нескольких форматах, отдаются они в RFC3339
+func parseDateTime(s string) (time.Time, bool) {
+	layouts := []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05"}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ----------------
+
+// fieldError - значение не подходит колонке, текст уходит клиенту как 400
+func fieldError(col Column, format string, args ...interface{}) error {
+	return badRequest("field %s %s", col.Name, fmt.Sprintf(format, args...))
+}
+
+// coerceValue проверяет значение из JSON по типу колонки и приводит его к виду для записи в базу.
+// nil проверяется отдельно по Nullable.
+func coerceValue(col Column, val interface{}) (interface{}, error) {
+	ct := parseColumnType(col.Type)
+	if ct.kind == kindInt && col.intBits > 0 {
+		ct.bits = col.intBits
+	}
+	invalid := fieldError(col, "have invalid type")
+
+	switch ct.kind {
+	case kindString:
+		s, ok := val.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if ct.length > 0 && utf8.RuneCountInString(s) > ct.length {
+			return nil, fieldError(col, "is longer than %d characters", ct.length)
+		}
+		return s, nil
+
+	case kindInt:
+		n, ok := numberValue(val)
+		if !ok {
+			return nil, invalid
+		}
+		return ct.intValue(col, n.String())
+
+	case kindFloat:
+		n, ok := numberValue(val)
+		if !ok {
+			return nil, invalid
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, invalid
+		}
+		if ct.unsigned && f < 0 {
+			return nil, fieldError(col, "must not be negative")
+		}
+		return f, nil
+
+	case kindDecimal:
+		var s string
+		switch v := val.(type) {
+		case json.Number:
+			s = v.String()
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			// строкой можно передать значение точнее, чем позволяет float64
+			s = v
+		default:
+			return nil, invalid
+		}
+		return s, checkDecimal(col, ct, s)
+
+	case kindBool:
+		if b, ok := val.(bool); ok {
+			return b, nil
+		}
+		if n, ok := numberValue(val); ok && (n == "0" || n == "1") {
+			return n == "1", nil
+		}
+		return nil, invalid
+
+	case kindDate:
+		s, ok := val.(string)
+		if !ok {
+			return nil, invalid
+		}
+		t, err := time.Parse(dateLayout, s)
+		if err != nil {
+			return nil, fieldError(col, "must be a date in format YYYY-MM-DD")
+		}
+		return t.Format(dateLayout), nil
+
+	case kindDateTime:
+		s, ok := val.(string)
+		if !ok {
+			return nil, invalid
+		}
+		t, ok := parseDateTime(s)
+		if !ok {
+			return nil, fieldError(col, "must be a date and time in RFC3339 format")
+		}
+		return t.UTC().Format(dateTimeLayout), nil
+
+	case kindTime:
+		s, ok := val.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if _, err := time.Parse(timeLayout, s); err != nil {
+			return nil, fieldError(col, "must be a time in format HH:MM:SS")
+		}
+		return s, nil
+
+	case kindEnum:
+		s, ok := val.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if !contains(ct.members, s) {
+			return nil, fieldError(col, "must be one of %s", strings.Join(ct.members, ", "))
+		}
+		return s, nil
+
+	case kindSet:
+		var items []string
+		switch v := val.(type) {
+		case string:
+			if v != "" {
+				items = strings.Split(v, ",")
+			}
+		case []interface{}:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, invalid
+				}
+				items = append(items, s)
+			}
+		default:
+			return nil, invalid
+		}
+		for _, item := range items {
+			if !contains(ct.members, item) {
+				return nil, fieldError(col, "must contain only %s", strings.Join(ct.members, ", "))
+			}
+		}
+		return strings.Join(items, ","), nil
+
+	case kindJSON:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return nil, invalid
+		}
+		return string(data), nil
+
+	case kindBlob:
+		s, ok := val.(string)
+		if !ok {
+			return nil, invalid
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fieldError(col, "must be base64 encoded")
+		}
+		return data, nil
+	}
+
+	// неизвестный тип - как раньше: строки и числа как есть
+	switch v := val.(type) {
+	case string, float64:
+		return val, nil
+	case json.Number:
+		return v.String(), nil
+	}
+	return nil, invalid
+}
+
+// checkDecimal проверяет запись числа и число знаков до и после запятой для DECIMAL(p,s)
+func checkDecimal(col Column, ct columnType, s string) error {
+	if !validDecimal(s) {
+		return fieldError(col, "have invalid type")
+	}
+	if ct.unsigned && strings.HasPrefix(s, "-") {
+		return fieldError(col, "must not be negative")
+	}
+	if ct.precision == 0 {
+		return nil
+	}
+	digits := strings.TrimPrefix(s, "-")
+	intPart, fracPart := digits, ""
+	if dot := strings.Index(digits, "."); dot >= 0 {
+		intPart, fracPart = digits[:dot], strings.TrimRight(digits[dot+1:], "0")
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(fracPart) > ct.scale {
+		return fieldError(col, "must have at most %d digits after the decimal point", ct.scale)
+	}
+	if len(intPart) > ct.precision-ct.scale {
+		return fieldError(col, "must have at most %d digits before the decimal point", ct.precision-ct.scale)
+	}
+	return nil
+}
+
+// validDecimal - запись DECIMAL вида [-]цифры[.цифры], без показателя степени, NaN, Inf и шестнадцатеричной формы
+func validDecimal(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if !allDigits(intPart) {
+		return false
+	}
+	return !hasDot || allDigits(fracPart)
+}
+
+// allDigits - непустая строка из одних цифр 0-9
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// numberValue - число из JSON: json.Number из тела запроса (см. decodeJSON) или float64
+func numberValue(val interface{}) (json.Number, bool) {
+	switch v := val.(type) {
+	case json.Number:
+		return v, true
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64)), true
+	}
+	return "", false
+}
+
+// intRange - допустимый диапазон целого типа; неизвестная разрядность считается 64-битной
+func (ct columnType) intRange() (min int64, max uint64) {
+	bits := ct.bits
+	if bits <= 0 || bits > 64 {
+		bits = 64
+	}
+	if ct.unsigned {
+		return 0, math.MaxUint64 >> (64 - bits)
+	}
+	return -1 << (bits - 1), 1<<(bits-1) - 1
+}
+
+// intValue проверяет целое по диапазону типа. Обычная запись разбирается точно во всём диапазоне
+// BIGINT и BIGINT UNSIGNED; 42.0 и 4.2e1 тоже принимаются, но только в пределах точности float64.
+func (ct columnType) intValue(col Column, s string) (interface{}, error) {
+	min, max := ct.intRange()
+	outOfRange := fieldError(col, "must be between %d and %d", min, max)
+	if ct.unsigned && strings.HasPrefix(s, "-") {
+		return nil, fieldError(col, "must not be negative")
+	}
+
+	var err error
+	if ct.unsigned {
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, 64); err == nil {
+			if u > max {
+				return nil, outOfRange
+			}
+			if u <= math.MaxInt64 {
+				return int64(u), nil
+			}
+			return u, nil
+		}
+	} else {
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, 64); err == nil {
+			if i < min || i > int64(max) {
+				return nil, outOfRange
+			}
+			return i, nil
+		}
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, outOfRange
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	switch {
+	case errors.Is(err, strconv.ErrRange):
+		return nil, outOfRange
+	case err != nil || f != math.Trunc(f) || math.Abs(f) >= 1<<53:
+		return nil, fieldError(col, "must be an integer")
+	case f < float64(min) || f > float64(max):
+		return nil, outOfRange
+	}
+	return int64(f), nil
+}
+
+// zeroValue - что подставить в NOT NULL колонку, если значение не передали.
+// ok == false - у типа нет разумного пустого значения (дата, время, JSON), поле обязательно.
+func (ct columnType) zeroValue() (interface{}, bool) {
+	switch ct.kind {
+	case kindInt, kindFloat, kindDecimal:
+		return 0, true
+	case kindBool:
+		return false, true
+	case kindEnum:
+		if len(ct.members) > 0 {
+			return ct.members[0], true
+		}
+	case kindDate, kindDateTime, kindTime, kindJSON:
+		return nil, false
+	}
+	return "", true
+}
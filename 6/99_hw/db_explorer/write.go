@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// apiError - ошибка, которую можно показать клиенту, со своим HTTP-статусом.
+// Всё остальное отдаётся как 500 "database error".
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...interface{}) *apiError {
+	return &apiError{status: http.StatusBadRequest, msg: fmt.Sprintf(format, args...)}
+}
+
+// errorStatus раскладывает ошибку на статус и сообщение для ответа
+func errorStatus(err error) (int, string) {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.status, apiErr.msg
+	}
+	return http.StatusInternalServerError, "database error"
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, msg := errorStatus(err)
+	errorResponse(w, msg, status)
+}
+
+// insertRow вставляет запись и возвращает {pk: значение ключа}.
+// q - база или транзакция, чтобы одну логику можно было использовать в /_batch.
+func (d *DbExplorer) insertRow(ctx context.Context, q querier, tableMeta Table, data map[string]interface{}) (map[string]interface{}, error) {
+	return d.insert(ctx, q, tableMeta, data, false)
+}
+
+// insert - общая часть insertRow и импорта. keepPK оставляет переданное значение
+// автоинкрементного ключа, чтобы при переносе данных не рвались ссылки на записи.
+func (d *DbExplorer) insert(ctx context.Context, q querier, tableMeta Table, data map[string]interface{}, keepPK bool) (map[string]interface{}, error) {
+	if err := callerFrom(ctx).checkWrite(tableMeta.Name, opCreate, data); err != nil {
+		return nil, err
+	}
+
+	// Определяем первичный ключ и автоинкрементное свойство
+	pk := ""
+	isAutoIncrement := false
+	for _, col := range tableMeta.Columns {
+		if col.Key == "PRI" {
+			pk = col.Name
+			if strings.Contains(strings.ToLower(col.Extra), "auto_increment") {
+				isAutoIncrement = true
+			}
+			break
+		}
+	}
+	if pk == "" {
+		return nil, &apiError{status: http.StatusInternalServerError, msg: "table has no primary key"}
+	}
+	if keepPK && data[pk] != nil {
+		isAutoIncrement = false
+	}
+
+	// Если первичный ключ является автоинкрементным, не берём его из данных
+	// Если для неавтоинкрементного pk значение передано, его оставляем (так как ожидается, что для таблицы users pk = user_id передаётся)
+
+	// Формируем списки для INSERT, обходя все столбцы из метаданных
+	var cols []string
+	var placeholders []string
+	args := &sqlArgs{dialect: d.dialect}
+	// итоговые значения колонок, включая подставленные по-умолчанию, - для проверки внешних ключей
+	rowValues := map[string]interface{}{}
+
+	// Для каждого столбца, кроме автоинкрементного pk, если значение передано — используем его,
+	// иначе, если поле НЕ NULL, подставляем значение по умолчанию, зависящее от типа.
+	for _, col := range tableMeta.Columns {
+		// Пропускаем автоинкрементный pk
+		if isAutoIncrement && col.Name == pk {
+			continue
+		}
+		cols = append(cols, d.dialect.QuoteIdent(col.Name))
+		if val, exists := data[col.Name]; exists {
+			// Если значение передано, проверяем тип
+			if val == nil {
+				// Если поле не допускает NULL — ошибка
+				if !col.Nullable {
+					return nil, badRequest("field %s have invalid type", col.Name)
+				}
+				placeholders = append(placeholders, "NULL")
+			} else {
+				dbVal, err := coerceValue(col, val)
+				if err != nil {
+					return nil, err
+				}
+				placeholders = append(placeholders, args.add(dbVal))
+				rowValues[col.Name] = dbVal
+			}
+		} else {
+			// Значение не передано в JSON.
+			// Если поле допускает NULL, можно подставить NULL.
+			// Если поле НЕ NULL, подставляем значение по умолчанию:
+			// для текстовых типов — пустая строка, для числовых — 0.
+			if col.Nullable {
+				placeholders = append(placeholders, "NULL")
+			} else {
+				def, ok := parseColumnType(col.Type).zeroValue()
+				if !ok {
+					return nil, fieldError(col, "is required")
+				}
+				placeholders = append(placeholders, args.add(def))
+				rowValues[col.Name] = def
+			}
+		}
+	}
+
+	// Если никаких полей для вставки не осталось — ошибка
+	if len(cols) == 0 {
+		return nil, badRequest("no valid fields provided")
+	}
+
+	if err := d.checkForeignKeys(ctx, q, tableMeta, rowValues); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		d.dialect.QuoteIdent(tableMeta.Name), strings.Join(cols, ", "), strings.Join(placeholders, ", "))
+
+	var insertedID interface{}
+	if isAutoIncrement {
+		id, err := d.dialect.InsertID(ctx, q, query, pk, args.values)
+		if err != nil {
+			return nil, err
+		}
+		insertedID = id
+	} else {
+		if _, err := q.ExecContext(ctx, query, args.values...); err != nil {
+			return nil, err
+		}
+		// Если pk не автоинкрементный, берем его значение из запроса, уже приведённое к типу колонки
+		insertedID = rowValues[pk]
+	}
+
+	after, err := d.rowImage(ctx, q, tableMeta, pk, insertedID)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.recordChange(ctx, q, tableMeta.Name, opCreate, insertedID, nil, after); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{pk: insertedID}, nil
+}
+
+// updateRow обновляет переданные поля записи id и возвращает число изменённых строк
+func (d *DbExplorer) updateRow(ctx context.Context, q querier, tableMeta Table, id string, data map[string]interface{}) (int64, error) {
+	if err := callerFrom(ctx).checkWrite(tableMeta.Name, opUpdate, data); err != nil {
+		return 0, err
+	}
+
+	pk := getPrimaryKey(tableMeta)
+	if pk == "" {
+		pk = "id"
+	}
+
+	if _, exists := data[pk]; exists {
+		return 0, badRequest("field %s have invalid type", pk)
+	}
+	if len(data) == 0 {
+		return 0, badRequest("no valid fields provided")
+	}
+
+	// обходим поля в стабильном порядке, чтобы запрос не зависел от порядка в map
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	setParts := []string{}
+	args := &sqlArgs{dialect: d.dialect}
+
+	for _, key := range keys {
+		val := data[key]
+		col, known := tableMeta.column(key)
+		if !known {
+			return 0, badRequest("unknown field %s", key)
+		}
+
+		if val == nil {
+			if !col.Nullable {
+				return 0, badRequest("field %s have invalid type", key)
+			}
+			setParts = append(setParts, fmt.Sprintf("%s = NULL", d.dialect.QuoteIdent(key)))
+		} else {
+			dbVal, err := coerceValue(col, val)
+			if err != nil {
+				return 0, err
+			}
+			setParts = append(setParts, fmt.Sprintf("%s = %s", d.dialect.QuoteIdent(key), args.add(dbVal)))
+		}
+	}
+
+	if err := d.checkForeignKeys(ctx, q, tableMeta, data); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		d.dialect.QuoteIdent(tableMeta.Name), strings.Join(setParts, ", "), d.dialect.QuoteIdent(pk), args.add(id))
+
+	before, err := d.rowImage(ctx, q, tableMeta, pk, id)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.ExecContext(ctx, query, args.values...)
+	if err != nil {
+		return 0, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil || updated == 0 {
+		return updated, err
+	}
+	after, err := d.rowImage(ctx, q, tableMeta, pk, id)
+	if err != nil {
+		return 0, err
+	}
+	return updated, d.recordChange(ctx, q, tableMeta.Name, opUpdate, id, before, after)
+}
+
+// deleteRow удаляет запись id и возвращает число удалённых строк
+func (d *DbExplorer) deleteRow(ctx context.Context, q querier, tableMeta Table, id string) (int64, error) {
+	if err := callerFrom(ctx).allow(tableMeta.Name, opDelete); err != nil {
+		return 0, err
+	}
+
+	pk := getPrimaryKey(tableMeta)
+	if pk == "" {
+		return 0, &apiError{status: http.StatusInternalServerError, msg: "table has no primary key"}
+	}
+
+	// Формируем и выполняем DELETE-запрос
+	args := &sqlArgs{dialect: d.dialect}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+		d.dialect.QuoteIdent(tableMeta.Name), d.dialect.QuoteIdent(pk), args.add(id))
+	before, err := d.rowImage(ctx, q, tableMeta, pk, id)
+	if err != nil {
+		return 0, err
+	}
+	result, err := q.ExecContext(ctx, query, args.values...)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil || deleted == 0 {
+		return deleted, err
+	}
+	return deleted, d.recordChange(ctx, q, tableMeta.Name, opDelete, id, before, nil)
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodMatcher - методы, разрешённые консюмеру. Шаблоны в ACL бывают трёх видов:
+// "*" - все методы, "/pkg.Service/*" - все методы сервиса, "/pkg.Service/Method" - один метод.
+type methodMatcher struct {
+	patterns []string
+	all      bool
+	services map[string]bool // "/pkg.Service/"
+	methods  map[string]bool
+}
+
+func newMethodMatcher(patterns []string) (*methodMatcher, error) {
+	m := &methodMatcher{
+		patterns: patterns,
+		services: make(map[string]bool),
+		methods:  make(map[string]bool),
+	}
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			m.all = true
+			continue
+		}
+		service, method, ok := splitMethod(pattern)
+		if !ok || strings.Contains(service, "*") || (method != "*" && strings.Contains(method, "*")) {
+			return nil, fmt.Errorf("bad method pattern %q, want *, /pkg.Service/* or /pkg.Service/Method", pattern)
+		}
+		if method == "*" {
+			m.services["/"+service+"/"] = true
+		} else {
+			m.methods[pattern] = true
+		}
+	}
+	return m, nil
+}
+
+// splitMethod разбирает полное имя метода gRPC вида /pkg.Service/Method
+func splitMethod(fullMethod string) (service, method string, ok bool) {
+	if !strings.HasPrefix(fullMethod, "/") {
+		return "", "", false
+	}
+	parts := strings.Split(fullMethod[1:], "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func (m *methodMatcher) match(fullMethod string) bool {
+	if m.all || m.methods[fullMethod] {
+		return true
+	}
+	service, _, ok := splitMethod(fullMethod)
+	return ok && m.services["/"+service+"/"]
+}
+
+// parseACL разбирает ACL вида {"consumer": ["/pkg.Service/Method", ...]}
+func parseACL(data string) (map[string]*methodMatcher, error) {
+	var raw map[string][]string
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return nil, err
+	}
+	acl := make(map[string]*methodMatcher, len(raw))
+	for consumer, patterns := range raw {
+		m, err := newMethodMatcher(patterns)
+		if err != nil {
+			return nil, fmt.Errorf("consumer %s: %v", consumer, err)
+		}
+		acl[consumer] = m
+	}
+	return acl, nil
+}
+
+// consumerFromContext - кто вызывает метод. С mTLS это имя из проверенного клиентского сертификата,
+// без него (или без сертификата у клиента, если разрешён dev-режим) - метаданные consumer.
+func (s *Service) consumerFromContext(ctx context.Context) (string, error) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			consumerID := certConsumer(info.State.VerifiedChains[0][0])
+			if consumerID == "" {
+				return "", status.Error(codes.Unauthenticated, "client certificate has no consumer name")
+			}
+			return consumerID, nil
+		}
+	}
+	if s.opts.tls != nil && !s.opts.consumerHeader {
+		return "", status.Error(codes.Unauthenticated, "client certificate required")
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "no metadata provided")
+	}
+	consumerIDs := md.Get("consumer")
+	if len(consumerIDs) == 0 {
+		return "", status.Error(codes.Unauthenticated, "consumer not provided")
+	}
+	return consumerIDs[0], nil
+}
+
+// certConsumer - имя консюмера в сертификате: CommonName субъекта, а если его нет - первое имя из SAN
+func certConsumer(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	}
+	return ""
+}
+
+// checkACL - проверка доступа клиента, возвращает его consumer
+func (s *Service) checkACL(ctx context.Context, method string) (string, error) {
+	consumerID, err := s.consumerFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	allowed, exists := s.clientsACL[consumerID]
+	s.mu.Unlock()
+
+	// Если consumer отсутствует в ACL, возвращаем Unauthenticated
+	if !exists {
+		return "", status.Error(codes.Unauthenticated, "consumer not registered")
+	}
+	if !allowed.match(method) {
+		return "", status.Error(codes.Unauthenticated, "method access denied")
+	}
+	return consumerID, nil
+}
+
+// ----------------
+
+// Названия событий об изменении ACL: это не методы gRPC, поэтому их не спутать с вызовами
+const (
+	aclGrantEvent  = "ACL.grant"
+	aclRevokeEvent = "ACL.revoke"
+	aclReloadEvent = "ACL.reload"
+)
+
+// List - текущий ACL
+func (s *Service) List(ctx context.Context, req *Nothing) (*ACL, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return aclMessage(s.clientsACL), nil
+}
+
+// Grant добавляет консюмеру методы
+func (s *Service) Grant(ctx context.Context, req *ACLEntry) (*ACL, error) {
+	if req.Consumer == "" || len(req.Methods) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "consumer and methods are required")
+	}
+	return s.changeACL(ctx, aclGrantEvent, req, func(patterns []string) []string {
+		for _, method := range req.Methods {
+			if !containsString(patterns, method) {
+				patterns = append(patterns, method)
+			}
+		}
+		return patterns
+	})
+}
+
+// Revoke убирает у консюмера методы, а если методы не переданы - самого консюмера
+func (s *Service) Revoke(ctx context.Context, req *ACLEntry) (*ACL, error) {
+	if req.Consumer == "" {
+		return nil, status.Error(codes.InvalidArgument, "consumer is required")
+	}
+	return s.changeACL(ctx, aclRevokeEvent, req, func(patterns []string) []string {
+		if len(req.Methods) == 0 {
+			return nil
+		}
+		var left []string
+		for _, pattern := range patterns {
+			if !containsString(req.Methods, pattern) {
+				left = append(left, pattern)
+			}
+		}
+		if left == nil {
+			// консюмер остаётся, но без доступа
+			left = []string{}
+		}
+		return left
+	})
+}
+
+// changeACL применяет change к шаблонам консюмера req.Consumer (nil - удалить консюмера).
+// Новый ACL собирается в копии и подменяется целиком, вместе с записью события под одной блокировкой.
+// Если ACL взят из файла, изменение применяется к его свежей версии и записывается обратно в файл.
+func (s *Service) changeACL(ctx context.Context, eventName string, req *ACLEntry, change func([]string) []string) (*ACL, error) {
+	caller, _ := s.consumerFromContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.aclFile != nil {
+		// правка файла, которую watcher ещё не подхватил, не должна затереться
+		s.reloadACLLocked(s.aclFile)
+	}
+
+	var current []string
+	if m, ok := s.clientsACL[req.Consumer]; ok {
+		current = append(current, m.patterns...)
+	} else if eventName == aclRevokeEvent {
+		return nil, status.Errorf(codes.NotFound, "consumer %s not registered", req.Consumer)
+	}
+
+	acl := make(map[string]*methodMatcher, len(s.clientsACL)+1)
+	for consumer, m := range s.clientsACL {
+		acl[consumer] = m
+	}
+	if patterns := change(current); patterns == nil {
+		delete(acl, req.Consumer)
+	} else {
+		m, err := newMethodMatcher(patterns)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		acl[req.Consumer] = m
+	}
+
+	if s.aclFile != nil {
+		if err := s.aclFile.save(acl); err != nil {
+			return nil, status.Errorf(codes.Internal, "cant save ACL: %v", err)
+		}
+	}
+	s.clientsACL = acl
+	s.publishLocked(&Event{
+		Timestamp: time.Now().Unix(),
+		Consumer:  caller,
+		Method:    eventName,
+		Host:      peerHost(ctx),
+		Details:   fmt.Sprintf("%s: %s", req.Consumer, strings.Join(req.Methods, ", ")),
+	})
+	return aclMessage(acl), nil
+}
+
+func aclMessage(acl map[string]*methodMatcher) *ACL {
+	consumers := make([]string, 0, len(acl))
+	for consumer := range acl {
+		consumers = append(consumers, consumer)
+	}
+	sort.Strings(consumers)
+	res := &ACL{}
+	for _, consumer := range consumers {
+		res.Entries = append(res.Entries, &ACLEntry{
+			Consumer: consumer,
+			Methods:  append([]string{}, acl[consumer].patterns...),
+		})
+	}
+	return res
+}
+
+// ----------------
+
+// aclWatcher перечитывает ACL из файла, когда у того меняется время изменения или размер.
+// Файл опрашивается раз в interval: так не нужны зависимости и работает на любой ФС.
+type aclWatcher struct {
+	path     string
+	interval time.Duration
+	modTime  time.Time
+	size     int64
+}
+
+// load читает файл, если он поменялся с прошлого раза; changed=false - перечитывать было нечего
+func (w *aclWatcher) load() (acl map[string]*methodMatcher, changed bool, err error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return nil, false, err
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, false, err
+	}
+	// время запоминаем и при ошибке разбора, чтобы не писать в лог одно и то же каждый опрос
+	w.modTime, w.size = info.ModTime(), info.Size()
+	acl, err = parseACL(string(data))
+	if err != nil {
+		return nil, false, err
+	}
+	return acl, true, nil
+}
+
+// save записывает acl в файл и запоминает его время и размер, чтобы не перечитывать собственную запись.
+// Пишем через временный файл и rename: так файл никогда не бывает прочитан наполовину.
+func (w *aclWatcher) save(acl map[string]*methodMatcher) error {
+	raw := make(map[string][]string, len(acl))
+	for consumer, m := range acl {
+		raw[consumer] = m.patterns
+	}
+	data, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// watchACL подменяет ACL сервиса при изменении файла, пока сервис не остановлен.
+// Файл с ошибкой пропускается - остаётся предыдущий ACL. Изменения через AdminACL к этому
+// моменту уже записаны в файл (см. changeACL), так что перечитывание их не отменяет.
+func (s *Service) watchACL(w *aclWatcher) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.reloadACLLocked(w)
+			s.mu.Unlock()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// reloadACLLocked перечитывает файл, если он поменялся. Вызывается под s.mu: чтение файла
+// и запись в него из changeACL не перемешиваются.
+func (s *Service) reloadACLLocked(w *aclWatcher) {
+	acl, changed, err := w.load()
+	if err != nil {
+		log.Printf("ACL reload from %s failed: %v", w.path, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	s.clientsACL = acl
+	s.publishLocked(&Event{
+		Timestamp: time.Now().Unix(),
+		Method:    aclReloadEvent,
+		Details:   fmt.Sprintf("%s: %d consumers", w.path, len(acl)),
+	})
+}
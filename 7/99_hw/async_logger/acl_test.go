@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMethodMatcher(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		method   string
+		match    bool
+	}{
+		{[]string{"*"}, "/main.Biz/Check", true},
+		{[]string{"/main.Biz/Check"}, "/main.Biz/Check", true},
+		{[]string{"/main.Biz/Check"}, "/main.Biz/Add", false},
+		{[]string{"/main.Biz/*"}, "/main.Biz/Test", true},
+		{[]string{"/main.Biz/*"}, "/main.Admin/Logging", false},
+		// префикс имени сервиса - это другой сервис
+		{[]string{"/main.Biz/*"}, "/main.BizAdmin/Check", false},
+		// метод короче шаблона не должен ронять проверку
+		{[]string{"/main.Admin/*"}, "/x", false},
+		{[]string{"/main.Admin/*"}, "", false},
+		{[]string{"/main.Admin/Logging", "/main.Biz/*"}, "/main.Biz/Add", true},
+		{[]string{}, "/main.Biz/Add", false},
+	}
+	for i, c := range cases {
+		m, err := newMethodMatcher(c.patterns)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+		if match := m.match(c.method); match != c.match {
+			t.Errorf("[%d] %v match %q: have %v, want %v", i, c.patterns, c.method, match, c.match)
+		}
+	}
+}
+
+func TestMethodMatcherBadPatterns(t *testing.T) {
+	for _, pattern := range []string{
+		"/main.Biz*",
+		"/main.*/Check",
+		"/main.Biz/Ch*",
+		"main.Biz/Check",
+		"/main.Biz/",
+		"/main.Biz/Check/More",
+		"",
+	} {
+		if _, err := newMethodMatcher([]string{pattern}); err == nil {
+			t.Errorf("pattern %q: expected error", pattern)
+		}
+	}
+}
+
+func TestParseACLBadPattern(t *testing.T) {
+	if _, err := parseACL(`{"biz_user": ["/main.Biz*"]}`); err == nil {
+		t.Fatalf("expected error for bad pattern")
+	}
+	ctx, finish := context.WithCancel(context.Background())
+	defer finish()
+	if err := StartMyMicroservice(ctx, listenAddr, `{"biz_user": ["/main.Biz*"]}`); err == nil {
+		t.Fatalf("expected error for bad pattern")
+	}
+}
+
+// стрим-методы проходят ту же проверку, что и unary
+func TestStreamACL(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	adm := NewAdminClient(conn)
+
+	for _, consumer := range []string{"unknown", "biz_admin", "logger1"} {
+		stream, _ := adm.Statistics(getConsumerCtx(consumer), &StatInterval{IntervalSeconds: 1})
+		if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: have %v, want Unauthenticated", consumer, err)
+		}
+	}
+}
+
+func TestAdminACL(t *testing.T) {
+	acl := `{
+	"acl_admin": ["/main.AdminACL/*"],
+	"acl_reader": ["/main.AdminACL/List"],
+	"logger":    ["/main.Admin/Logging"],
+	"biz_user":  ["/main.Biz/Check"]
+}`
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, acl)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+	aclClient := NewAdminACLClient(conn)
+
+	// AdminACL закрыт тем же ACL
+	if _, err := aclClient.Grant(getConsumerCtx("acl_reader"), &ACLEntry{Consumer: "biz_user", Methods: []string{"*"}}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("grant by reader: have %v, want Unauthenticated", err)
+	}
+	list, err := aclClient.List(getConsumerCtx("acl_reader"), &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Entries) != 4 || list.Entries[0].Consumer != "acl_admin" {
+		t.Fatalf("list: have %v", list.Entries)
+	}
+
+	logStream, err := adm.Logging(getConsumerCtx("logger"), &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("add before grant: have %v, want Unauthenticated", err)
+	}
+	list, err = aclClient.Grant(getConsumerCtx("acl_admin"), &ACLEntry{Consumer: "biz_user", Methods: []string{"/main.Biz/Add", "/main.Biz/Check"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"/main.Biz/Check", "/main.Biz/Add"}; !reflect.DeepEqual(list.Entries[2].Methods, want) {
+		t.Fatalf("grant: have %v, want %v", list.Entries[2], want)
+	}
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("add after grant: %v", err)
+	}
+
+	if _, err := aclClient.Revoke(getConsumerCtx("acl_admin"), &ACLEntry{Consumer: "biz_user"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := biz.Check(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("check after revoke: have %v, want Unauthenticated", err)
+	}
+
+	// неверные запросы ACL не меняют
+	if _, err := aclClient.Grant(getConsumerCtx("acl_admin"), &ACLEntry{Consumer: "biz_user", Methods: []string{"/main.Biz*"}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("bad pattern: have %v, want InvalidArgument", err)
+	}
+	if _, err := aclClient.Revoke(getConsumerCtx("acl_admin"), &ACLEntry{Consumer: "nobody"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("revoke unknown: have %v, want NotFound", err)
+	}
+
+	expected := []*Event{
+		{Consumer: "acl_admin", Method: "/main.AdminACL/Grant"},
+		{Consumer: "acl_admin", Method: aclGrantEvent, Details: "biz_user: /main.Biz/Add, /main.Biz/Check"},
+		{Consumer: "biz_user", Method: "/main.Biz/Add"},
+		{Consumer: "acl_admin", Method: "/main.AdminACL/Revoke"},
+		{Consumer: "acl_admin", Method: aclRevokeEvent, Details: "biz_user: "},
+	}
+	logData := []*Event{}
+	for range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		logData = append(logData, &Event{Consumer: evt.Consumer, Method: evt.Method, Details: evt.Details})
+	}
+	// изменение ACL попадает в журнал сразу за вызовом, который его сделал
+	if !reflect.DeepEqual(logData, expected) {
+		t.Fatalf("logs dont match\nhave %+v\nwant %+v", logData, expected)
+	}
+}
+
+func TestACLFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acl.json")
+	if err := os.WriteFile(path, []byte(`{"biz_user": ["/main.Biz/Check"], "logger": ["/main.Admin/Logging"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, "", WithACLFile(path, 100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	logStream, err := adm.Logging(getConsumerCtx("logger"), &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("add before reload: have %v, want Unauthenticated", err)
+	}
+
+	// файл с ошибкой пропускается, ACL остаётся прежним
+	if err := os.WriteFile(path, []byte(`{"biz_user": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, err := biz.Check(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("check after bad file: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"biz_user": ["/main.Biz/*"], "logger": ["/main.Admin/Logging"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("add after reload: %v", err)
+	}
+
+	expected := []*Event{
+		{Consumer: "biz_user", Method: "/main.Biz/Check"},
+		{Method: aclReloadEvent, Details: path + ": 2 consumers"},
+		{Consumer: "biz_user", Method: "/main.Biz/Add"},
+	}
+	logData := []*Event{}
+	for range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		logData = append(logData, &Event{Consumer: evt.Consumer, Method: evt.Method, Details: evt.Details})
+	}
+	if !reflect.DeepEqual(logData, expected) {
+		t.Fatalf("logs dont match\nhave %+v\nwant %+v", logData, expected)
+	}
+
+	if err := StartMyMicroservice(ctx, listenAddr, "", WithACLFile(filepath.Join(t.TempDir(), "missing.json"), 0)); err == nil {
+		t.Fatalf("expected error for missing ACL file")
+	}
+}
+
+func TestACLFileGrant(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acl.json")
+	if err := os.WriteFile(path, []byte(`{"acl_admin": ["/main.AdminACL/*"], "biz_user": ["/main.Biz/Check"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, "", WithACLFile(path, 100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	aclClient := NewAdminACLClient(conn)
+
+	if _, err := aclClient.Grant(getConsumerCtx("acl_admin"), &ACLEntry{Consumer: "biz_user", Methods: []string{"/main.Biz/Add"}}); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+
+	// выданное через AdminACL записано в файл и переживает его перечитывание
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string][]string
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("bad saved ACL %s: %v", data, err)
+	}
+	if want := []string{"/main.Biz/Check", "/main.Biz/Add"}; !reflect.DeepEqual(saved["biz_user"], want) {
+		t.Fatalf("saved biz_user: have %v, want %v", saved["biz_user"], want)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("add after grant: %v", err)
+	}
+
+	// правка файла после этого по-прежнему подхватывается
+	if err := os.WriteFile(path, []byte(`{"acl_admin": ["/main.AdminACL/*"], "biz_user": ["/main.Biz/Check"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if _, err := biz.Add(getConsumerCtx("biz_user"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("add after file edit: have %v, want Unauthenticated", err)
+	}
+}
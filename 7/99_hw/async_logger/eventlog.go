@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// logError - ошибки журнала; константы, потому что глобальных переменных в задании нет
+type logError string
+
+func (e logError) Error() string {
+	return string(e)
+}
+
+const (
+	// errLogTruncated - запрошенные события уже вытеснены из журнала
+	errLogTruncated = logError("events were dropped from the log")
+	errLogClosed    = logError("event log is closed")
+)
+
+// eventLog - журнал событий с ограниченным размером: старые события вытесняются новыми.
+// Append присваивает событию Id, события с Id > since можно перечитать через Since.
+type eventLog interface {
+	Append(evt *Event) error
+	// Since вызывает fn для событий с Id > since по порядку.
+	// Если часть из них уже вытеснена, возвращает errLogTruncated, ничего не вызывая.
+	Since(since uint64, fn func(*Event) error) error
+	// First - Id самого старого события в журнале, 0 - журнал пуст
+	First() uint64
+	// Last - Id последнего записанного события
+	Last() uint64
+	Close() error
+}
+
+// ----------------
+
+// memoryLog - журнал в памяти на случай, когда каталог для журнала не задан
+type memoryLog struct {
+	mu     sync.Mutex
+	events []*Event // кольцевой буфер
+	start  int      // индекс самого старого события
+	count  int
+	lastID uint64
+}
+
+func newMemoryLog(size int) *memoryLog {
+	return &memoryLog{events: make([]*Event, size)}
+}
+
+func (l *memoryLog) Append(evt *Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	evt.Id = l.lastID
+	if l.count < len(l.events) {
+		l.events[(l.start+l.count)%len(l.events)] = evt
+		l.count++
+		return nil
+	}
+	l.events[l.start] = evt
+	l.start = (l.start + 1) % len(l.events)
+	return nil
+}
+
+func (l *memoryLog) Since(since uint64, fn func(*Event) error) error {
+	l.mu.Lock()
+	if since >= l.lastID {
+		l.mu.Unlock()
+		return nil
+	}
+	first := l.lastID - uint64(l.count) + 1
+	if since+1 < first {
+		l.mu.Unlock()
+		return errLogTruncated
+	}
+	// копируем под блокировкой, отправляем без неё
+	skip := int(since + 1 - first)
+	events := make([]*Event, 0, l.count-skip)
+	for i := skip; i < l.count; i++ {
+		events = append(events, l.events[(l.start+i)%len(l.events)])
+	}
+	l.mu.Unlock()
+
+	for _, evt := range events {
+		if err := fn(evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *memoryLog) First() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.count == 0 {
+		return 0
+	}
+	return l.lastID - uint64(l.count) + 1
+}
+
+func (l *memoryLog) Last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastID
+}
+
+func (l *memoryLog) Close() error {
+	return nil
+}
+
+// ----------------
+
+// fileLog - журнал на диске: каталог с сегментами <id первого события>.log,
+// в каждом записи вида uvarint(длина) + Event в protobuf.
+// Когда событий больше maxEvents, удаляется самый старый сегмент целиком.
+type fileLog struct {
+	dir         string
+	maxEvents   int
+	segmentSize int
+
+	mu       sync.Mutex
+	segments []logSegment // по возрастанию first
+	file     *os.File     // последний сегмент, открыт на запись
+	lastID   uint64
+	closed   bool
+}
+
+type logSegment struct {
+	first uint64
+	count int
+}
+
+func (s logSegment) path(dir string) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d.log", s.first))
+}
+
+const segmentsPerLog = 4
+
+func openFileLog(dir string, maxEvents int) (*fileLog, error) {
+	if maxEvents <= 0 {
+		return nil, fmt.Errorf("event log size must be positive, got %d", maxEvents)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	l := &fileLog{
+		dir:         dir,
+		maxEvents:   maxEvents,
+		segmentSize: (maxEvents + segmentsPerLog - 1) / segmentsPerLog,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, ".log"), 10, 64)
+		if err != nil {
+			continue
+		}
+		l.segments = append(l.segments, logSegment{first: first})
+	}
+	sort.Slice(l.segments, func(i, j int) bool { return l.segments[i].first < l.segments[j].first })
+
+	// пересчитываем события; хвост последнего сегмента мог быть недописан при падении
+	for i := range l.segments {
+		seg := &l.segments[i]
+		count, validSize, err := scanSegment(seg.path(dir))
+		if err != nil {
+			return nil, err
+		}
+		seg.count = count
+		if i == len(l.segments)-1 {
+			if err := os.Truncate(seg.path(dir), validSize); err != nil {
+				return nil, err
+			}
+			l.lastID = seg.first + uint64(count) - 1
+		}
+	}
+	if len(l.segments) > 0 {
+		last := l.segments[len(l.segments)-1]
+		if last.count == 0 {
+			l.lastID = last.first - 1
+		}
+		l.file, err = os.OpenFile(last.path(dir), os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// scanSegment возвращает число целых записей и размер файла, который они занимают
+func scanSegment(path string) (int, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	count := 0
+	var size int64
+	for {
+		data, n, err := readRecord(r)
+		if err != nil {
+			// обрыв посреди записи - считаем, что записи нет
+			return count, size, nil
+		}
+		if err := proto.Unmarshal(data, &Event{}); err != nil {
+			return count, size, nil
+		}
+		count++
+		size += int64(n)
+	}
+}
+
+// readRecord читает одну запись, n - сколько байт она заняла вместе с длиной
+func readRecord(r *bufio.Reader) ([]byte, int, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, 0, err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	return data, binary.PutUvarint(buf[:], length) + int(length), nil
+}
+
+// Append занимает следующий id только после удачной записи: иначе в журнале осталась бы дыра,
+// а после перезапуска lastID, пересчитанный по сегментам, выдал бы тот же id другому событию
+func (l *fileLog) Append(evt *Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return errLogClosed
+	}
+
+	id := l.lastID + 1
+	if l.file == nil || l.segments[len(l.segments)-1].count >= l.segmentSize {
+		if err := l.rotate(id); err != nil {
+			return err
+		}
+	}
+
+	evt.Id = id
+	data, err := proto.Marshal(evt)
+	if err != nil {
+		evt.Id = 0
+		return err
+	}
+	record := make([]byte, binary.MaxVarintLen64+len(data))
+	n := binary.PutUvarint(record, uint64(len(data)))
+	n += copy(record[n:], data)
+	info, err := l.file.Stat()
+	if err != nil {
+		evt.Id = 0
+		return err
+	}
+	if _, err := l.file.Write(record[:n]); err != nil {
+		// недописанную запись отрезаем, чтобы за ней не легли следующие
+		l.file.Truncate(info.Size())
+		evt.Id = 0
+		return err
+	}
+	l.lastID = id
+	l.segments[len(l.segments)-1].count++
+	return nil
+}
+
+// rotate начинает новый сегмент с события first и удаляет старые, без которых журнал всё ещё не меньше maxEvents
+func (l *fileLog) rotate(first uint64) error {
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return err
+		}
+		l.file = nil
+	}
+	seg := logSegment{first: first}
+	f, err := os.OpenFile(seg.path(l.dir), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.file = f
+	l.segments = append(l.segments, seg)
+
+	total := 0
+	for _, s := range l.segments {
+		total += s.count
+	}
+	for len(l.segments) > 1 && total-l.segments[0].count >= l.maxEvents {
+		total -= l.segments[0].count
+		if err := os.Remove(l.segments[0].path(l.dir)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		l.segments = l.segments[1:]
+	}
+	return nil
+}
+
+func (l *fileLog) Since(since uint64, fn func(*Event) error) error {
+	l.mu.Lock()
+	lastID := l.lastID
+	segments := append([]logSegment(nil), l.segments...)
+	l.mu.Unlock()
+
+	if since >= lastID {
+		return nil
+	}
+	if len(segments) == 0 || since+1 < segments[0].first {
+		return errLogTruncated
+	}
+
+	for i, seg := range segments {
+		if i+1 < len(segments) && segments[i+1].first <= since+1 {
+			continue
+		}
+		done, err := l.readSegment(seg, since, lastID, fn)
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSegment отдаёт события сегмента с since < Id <= lastID, done - дошли до lastID
+func (l *fileLog) readSegment(seg logSegment, since, lastID uint64, fn func(*Event) error) (bool, error) {
+	f, err := os.Open(seg.path(l.dir))
+	if os.IsNotExist(err) {
+		// сегмент удалили, пока мы читали предыдущие
+		return false, errLogTruncated
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		data, _, err := readRecord(r)
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		evt := &Event{}
+		if err := proto.Unmarshal(data, evt); err != nil {
+			return false, err
+		}
+		if evt.Id <= since {
+			continue
+		}
+		if evt.Id > lastID {
+			return true, nil
+		}
+		if err := fn(evt); err != nil {
+			return false, err
+		}
+		if evt.Id == lastID {
+			return true, nil
+		}
+	}
+}
+
+func (l *fileLog) First() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, seg := range l.segments {
+		if seg.count > 0 {
+			return seg.first
+		}
+	}
+	return 0
+}
+
+func (l *fileLog) Last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastID
+}
+
+func (l *fileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
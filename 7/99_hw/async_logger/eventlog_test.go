@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// collectSince - Id событий, которые журнал отдаёт после since
+func collectSince(t *testing.T, l eventLog, since uint64) ([]uint64, error) {
+	t.Helper()
+	var ids []uint64
+	err := l.Since(since, func(evt *Event) error {
+		ids = append(ids, evt.Id)
+		return nil
+	})
+	return ids, err
+}
+
+func appendEvents(t *testing.T, l eventLog, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := l.Append(&Event{Consumer: "biz_user", Method: "/main.Biz/Check"}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+}
+
+func TestMemoryLog(t *testing.T) {
+	l := newMemoryLog(3)
+	appendEvents(t, l, 5)
+
+	if first := l.First(); first != 3 {
+		t.Fatalf("first: have %d, want 3", first)
+	}
+	ids, err := collectSince(t, l, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []uint64{3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("since 2: have %v, want %v", ids, want)
+	}
+	ids, _ = collectSince(t, l, 5)
+	if len(ids) != 0 {
+		t.Fatalf("since last: have %v, want nothing", ids)
+	}
+	if _, err := collectSince(t, l, 1); err != errLogTruncated {
+		t.Fatalf("since 1: have %v, want errLogTruncated", err)
+	}
+}
+
+func TestFileLog(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openFileLog(dir, 8)
+	if err != nil {
+		t.Fatalf("cant open log: %v", err)
+	}
+	appendEvents(t, l, 20)
+	if err := l.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	// после переоткрытия журнал продолжает нумерацию и помнит последние события
+	l, err = openFileLog(dir, 8)
+	if err != nil {
+		t.Fatalf("cant reopen log: %v", err)
+	}
+	defer l.Close()
+	if last := l.Last(); last != 20 {
+		t.Fatalf("last after reopen: have %d, want 20", last)
+	}
+	first := l.First()
+	if first == 0 || 20-first+1 < 8 {
+		t.Fatalf("log keeps %d events, want at least 8", 20-first+1)
+	}
+	if _, err := collectSince(t, l, 0); err != errLogTruncated {
+		t.Fatalf("since 0: have %v, want errLogTruncated", err)
+	}
+
+	appendEvents(t, l, 1)
+	ids, err := collectSince(t, l, 18)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []uint64{19, 20, 21}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("since 18: have %v, want %v", ids, want)
+	}
+}
+
+func TestFileLogPartialRecord(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openFileLog(dir, 100)
+	if err != nil {
+		t.Fatalf("cant open log: %v", err)
+	}
+	appendEvents(t, l, 3)
+	l.Close()
+
+	// имитируем падение посреди записи: длина есть, данных нет
+	path := filepath.Join(dir, logSegment{first: 1}.path(""))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("cant open segment: %v", err)
+	}
+	f.Write([]byte{50, 1, 2})
+	f.Close()
+
+	l, err = openFileLog(dir, 100)
+	if err != nil {
+		t.Fatalf("cant reopen log: %v", err)
+	}
+	defer l.Close()
+	appendEvents(t, l, 1)
+	ids, err := collectSince(t, l, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []uint64{1, 2, 3, 4}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("have %v, want %v", ids, want)
+	}
+}
+
+func TestFileLogFailedAppend(t *testing.T) {
+	dir := t.TempDir()
+	l, err := openFileLog(dir, 100)
+	if err != nil {
+		t.Fatalf("cant open log: %v", err)
+	}
+	appendEvents(t, l, 3)
+
+	// сегмент открыт только на чтение - запись не проходит
+	writable := l.file
+	readOnly, err := os.Open(writable.Name())
+	if err != nil {
+		t.Fatalf("cant open segment: %v", err)
+	}
+	l.file = readOnly
+	evt := &Event{Consumer: "biz_user", Method: "/main.Biz/Check"}
+	if err := l.Append(evt); err == nil {
+		t.Fatalf("append to read-only segment: expected error")
+	}
+	if evt.Id != 0 || l.Last() != 3 {
+		t.Fatalf("failed append took id: event %d, last %d", evt.Id, l.Last())
+	}
+	readOnly.Close()
+	l.file = writable
+
+	// неудачная запись не оставляет дыры ни сейчас, ни после перезапуска
+	appendEvents(t, l, 1)
+	l.Close()
+	l, err = openFileLog(dir, 100)
+	if err != nil {
+		t.Fatalf("cant reopen log: %v", err)
+	}
+	defer l.Close()
+	appendEvents(t, l, 1)
+	ids, err := collectSince(t, l, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []uint64{1, 2, 3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("have %v, want %v", ids, want)
+	}
+}
+
+// получаем контекст, в котором Logging продолжит после события lastID
+func getResumeCtx(consumerName string, lastID uint64) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	md := metadata.Pairs(
+		"consumer", consumerName,
+		lastEventIDKey, strconv.FormatUint(lastID, 10),
+	)
+	return metadata.NewOutgoingContext(ctx, md), cancel
+}
+
+// события, пропущенные, пока клиент был отключён, досылаются после переподключения -
+// в том числе после перезапуска сервиса с журналом на диске
+func TestLoggingResume(t *testing.T) {
+	dir := t.TempDir()
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData, WithEventLog(dir, 100))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+
+	conn := getGrpcConn(t)
+	biz := NewBizClient(conn)
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	conn.Close()
+	wait(1)
+	finish()
+	wait(10)
+
+	ctx, finish = context.WithCancel(context.Background())
+	err = StartMyMicroservice(ctx, listenAddr, ACLData, WithEventLog(dir, 100))
+	if err != nil {
+		t.Fatalf("cant start server again: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(10)
+	}()
+
+	conn = getGrpcConn(t)
+	defer conn.Close()
+	biz = NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	// первое событие клиент уже видел
+	resumeCtx, cancel := getResumeCtx("logger1", 1)
+	defer cancel()
+	logStream, err := adm.Logging(resumeCtx, &Nothing{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+
+	expected := []*Event{
+		{Id: 2, Consumer: "biz_user", Method: "/main.Biz/Add"},
+		{Id: 4, Consumer: "biz_admin", Method: "/main.Biz/Test"},
+	}
+	logData := []*Event{}
+	for range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		logData = append(logData, &Event{Id: evt.Id, Consumer: evt.Consumer, Method: evt.Method})
+	}
+	if !reflect.DeepEqual(logData, expected) {
+		t.Fatalf("logs dont match\nhave %+v\nwant %+v", logData, expected)
+	}
+}
+
+func TestLoggingResumeErrors(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData, WithEventLog(t.TempDir(), 2))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(10)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	for i := 0; i < 6; i++ {
+		biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	}
+	wait(1)
+
+	resumeCtx, cancel := getResumeCtx("logger1", 1)
+	defer cancel()
+	logStream, _ := adm.Logging(resumeCtx, &Nothing{})
+	if _, err := logStream.Recv(); status.Code(err) != codes.OutOfRange {
+		t.Fatalf("truncated log: have %v, want OutOfRange", err)
+	}
+
+	md := metadata.Pairs("consumer", "logger1", lastEventIDKey, "abc")
+	badCtx, cancelBad := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), time.Second)
+	defer cancelBad()
+	logStream, _ = adm.Logging(badCtx, &Nothing{})
+	if _, err := logStream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("bad last-event-id: have %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net"
+	"path"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventFilter - проверенный LogFilter; nil пропускает все события
+type eventFilter struct {
+	consumers []string
+	methods   []string
+	hosts     []string
+}
+
+func newEventFilter(f *LogFilter) (*eventFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	if err := checkPatterns(f.Methods); err != nil {
+		return nil, err
+	}
+	return &eventFilter{
+		consumers: f.Consumers,
+		methods:   f.Methods,
+		hosts:     f.Hosts,
+	}, nil
+}
+
+// checkPatterns - шаблоны методов должны разбираться path.Match
+func checkPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return status.Errorf(codes.InvalidArgument, "bad method pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+func (f *eventFilter) match(evt *Event) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.consumers) > 0 && !containsString(f.consumers, evt.Consumer) {
+		return false
+	}
+	if len(f.methods) > 0 && !matchMethod(f.methods, evt.Method) {
+		return false
+	}
+	if len(f.hosts) > 0 && !matchHost(f.hosts, evt.Host) {
+		return false
+	}
+	return true
+}
+
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost - host в событии вида ip:port, фильтр может содержать только ip
+func matchHost(hosts []string, host string) bool {
+	ip, _, err := net.SplitHostPort(host)
+	if err != nil {
+		ip = host
+	}
+	for _, h := range hosts {
+		if h == host || h == ip {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// filterStats оставляет в статистике только ключи, для которых keep вернул true
+func filterStats(stats map[string]uint64, keep func(string) bool) {
+	for k := range stats {
+		if !keep(k) {
+			delete(stats, k)
+		}
+	}
+}
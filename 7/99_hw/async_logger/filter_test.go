@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEventFilter(t *testing.T) {
+	evt := &Event{Consumer: "biz_user", Method: "/main.Biz/Check", Host: "127.0.0.1:5555"}
+	cases := []struct {
+		filter *LogFilter
+		match  bool
+	}{
+		{nil, true},
+		{&LogFilter{}, true},
+		{&LogFilter{Consumers: []string{"biz_admin", "biz_user"}}, true},
+		{&LogFilter{Consumers: []string{"biz_admin"}}, false},
+		{&LogFilter{Methods: []string{"/main.Biz/*"}}, true},
+		{&LogFilter{Methods: []string{"/main.Admin/*"}}, false},
+		{&LogFilter{Methods: []string{"/main.Biz/Ch?ck"}}, true},
+		{&LogFilter{Hosts: []string{"127.0.0.1"}}, true},
+		{&LogFilter{Hosts: []string{"127.0.0.1:5555"}}, true},
+		{&LogFilter{Hosts: []string{"127.0.0.1:6666"}}, false},
+		{&LogFilter{Consumers: []string{"biz_user"}, Methods: []string{"/main.Biz/Add"}}, false},
+	}
+	for i, c := range cases {
+		f, err := newEventFilter(c.filter)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %v", i, err)
+		}
+		if match := f.match(evt); match != c.match {
+			t.Errorf("[%d] %v: have %v, want %v", i, c.filter, match, c.match)
+		}
+	}
+
+	if _, err := newEventFilter(&LogFilter{Methods: []string{"/main.Biz/["}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("bad pattern: have %v, want InvalidArgument", err)
+	}
+}
+
+func TestLoggingFiltered(t *testing.T) {
+	acl := `{
+	"logger1":   ["/main.Admin/LoggingFiltered"],
+	"logger2":   ["/main.Admin/Logging"],
+	"stat1":     ["/main.Admin/Statistics"],
+	"biz_user":  ["/main.Biz/Check", "/main.Biz/Add"],
+	"biz_admin": ["/main.Biz/*"]
+}`
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, acl)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	// доступ к Logging не даёт доступа к LoggingFiltered
+	denied, _ := adm.LoggingFiltered(getConsumerCtx("logger2"), &LogFilter{})
+	if _, err := denied.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("have %v, want Unauthenticated", err)
+	}
+
+	logStream, err := adm.LoggingFiltered(getConsumerCtx("logger1"), &LogFilter{
+		Consumers: []string{"biz_admin"},
+		Methods:   []string{"/main.Biz/*"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Check(getConsumerCtx("biz_admin"), &Nothing{})
+	biz.Add(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+
+	expected := []*Event{
+		{Consumer: "biz_admin", Method: "/main.Biz/Check"},
+		{Consumer: "biz_admin", Method: "/main.Biz/Test"},
+	}
+	logData := []*Event{}
+	for range expected {
+		evt, err := logStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v, awaiting event", err)
+		}
+		logData = append(logData, &Event{Consumer: evt.Consumer, Method: evt.Method})
+	}
+	if !reflect.DeepEqual(logData, expected) {
+		t.Fatalf("logs dont match\nhave %+v\nwant %+v", logData, expected)
+	}
+
+	statStream, err := adm.Statistics(getConsumerCtx("stat1"), &StatInterval{
+		IntervalSeconds: 1,
+		Methods:         []string{"/main.Biz/*"},
+		Consumers:       []string{"biz_user"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stat, err := statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// stat1 получает разницу с момента подключения, поэтому до первого такта ничего нового не было
+	if len(stat.ByMethod) != 0 || len(stat.ByConsumer) != 0 {
+		t.Fatalf("unexpected stat before calls: %+v", stat)
+	}
+
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Test(getConsumerCtx("biz_admin"), &Nothing{})
+	stat, err = statStream.Recv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedMethods := map[string]uint64{"/main.Biz/Check": 1, "/main.Biz/Test": 1}
+	expectedConsumers := map[string]uint64{"biz_user": 1}
+	if !reflect.DeepEqual(stat.ByMethod, expectedMethods) || !reflect.DeepEqual(stat.ByConsumer, expectedConsumers) {
+		t.Fatalf("stat dont match\nhave %v %v\nwant %v %v", stat.ByMethod, stat.ByConsumer, expectedMethods, expectedConsumers)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+/*
+HTTP-шлюз для тех, кто не умеет gRPC. Вызовы идут в тот же сервис через те же интерсепторы,
+поэтому ACL, лог и статистика работают как для gRPC; консюмер берётся из заголовка Consumer.
+
+curl -X POST http://127.0.0.1:8083/v1/biz/check -H "Consumer: biz_user"
+curl -N http://127.0.0.1:8083/v1/admin/logging -H "Consumer: logger"
+curl -N "http://127.0.0.1:8083/v1/admin/loggingfiltered?methods=/main.Biz/*&consumers=biz_user" -H "Consumer: logger"
+curl -N "http://127.0.0.1:8083/v1/admin/statistics?interval_seconds=2&mode=CUMULATIVE" -H "Consumer: stat"
+*/
+
+// gatewayHandler - unary-методы Biz как POST /v1/biz/<method> с JSON в теле,
+// стримы Admin как Server-Sent Events по GET /v1/admin/<method> с параметрами в query
+func (s *Service) gatewayHandler() http.Handler {
+	mux := http.NewServeMux()
+	for _, m := range Biz_ServiceDesc.Methods {
+		desc := m
+		mux.HandleFunc("/v1/biz/"+strings.ToLower(desc.MethodName), func(w http.ResponseWriter, r *http.Request) {
+			s.gatewayUnary(w, r, desc)
+		})
+	}
+	for _, st := range Admin_ServiceDesc.Streams {
+		desc := st
+		fullMethod := "/" + Admin_ServiceDesc.ServiceName + "/" + desc.StreamName
+		mux.HandleFunc("/v1/admin/"+strings.ToLower(desc.StreamName), func(w http.ResponseWriter, r *http.Request) {
+			s.gatewayStream(w, r, fullMethod, desc)
+		})
+	}
+	return mux
+}
+
+// gatewayContext - контекст вызова с метаданными из заголовков и адресом HTTP-клиента
+func gatewayContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	// заголовки HTTP, которые становятся метаданными вызова
+	for _, key := range []string{"consumer", lastEventIDKey, slowPolicyKey} {
+		if value := r.Header.Get(key); value != "" {
+			md.Set(key, value)
+		}
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: addr})
+	}
+	return ctx
+}
+
+func (s *Service) gatewayUnary(w http.ResponseWriter, r *http.Request, desc grpc.MethodDesc) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeGatewayError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	dec := func(in interface{}) error {
+		if len(body) == 0 {
+			return nil
+		}
+		if err := protojson.Unmarshal(body, in.(proto.Message)); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil
+	}
+
+	resp, err := desc.Handler(s, gatewayContext(r), dec, s.unaryInterceptor)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	data, err := marshalGateway(resp.(proto.Message))
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Service) gatewayStream(w http.ResponseWriter, r *http.Request, fullMethod string, desc grpc.StreamDesc) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	stream := &sseStream{ctx: gatewayContext(r), w: w, flusher: flusher, query: r.URL.Query()}
+	info := &grpc.StreamServerInfo{FullMethod: fullMethod, IsServerStream: true}
+
+	// заголовки SSE отдаём, только когда интерсептор пропустил вызов: отказ в доступе - обычный HTTP-ответ
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		stream.start()
+		return desc.Handler(srv, ss)
+	}
+	err := s.streamInterceptor(s, stream, info, handler)
+	switch {
+	case err == nil:
+	case stream.started:
+		stream.writeEvent("error", "", gatewayError(err))
+	default:
+		writeGatewayError(w, err)
+	}
+}
+
+// ----------------
+
+// sseStream - grpc.ServerStream поверх ответа HTTP: сообщения уходят событиями Server-Sent Events,
+// а единственный запрос стрима собирается из параметров query
+type sseStream struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	flusher http.Flusher
+	query   map[string][]string
+	started bool
+}
+
+func (st *sseStream) start() {
+	st.w.Header().Set("Content-Type", "text/event-stream")
+	st.w.Header().Set("Cache-Control", "no-cache")
+	st.w.WriteHeader(http.StatusOK)
+	st.flusher.Flush()
+	st.started = true
+}
+
+func (st *sseStream) writeEvent(name, id string, data []byte) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(st.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(st.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+	st.flusher.Flush()
+	return nil
+}
+
+func (st *sseStream) SendMsg(m interface{}) error {
+	msg := m.(proto.Message)
+	data, err := marshalGateway(msg)
+	if err != nil {
+		return err
+	}
+	// Id события пригодится клиенту для Last-Event-ID при переподключении
+	var id string
+	if evt, ok := msg.(*Event); ok {
+		id = strconv.FormatUint(evt.Id, 10)
+	}
+	name := strings.ToLower(string(msg.ProtoReflect().Descriptor().Name()))
+	return st.writeEvent(name, id, data)
+}
+
+func (st *sseStream) RecvMsg(m interface{}) error {
+	if err := queryToMessage(st.query, m.(proto.Message)); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+func (st *sseStream) Context() context.Context     { return st.ctx }
+func (st *sseStream) SetHeader(metadata.MD) error  { return nil }
+func (st *sseStream) SendHeader(metadata.MD) error { return nil }
+func (st *sseStream) SetTrailer(metadata.MD)       {}
+
+// queryToMessage заполняет поля сообщения из query: ?interval_seconds=2&methods=/main.Biz/*&methods=...
+// Поддерживаются строки, числа и enum (по имени или номеру), повторяющиеся поля - повтором параметра.
+func queryToMessage(query map[string][]string, m proto.Message) error {
+	msg := m.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	for name, values := range query {
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("unknown parameter %s", name)
+		}
+		if !fd.IsList() && len(values) > 1 {
+			return fmt.Errorf("parameter %s is not repeated", name)
+		}
+		for _, raw := range values {
+			value, err := parseQueryValue(fd, raw)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %v", name, err)
+			}
+			if fd.IsList() {
+				msg.Mutable(fd).List().Append(value)
+			} else {
+				msg.Set(fd, value)
+			}
+		}
+	}
+	return nil
+}
+
+func parseQueryValue(fd protoreflect.FieldDescriptor, raw string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(raw), nil
+	case protoreflect.Uint64Kind:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(raw)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported type %s", fd.Kind())
+}
+
+// ----------------
+
+// marshalGateway - JSON ответа шлюза с именами полей как в proto
+func marshalGateway(m proto.Message) ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+}
+
+// gatewayError - ошибка вызова в JSON: {"code": "Unauthenticated", "error": "..."}
+func gatewayError(err error) []byte {
+	st := status.Convert(err)
+	data, _ := json.Marshal(map[string]string{"code": st.Code().String(), "error": st.Message()})
+	return data
+}
+
+func writeGatewayError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(status.Code(err)))
+	w.Write(gatewayError(err))
+}
+
+// httpStatus - код ответа HTTP для кода gRPC
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// serveGateway запускает HTTP-шлюз на lis и останавливает его вместе с сервисом
+func (s *Service) serveGateway(lis net.Listener) {
+	server := &http.Server{Handler: s.gatewayHandler()}
+	go func() {
+		<-s.stopChan
+		// стримы SSE сами завершаются по stopChan, так что ждать их не нужно
+		server.Close()
+	}()
+	if err := server.Serve(lis); err != nil && err != http.ErrServerClosed {
+		log.Printf("Gateway error: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const gatewayAddr = "127.0.0.1:8083"
+
+func gatewayRequest(t *testing.T, method, path, consumer string) *http.Response {
+	req, err := http.NewRequest(method, "http://"+gatewayAddr+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consumer != "" {
+		req.Header.Set("Consumer", consumer)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("gateway request: %v", err)
+	}
+	return resp
+}
+
+// sseEvent читает из потока следующее событие Server-Sent Events
+func sseEvent(t *testing.T, r *bufio.Reader) (name string, data string) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestGateway(t *testing.T) {
+	acl := `{
+	"logger":    ["/main.Admin/LoggingFiltered"],
+	"stat1":     ["/main.Admin/Statistics"],
+	"biz_user":  ["/main.Biz/Check", "/main.Biz/Add"],
+	"biz_admin": ["/main.Biz/*"]
+}`
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, acl, WithHTTPGateway(gatewayAddr))
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	// ACL тот же, что и для gRPC
+	cases := []struct {
+		method, path, consumer string
+		code                   int
+	}{
+		{http.MethodPost, "/v1/biz/check", "biz_user", http.StatusOK},
+		{http.MethodPost, "/v1/biz/test", "biz_user", http.StatusUnauthorized},
+		{http.MethodPost, "/v1/biz/check", "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/biz/check", "biz_user", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/admin/logging", "biz_user", http.StatusUnauthorized},
+	}
+	for i, c := range cases {
+		resp := gatewayRequest(t, c.method, c.path, c.consumer)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Fatalf("[%d] %s %s: have %d %s, want %d", i, c.method, c.path, resp.StatusCode, body, c.code)
+		}
+	}
+
+	logResp := gatewayRequest(t, http.MethodGet, "/v1/admin/loggingfiltered?methods=/main.Biz/*", "logger")
+	defer logResp.Body.Close()
+	if ct := logResp.Header.Get("Content-Type"); logResp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("logging: have %d %s", logResp.StatusCode, ct)
+	}
+	statResp := gatewayRequest(t, http.MethodGet, "/v1/admin/statistics?interval_seconds=1&mode=DELTA&methods=/main.Biz/*", "stat1")
+	defer statResp.Body.Close()
+	wait(1)
+
+	gatewayRequest(t, http.MethodPost, "/v1/biz/add", "biz_user").Body.Close()
+	gatewayRequest(t, http.MethodPost, "/v1/biz/test", "biz_admin").Body.Close()
+
+	logReader := bufio.NewReader(logResp.Body)
+	logData := []*Event{}
+	for i := 0; i < 2; i++ {
+		name, data := sseEvent(t, logReader)
+		evt := &Event{}
+		if err := protojson.Unmarshal([]byte(data), evt); name != "event" || err != nil {
+			t.Fatalf("bad event %s %s: %v", name, data, err)
+		}
+		if !strings.HasPrefix(evt.Host, "127.0.0.1:") {
+			t.Fatalf("bad host: %s", evt.Host)
+		}
+		logData = append(logData, &Event{Consumer: evt.Consumer, Method: evt.Method})
+	}
+	expectedLog := []*Event{
+		{Consumer: "biz_user", Method: "/main.Biz/Add"},
+		{Consumer: "biz_admin", Method: "/main.Biz/Test"},
+	}
+	if !reflect.DeepEqual(logData, expectedLog) {
+		t.Fatalf("logs dont match\nhave %+v\nwant %+v", logData, expectedLog)
+	}
+
+	name, data := sseEvent(t, bufio.NewReader(statResp.Body))
+	stat := &Stat{}
+	if err := protojson.Unmarshal([]byte(data), stat); name != "stat" || err != nil {
+		t.Fatalf("bad stat %s %s: %v", name, data, err)
+	}
+	expectedStat := map[string]uint64{"/main.Biz/Add": 1, "/main.Biz/Test": 1}
+	if !reflect.DeepEqual(stat.ByMethod, expectedStat) {
+		t.Fatalf("stat dont match\nhave %v\nwant %v", stat.ByMethod, expectedStat)
+	}
+
+	// ошибки после начала стрима приходят событием error
+	badResp := gatewayRequest(t, http.MethodGet, "/v1/admin/statistics?interval_seconds=x", "stat1")
+	defer badResp.Body.Close()
+	if name, data := sseEvent(t, bufio.NewReader(badResp.Body)); name != "error" || !strings.Contains(data, "InvalidArgument") {
+		t.Fatalf("bad query: have %s %s", name, data)
+	}
+}
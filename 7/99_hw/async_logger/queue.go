@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// slowPolicy - что делать, когда подписчик Logging не успевает забирать события
+type slowPolicy string
+
+const (
+	// policyDropOldest - выбросить самое старое событие из очереди
+	policyDropOldest slowPolicy = "drop-oldest"
+	// policyDropNewest - выбросить пришедшее событие
+	policyDropNewest slowPolicy = "drop-newest"
+	// policyBlock - подождать, пока подписчик освободит место, но не дольше timeout
+	policyBlock slowPolicy = "block"
+	// policyDisconnect - отключить подписчика с codes.ResourceExhausted
+	policyDisconnect slowPolicy = "disconnect"
+)
+
+func parseSlowPolicy(s string) (slowPolicy, error) {
+	switch p := slowPolicy(s); p {
+	case policyDropOldest, policyDropNewest, policyBlock, policyDisconnect:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown slow subscriber policy %q, want one of %s, %s, %s, %s",
+		s, policyDropOldest, policyDropNewest, policyBlock, policyDisconnect)
+}
+
+// subscriberQueue - очередь событий одного подписчика Logging.
+// push никогда не блокируется, поэтому медленный подписчик не задерживает остальных:
+// при policyBlock "ждёт" не рассыльщик, а само событие - оно встаёт в очередь сверх размера,
+// и так не дольше timeout с момента, как очередь заполнилась.
+type subscriberQueue struct {
+	size    int
+	policy  slowPolicy
+	timeout time.Duration
+
+	mu         sync.Mutex
+	events     []*Event
+	fullSince  time.Time // когда очередь заполнилась, для policyBlock
+	overflowed bool      // policyDisconnect сработал, подписчика пора отключать
+	ready      chan struct{}
+}
+
+func newSubscriberQueue(size int, policy slowPolicy, timeout time.Duration) *subscriberQueue {
+	return &subscriberQueue{
+		size:    size,
+		policy:  policy,
+		timeout: timeout,
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// push кладёт событие в очередь и возвращает, сколько событий подписчик из-за этого не получит
+func (q *subscriberQueue) push(evt *Event, now time.Time) uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.overflowed {
+		return 1
+	}
+
+	var dropped uint64
+	if len(q.events) >= q.size {
+		switch q.policy {
+		case policyDropOldest:
+			copy(q.events, q.events[1:])
+			q.events = q.events[:len(q.events)-1]
+			dropped = 1
+		case policyDropNewest:
+			return 1
+		case policyBlock:
+			if q.fullSince.IsZero() {
+				q.fullSince = now
+			}
+			if now.Sub(q.fullSince) >= q.timeout {
+				return 1
+			}
+		case policyDisconnect:
+			dropped = uint64(len(q.events)) + 1
+			q.events = nil
+			q.overflowed = true
+			q.signal()
+			return dropped
+		}
+	}
+	q.events = append(q.events, evt)
+	q.signal()
+	return dropped
+}
+
+func (q *subscriberQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take забирает всё, что накопилось; overflowed - подписчика надо отключить
+func (q *subscriberQueue) take() (events []*Event, overflowed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	events = q.events
+	q.events = nil
+	q.fullSince = time.Time{}
+	return events, q.overflowed
+}
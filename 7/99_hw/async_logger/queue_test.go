@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func queueIDs(events []*Event) []uint64 {
+	ids := []uint64{}
+	for _, evt := range events {
+		ids = append(ids, evt.Id)
+	}
+	return ids
+}
+
+func TestSubscriberQueue(t *testing.T) {
+	start := time.Now()
+	cases := []struct {
+		policy     slowPolicy
+		pushAt     []time.Duration // когда пришло событие с Id i+1, от start
+		ids        []uint64
+		dropped    uint64
+		overflowed bool
+	}{
+		{policyDropOldest, []time.Duration{0, 0, 0, 0}, []uint64{3, 4}, 2, false},
+		{policyDropNewest, []time.Duration{0, 0, 0, 0}, []uint64{1, 2}, 2, false},
+		// два события ждут в пределах таймаута, после него - выбрасываются
+		{policyBlock, []time.Duration{0, 0, 0, 500 * time.Millisecond, 2 * time.Second}, []uint64{1, 2, 3, 4}, 1, false},
+		{policyDisconnect, []time.Duration{0, 0, 0, 0}, []uint64{}, 4, true},
+	}
+	for _, c := range cases {
+		q := newSubscriberQueue(2, c.policy, time.Second)
+		var dropped uint64
+		for i, at := range c.pushAt {
+			dropped += q.push(&Event{Id: uint64(i + 1)}, start.Add(at))
+		}
+		events, overflowed := q.take()
+		if ids := queueIDs(events); !reflect.DeepEqual(ids, c.ids) {
+			t.Errorf("%s: have %v, want %v", c.policy, ids, c.ids)
+		}
+		if dropped != c.dropped {
+			t.Errorf("%s: dropped %d, want %d", c.policy, dropped, c.dropped)
+		}
+		if overflowed != c.overflowed {
+			t.Errorf("%s: overflowed %v, want %v", c.policy, overflowed, c.overflowed)
+		}
+	}
+}
+
+func TestSubscriberQueueBlockResets(t *testing.T) {
+	start := time.Now()
+	q := newSubscriberQueue(1, policyBlock, time.Second)
+	q.push(&Event{Id: 1}, start)
+	q.push(&Event{Id: 2}, start)
+	q.take()
+	// подписчик забрал очередь - отсчёт таймаута начинается заново
+	q.push(&Event{Id: 3}, start.Add(5*time.Second))
+	if dropped := q.push(&Event{Id: 4}, start.Add(5*time.Second)); dropped != 0 {
+		t.Fatalf("dropped %d after queue was drained, want 0", dropped)
+	}
+}
+
+// медленный подписчик не задерживает остальных, а его потери видны в статистике
+func TestSlowSubscriberDoesNotBlockOthers(t *testing.T) {
+	s := NewService(map[string]*methodMatcher{}, newMemoryLog(100), options{queueSize: 5})
+	defer close(s.stopChan)
+
+	fast := newSubscriberQueue(100, policyBlock, time.Hour)
+	slow := newSubscriberQueue(5, policyBlock, 0)
+	s.mu.Lock()
+	s.logSubscribers = append(s.logSubscribers,
+		logSubscriber{consumer: "slow", queue: slow},
+		logSubscriber{consumer: "fast", queue: fast},
+	)
+	s.mu.Unlock()
+
+	for i := 0; i < 20; i++ {
+		s.logMethod("biz_user", "/main.Biz/Check", "127.0.0.1:1")
+	}
+
+	received := 0
+	timeout := time.After(time.Second)
+	for received < 20 {
+		select {
+		case <-fast.ready:
+			events, _ := fast.take()
+			received += len(events)
+		case <-timeout:
+			t.Fatalf("fast subscriber got %d of 20 events", received)
+		}
+	}
+
+	s.mu.Lock()
+	dropped := s.droppedStats["slow"]
+	s.mu.Unlock()
+	if dropped != 15 {
+		t.Fatalf("slow subscriber dropped %d, want 15", dropped)
+	}
+}
+
+// loggingStream - Admin_LoggingServer, у которого Send ждёт, пока тест его отпустит
+type loggingStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *Event
+}
+
+func (l *loggingStream) Context() context.Context {
+	return l.ctx
+}
+
+func (l *loggingStream) Send(evt *Event) error {
+	l.sent <- evt
+	return nil
+}
+
+func TestSlowSubscriberDisconnect(t *testing.T) {
+	s := NewService(map[string]*methodMatcher{}, newMemoryLog(100), options{queueSize: 2, slowPolicy: string(policyDropNewest)})
+	defer close(s.stopChan)
+
+	md := metadata.Pairs("consumer", "logger1", slowPolicyKey, string(policyDisconnect))
+	stream := &loggingStream{
+		ctx:  metadata.NewIncomingContext(context.Background(), md),
+		sent: make(chan *Event),
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Logging(&Nothing{}, stream)
+	}()
+	wait(1)
+
+	// первое событие застревает в Send, следующие переполняют очередь
+	for i := 0; i < 5; i++ {
+		s.logMethod("biz_user", "/main.Biz/Check", "127.0.0.1:1")
+	}
+	wait(1)
+	go func() {
+		for range stream.sent {
+		}
+	}()
+
+	select {
+	case err := <-result:
+		close(stream.sent)
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("have %v, want ResourceExhausted", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("slow subscriber was not disconnected")
+	}
+}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"google.golang.org/grpc/peer"
 	"log"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
@@ -20,201 +23,244 @@ import (
 type Service struct {
 	UnimplementedAdminServer
 	UnimplementedBizServer
+	UnimplementedAdminACLServer
 	mu             sync.Mutex
-	logChan        chan *Event
 	methodStats    map[string]uint64
 	consumerStats  map[string]uint64
-	clientsACL     map[string][]string
+	clientsACL     map[string]*methodMatcher
 	stopChan       chan struct{}
 	logSubscribers []logSubscriber
+	events         eventLog // журнал событий, из него Logging досылает пропущенное
+	droppedStats   map[string]uint64
+	latencyStats   map[string]*latencyHistogram
+	opts           options
+	aclFile        *aclWatcher // файл, из которого взят ACL; nil - ACL из ACLData
 }
 
 type logSubscriber struct { //тип подписчика
 	consumer string
-	ch       chan *Event
+	queue    *subscriberQueue
+	filter   *eventFilter
 }
 
-// NewService - конструктор сервиса
-func NewService(acl map[string][]string) *Service {
-	s := &Service{
-		logChan:        make(chan *Event, 100),
-		methodStats:    make(map[string]uint64),
-		consumerStats:  make(map[string]uint64),
-		clientsACL:     acl, // Используем переданный ACL
-		stopChan:       make(chan struct{}),
-		logSubscribers: []logSubscriber{}, //  новый тип подписчика
-	}
-	go s.logDistributor()
-	return s
+const (
+	// размер журнала в памяти, если каталог для журнала не задан
+	defaultEventLogSize = 1000
+	// метаданные с Id последнего полученного события, Logging продолжит со следующего
+	lastEventIDKey = "last-event-id"
+
+	defaultQueueSize   = 100
+	defaultSlowPolicy  = policyDropNewest
+	defaultSlowTimeout = time.Second
+	// метаданные, которыми подписчик Logging выбирает себе slowPolicy
+	slowPolicyKey = "slow-policy"
+)
+
+// Option - необязательные настройки StartMyMicroservice
+type Option func(*options)
+
+type options struct {
+	eventLogDir  string
+	eventLogSize int
+	queueSize    int
+	slowPolicy   string
+	slowTimeout  time.Duration
+	aclFile      string
+	aclInterval  time.Duration
+	// с tls консюмер берётся из клиентского сертификата, consumerHeader - разрешить клиентов без сертификата
+	tls            *tls.Config
+	consumerHeader bool
+	gatewayAddr    string
 }
 
-// checkACL - проверка доступа клиента
-func (s *Service) checkACL(ctx context.Context, method string) error {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return status.Error(codes.Unauthenticated, "no metadata provided")
+// WithSubscriberQueue - размер очереди каждого подписчика Logging и что делать, когда она полна:
+// drop-oldest, drop-newest, block (timeout - сколько ждать) или disconnect.
+// Подписчик может выбрать другую политику метаданными slow-policy.
+func WithSubscriberQueue(size int, policy string, timeout time.Duration) Option {
+	return func(o *options) {
+		o.queueSize = size
+		o.slowPolicy = policy
+		o.slowTimeout = timeout
 	}
+}
 
-	consumerIDs := md.Get("consumer")
-	if len(consumerIDs) == 0 {
-		return status.Error(codes.Unauthenticated, "consumer not provided")
+// WithEventLog - хранить журнал событий на диске в каталоге dir, не больше maxEvents последних событий.
+// Журнал переживает перезапуск сервиса, и Logging может продолжить с того места, где клиент остановился.
+func WithEventLog(dir string, maxEvents int) Option {
+	return func(o *options) {
+		o.eventLogDir = dir
+		o.eventLogSize = maxEvents
 	}
+}
 
-	consumerID := consumerIDs[0]
+// WithACLFile - брать ACL из файла path вместо ACLData и перечитывать его, если файл поменялся.
+// Файл проверяется раз в interval; при interval <= 0 он читается только при запуске.
+// Grant и Revoke через AdminACL записываются в этот же файл, так что перечитывание их не теряет.
+func WithACLFile(path string, interval time.Duration) Option {
+	return func(o *options) {
+		o.aclFile = path
+		o.aclInterval = interval
+	}
+}
 
-	s.mu.Lock()
-	allowedMethods, exists := s.clientsACL[consumerID]
-	s.mu.Unlock()
+// WithTLS - принимать соединения по TLS с сертификатом cert и проверять клиентские сертификаты по clientCAs.
+// Консюмер тогда определяется по сертификату клиента, а не по метаданным consumer.
+func WithTLS(cert tls.Certificate, clientCAs *x509.CertPool) Option {
+	return func(o *options) {
+		o.tls = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    clientCAs,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+}
 
-	// Если consumer отсутствует в ACL, возвращаем Unauthenticated
-	if !exists {
-		return status.Error(codes.Unauthenticated, "consumer not registered")
+// WithConsumerHeaderFallback - dev-режим для WithTLS: клиент без сертификата может
+// представиться метаданными consumer, как без TLS. Сертификат, если он есть, всё равно проверяется.
+func WithConsumerHeaderFallback() Option {
+	return func(o *options) {
+		o.consumerHeader = true
 	}
+}
 
-	// Проверяем, разрешён ли метод
-	for _, allowedMethod := range allowedMethods {
-		if allowedMethod == method || allowedMethod == "*" ||
-			(len(allowedMethod) > 1 && allowedMethod[len(allowedMethod)-1] == '*' &&
-				method[:len(allowedMethod)-1] == allowedMethod[:len(allowedMethod)-1]) {
-			return nil
-		}
+// WithHTTPGateway - поднять рядом с gRPC HTTP-шлюз на addr: Biz через POST с JSON, стримы Admin через SSE.
+// Консюмер шлюза берётся из заголовка Consumer, поэтому с WithTLS он работает только вместе с WithConsumerHeaderFallback.
+func WithHTTPGateway(addr string) Option {
+	return func(o *options) {
+		o.gatewayAddr = addr
 	}
+}
 
-	// Если метод не разрешён, возвращаем Unauthenticated
-	return status.Error(codes.Unauthenticated, "method access denied")
+// NewService - конструктор сервиса
+func NewService(acl map[string]*methodMatcher, events eventLog, opts options) *Service {
+	s := &Service{
+		methodStats:    make(map[string]uint64),
+		consumerStats:  make(map[string]uint64),
+		clientsACL:     acl, // Используем переданный ACL
+		stopChan:       make(chan struct{}),
+		logSubscribers: []logSubscriber{}, //  новый тип подписчика
+		events:         events,
+		droppedStats:   make(map[string]uint64),
+		latencyStats:   make(map[string]*latencyHistogram),
+		opts:           opts,
+	}
+	return s
 }
 
-// logMethod - логирование вызова метода
+// logMethod - логирование вызова метода: событие сразу уходит подписчикам и в журнал.
+// Всё под одной блокировкой, поэтому порядок событий у всех подписчиков одинаковый,
+// а подписавшийся позже не получит событий, случившихся до него.
 func (s *Service) logMethod(consumer, method, host string) {
-	/*if method == "/main.Admin/Statistics" {
-		return
-	}*/
-
 	event := &Event{
 		Timestamp: time.Now().Unix(),
 		Consumer:  consumer,
 		Method:    method,
 		Host:      host,
 	}
-	//log.Printf("Updating consumer stats: consumer=%s count=%d", consumer, s.consumerStats[consumer]+1)
-	s.logChan <- event
 
 	s.mu.Lock()
 	s.methodStats[method]++
 	s.consumerStats[consumer]++
+	s.publishLocked(event)
 	s.mu.Unlock()
 }
 
-// logDistributor - распределение логов
-func (s *Service) logDistributor() {
-	for {
-		select {
-		case logEntry := <-s.logChan:
-			//fmt.Printf("logDistributor: got event %+v\n", logEntry)
-			s.mu.Lock()
-			for _, sub := range s.logSubscribers {
-				// Попытка отправить событие в канал подписчика
-				select {
-				case sub.ch <- logEntry:
-					fmt.Printf("logDistributor: sent event %+v to subscriber %s\n", logEntry, sub.consumer)
-				default:
-					fmt.Printf("logDistributor: subscriber %s channel full, event dropped\n", sub.consumer)
-				}
-			}
-			s.mu.Unlock()
-		case <-s.stopChan:
-			return
+// observeLatency - время выполнения unary-метода для перцентилей в Statistics
+func (s *Service) observeLatency(method string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.latencyStats[method]
+	if !ok {
+		h = &latencyHistogram{}
+		s.latencyStats[method] = h
+	}
+	h.observe(d)
+}
+
+// publishLocked записывает событие в журнал и раскладывает по очередям подписчиков, s.mu захвачен.
+// Под одной блокировкой новый подписчик либо получит событие из очереди, либо увидит его в журнале.
+// push не блокируется, так что блокировка держится недолго при любом числе подписчиков.
+func (s *Service) publishLocked(evt *Event) {
+	if err := s.events.Append(evt); err != nil {
+		log.Printf("event log append failed: %v", err)
+	}
+	now := time.Now()
+	for _, sub := range s.logSubscribers {
+		if !sub.filter.match(evt) {
+			continue
+		}
+		if dropped := sub.queue.push(evt, now); dropped > 0 {
+			s.droppedStats[sub.consumer] += dropped
 		}
 	}
 }
 
 // Check - метод бизнес-логики
 func (s *Service) Check(ctx context.Context, req *Nothing) (*Nothing, error) {
-	if err := s.checkACL(ctx, "/main.Biz/Check"); err != nil {
-		return nil, err
-	}
-	md, _ := metadata.FromIncomingContext(ctx)
-	consumer := md.Get("consumer")[0]
-
-	var host string
-	if p, ok := peer.FromContext(ctx); ok {
-		host = p.Addr.String()
-	} else {
-		host = "127.0.0.1:unknown"
-	}
-	s.logMethod(consumer, "/main.Biz/Check", host)
 	return &Nothing{Dummy: true}, nil
 }
 
 // Add - метод бизнес-логики
 func (s *Service) Add(ctx context.Context, req *Nothing) (*Nothing, error) {
-	if err := s.checkACL(ctx, "/main.Biz/Add"); err != nil {
-		return nil, err
-	}
-	md, _ := metadata.FromIncomingContext(ctx)
-	consumer := md.Get("consumer")[0]
-	var host string
-	if p, ok := peer.FromContext(ctx); ok {
-		host = p.Addr.String()
-	} else {
-		host = "127.0.0.1:unknown"
-	}
-	s.logMethod(consumer, "/main.Biz/Add", host)
 	return &Nothing{Dummy: true}, nil
 }
 
 // Test - метод бизнес-логики
 func (s *Service) Test(ctx context.Context, req *Nothing) (*Nothing, error) {
-	if err := s.checkACL(ctx, "/main.Biz/Test"); err != nil {
-		return nil, err
-	}
-	md, _ := metadata.FromIncomingContext(ctx)
-	consumer := md.Get("consumer")[0]
-
-	// Получаем реальный адрес клиента из контекста
-	var host string
-	if p, ok := peer.FromContext(ctx); ok {
-		host = p.Addr.String()
-	} else {
-		host = "127.0.0.1:unknown"
-	}
-
-	s.logMethod(consumer, "/main.Biz/Test", host)
 	return &Nothing{Dummy: true}, nil
 }
 
-// Logging - потоковая передача логов
+// Logging - потоковая передача логов.
+// Если в метаданных передан last-event-id, сначала досылаются события после него из журнала,
+// затем поток переключается на новые события.
 func (s *Service) Logging(_ *Nothing, stream Admin_LoggingServer) error {
-	if err := s.checkACL(stream.Context(), "/main.Admin/Logging"); err != nil {
+	return s.streamEvents(stream, nil)
+}
+
+// LoggingFiltered - то же, что Logging, но только события, подходящие под фильтр
+func (s *Service) LoggingFiltered(req *LogFilter, stream Admin_LoggingFilteredServer) error {
+	filter, err := newEventFilter(req)
+	if err != nil {
+		return err
+	}
+	return s.streamEvents(stream, filter)
+}
+
+// streamEvents отправляет в stream события, подходящие под filter, кроме событий самого подписчика
+func (s *Service) streamEvents(stream Admin_LoggingServer, filter *eventFilter) error {
+	// кто подписался - тот же, кого проверял ACL: при mTLS метаданные consumer не учитываются
+	consumerID, err := s.consumerFromContext(stream.Context())
+	if err != nil {
 		return err
 	}
 	md, _ := metadata.FromIncomingContext(stream.Context())
-	consumerIDs := md.Get("consumer")
-	consumerID := consumerIDs[0]
 
-	var host string
-	if p, ok := peer.FromContext(stream.Context()); ok {
-		host = p.Addr.String()
-	} else {
-		host = "127.0.0.1:unknown"
+	resumeFrom, resume, err := lastEventID(md)
+	if err != nil {
+		return err
+	}
+	policy, err := s.subscriberPolicy(md)
+	if err != nil {
+		return err
 	}
 
-	// Создаем канал подписчика
-	ch := make(chan *Event, 10)
+	// Создаем очередь подписчика
+	queue := newSubscriberQueue(s.opts.queueSize, policy, s.opts.slowTimeout)
 	s.mu.Lock()
 	s.logSubscribers = append(s.logSubscribers, logSubscriber{
 		consumer: consumerID,
-		ch:       ch,
+		queue:    queue,
+		filter:   filter,
 	})
+	// всё, что записано в журнал до подписки, в очередь уже не придёт
+	lastSent := s.events.Last()
 	s.mu.Unlock()
 
-	// Логируем вызов метода Logging
-	s.logMethod(consumerID, "/main.Admin/Logging", host)
-
 	defer func() {
 		s.mu.Lock()
 		for i, sub := range s.logSubscribers {
-			if sub.ch == ch {
+			if sub.queue == queue {
 				s.logSubscribers = append(s.logSubscribers[:i], s.logSubscribers[i+1:]...)
 				break
 			}
@@ -222,119 +268,46 @@ func (s *Service) Logging(_ *Nothing, stream Admin_LoggingServer) error {
 		s.mu.Unlock()
 	}()
 
-	for {
-		select {
-		case evt, ok := <-ch:
-			if !ok {
-				return nil
-			}
-			// Пропускаем событие, если оно создано самим подписчиком
-			if evt.Consumer == consumerID {
-				continue
-			}
-			if err := stream.Send(evt); err != nil {
-				return err
-			}
-		case <-stream.Context().Done():
+	send := func(evt *Event) error {
+		// событие могло прийти и из журнала, и из очереди
+		if evt.Id <= lastSent {
 			return nil
-		case <-s.stopChan:
+		}
+		lastSent = evt.Id
+		// Пропускаем событие, если оно создано самим подписчиком
+		if evt.Consumer == consumerID || !filter.match(evt) {
 			return nil
 		}
+		return stream.Send(evt)
 	}
-}
-
-// Statistics - потоковая передача статистики
-func (s *Service) Statistics(req *StatInterval, stream Admin_StatisticsServer) error {
-	md, _ := metadata.FromIncomingContext(stream.Context())
-	consumer := md.Get("consumer")[0]
-
-	var host string
-	if p, ok := peer.FromContext(stream.Context()); ok {
-		host = p.Addr.String()
-	} else {
-		host = "127.0.0.1:unknown"
-	}
-
-	// Для "stat2" логируем вызов Statistics (накопительная статистика)
-	if consumer == "stat2" {
-		s.logMethod(consumer, "/main.Admin/Statistics", host)
+	catchUp := func(since uint64) error {
+		err := s.events.Since(since, send)
+		if err == errLogTruncated {
+			return status.Errorf(codes.OutOfRange,
+				"events after %d were dropped from the log, resume from %d", since, s.events.First()-1)
+		}
+		return err
 	}
 
-	interval := time.Duration(req.IntervalSeconds) * time.Second
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	// Если клиент "stat1", фиксируем baseline при подключении.
-	var baselineMethod map[string]uint64
-	var baselineConsumer map[string]uint64
-	isDelta := false
-	if consumer == "stat1" {
-		s.mu.Lock()
-		baselineMethod = make(map[string]uint64)
-		baselineConsumer = make(map[string]uint64)
-		for k, v := range s.methodStats {
-			baselineMethod[k] = v
-		}
-		for k, v := range s.consumerStats {
-			baselineConsumer[k] = v
+	if resume && resumeFrom < lastSent {
+		lastSent = resumeFrom
+		if err := catchUp(resumeFrom); err != nil {
+			return err
 		}
-		// Устанавливаем baseline для "/main.Admin/Statistics" равным 0,
-		// чтобы любые последующие вызовы этого метода учитывались как изменения.
-		baselineMethod["/main.Admin/Statistics"] = 0
-		s.mu.Unlock()
-		isDelta = true
 	}
 
 	for {
 		select {
-		case <-ticker.C:
-			s.mu.Lock()
-			currentMethod := make(map[string]uint64)
-			currentConsumer := make(map[string]uint64)
-			for k, v := range s.methodStats {
-				currentMethod[k] = v
-			}
-			for k, v := range s.consumerStats {
-				currentConsumer[k] = v
+		case <-queue.ready:
+			events, overflowed := queue.take()
+			if overflowed {
+				return status.Errorf(codes.ResourceExhausted,
+					"subscriber is too slow, queue of %d events overflowed", s.opts.queueSize)
 			}
-			s.mu.Unlock()
-
-			if isDelta {
-				// Вычисляем дельту для клиента stat1
-				deltaMethod := make(map[string]uint64)
-				deltaConsumer := make(map[string]uint64)
-				for k, v := range currentMethod {
-					b := baselineMethod[k]
-					if v > b {
-						deltaMethod[k] = v - b
-					}
-				}
-				for k, v := range currentConsumer {
-					b := baselineConsumer[k]
-					if v > b {
-						deltaConsumer[k] = v - b
-					}
+			for _, evt := range events {
+				if err := send(evt); err != nil {
+					return err
 				}
-				// Обновляем baseline для следующего такта.
-				baselineMethod = currentMethod
-				baselineConsumer = currentConsumer
-
-				currentMethod = deltaMethod
-				currentConsumer = deltaConsumer
-			} else if consumer == "stat2" {
-				// Для клиента stat2 используем накопительную статистику,
-				// но исключаем записи по методу "/main.Admin/Statistics" и consumer "stat2"
-				delete(currentMethod, "/main.Admin/Statistics")
-				delete(currentConsumer, "stat2")
-			}
-
-			stat := &Stat{
-				Timestamp:  time.Now().Unix(),
-				ByMethod:   currentMethod,
-				ByConsumer: currentConsumer,
-			}
-			if err := stream.Send(stat); err != nil {
-				return err
 			}
 		case <-stream.Context().Done():
 			return nil
@@ -344,31 +317,118 @@ func (s *Service) Statistics(req *StatInterval, stream Admin_StatisticsServer) e
 	}
 }
 
+// lastEventID разбирает позицию, с которой клиент хочет продолжить Logging
+func lastEventID(md metadata.MD) (uint64, bool, error) {
+	values := md.Get(lastEventIDKey)
+	if len(values) == 0 {
+		return 0, false, nil
+	}
+	id, err := strconv.ParseUint(values[0], 10, 64)
+	if err != nil {
+		return 0, false, status.Errorf(codes.InvalidArgument, "%s must be an event id", lastEventIDKey)
+	}
+	return id, true, nil
+}
+
+// subscriberPolicy - политика из метаданных slow-policy, иначе заданная при запуске
+func (s *Service) subscriberPolicy(md metadata.MD) (slowPolicy, error) {
+	values := md.Get(slowPolicyKey)
+	if len(values) == 0 {
+		return slowPolicy(s.opts.slowPolicy), nil
+	}
+	policy, err := parseSlowPolicy(values[0])
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+	return policy, nil
+}
+
 // StartMyMicroservice - запуск микросервиса
-func StartMyMicroservice(ctx context.Context, listenAddr string, ACLData string) error {
+func StartMyMicroservice(ctx context.Context, listenAddr string, ACLData string, opts ...Option) error {
 	// Парсинг ACLData (предполагаем, что это строка с клиентами, разделенными запятыми)
 	//fmt.Println("ACLData:", ACLData)
 
-	var clientsACL map[string][]string
-	err := json.Unmarshal([]byte(ACLData), &clientsACL)
+	o := options{
+		eventLogSize: defaultEventLogSize,
+		queueSize:    defaultQueueSize,
+		slowPolicy:   string(defaultSlowPolicy),
+		slowTimeout:  defaultSlowTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var aclFile *aclWatcher
+	var clientsACL map[string]*methodMatcher
+	var err error
+	if o.aclFile != "" {
+		aclFile = &aclWatcher{path: o.aclFile, interval: o.aclInterval}
+		clientsACL, _, err = aclFile.load()
+	} else {
+		clientsACL, err = parseACL(ACLData)
+	}
 	if err != nil {
 		fmt.Println("Error parsing JSON:", err)
 		return fmt.Errorf("failed to parse ACL data: %v", err)
 	}
-
-	// Передаём ACL в сервис
-	s := NewService(clientsACL)
+	if o.eventLogSize <= 0 {
+		return fmt.Errorf("event log size must be positive, got %d", o.eventLogSize)
+	}
+	if o.queueSize <= 0 {
+		return fmt.Errorf("subscriber queue size must be positive, got %d", o.queueSize)
+	}
+	if _, err := parseSlowPolicy(o.slowPolicy); err != nil {
+		return err
+	}
+	if o.tls != nil && o.consumerHeader {
+		o.tls.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	var events eventLog = newMemoryLog(o.eventLogSize)
+	if o.eventLogDir != "" {
+		fl, err := openFileLog(o.eventLogDir, o.eventLogSize)
+		if err != nil {
+			return fmt.Errorf("failed to open event log: %v", err)
+		}
+		events = fl
+	}
 
 	lis, err := net.Listen("tcp", listenAddr)
 	if err != nil {
+		events.Close()
 		return err
 	}
+	var gatewayLis net.Listener
+	if o.gatewayAddr != "" {
+		gatewayLis, err = net.Listen("tcp", o.gatewayAddr)
+		if err != nil {
+			lis.Close()
+			events.Close()
+			return err
+		}
+	}
+
+	// Передаём ACL в сервис
+	s := NewService(clientsACL, events, o)
+	s.aclFile = aclFile
 
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(interceptor),
-	)
+	serverOpts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	}
+	if o.tls != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(o.tls)))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 	RegisterAdminServer(grpcServer, s)
 	RegisterBizServer(grpcServer, s)
+	RegisterAdminACLServer(grpcServer, s)
+
+	if gatewayLis != nil {
+		go s.serveGateway(gatewayLis)
+	}
+	if aclFile != nil && aclFile.interval > 0 {
+		go s.watchACL(aclFile)
+	}
 
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
@@ -381,30 +441,53 @@ func StartMyMicroservice(ctx context.Context, listenAddr string, ACLData string)
 		<-ctx.Done()
 		close(s.stopChan)         // Закрываем канал для остановки горутин
 		grpcServer.GracefulStop() // Плавно завершаем сервер
+		if err := s.events.Close(); err != nil {
+			log.Printf("Event log close error: %v", err)
+		}
 	}()
 
 	return nil
 }
 
-func interceptor(
+// unaryInterceptor проверяет доступ, пишет вызов в лог и статистику и замеряет время выполнения
+func (s *Service) unaryInterceptor(
 	ctx context.Context,
 	req interface{},
 	info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler,
 ) (interface{}, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	//fmt.Println("Received metadata:", md) // Логируем все метаданные
-
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "no metadata provided")
+	consumer, err := s.checkACL(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
 	}
+	s.logMethod(consumer, info.FullMethod, peerHost(ctx))
 
-	consumerIDs := md.Get("consumer") // Ищем "consumer" вместо "client_id"
-	if len(consumerIDs) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "consumer not provided")
-	}
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.observeLatency(info.FullMethod, time.Since(start))
+	return resp, err
+}
 
-	fmt.Println("consumer:", consumerIDs[0]) // Логируем найденный consumer
+// streamInterceptor - то же для потоковых методов; вызов учитывается при открытии потока,
+// время выполнения не замеряется - поток живёт, пока клиент его не закроет
+func (s *Service) streamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	consumer, err := s.checkACL(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	s.logMethod(consumer, info.FullMethod, peerHost(ss.Context()))
+	return handler(srv, ss)
+}
 
-	return handler(ctx, req)
+// peerHost - адрес клиента для Event.Host
+func peerHost(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return "127.0.0.1:unknown"
 }
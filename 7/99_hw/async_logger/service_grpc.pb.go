@@ -23,6 +23,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AdminClient interface {
 	Logging(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (Admin_LoggingClient, error)
+	LoggingFiltered(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (Admin_LoggingFilteredClient, error)
 	Statistics(ctx context.Context, in *StatInterval, opts ...grpc.CallOption) (Admin_StatisticsClient, error)
 }
 
@@ -66,8 +67,40 @@ func (x *adminLoggingClient) Recv() (*Event, error) {
 	return m, nil
 }
 
+func (c *adminClient) LoggingFiltered(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (Admin_LoggingFilteredClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Admin_ServiceDesc.Streams[1], "/main.Admin/LoggingFiltered", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adminLoggingFilteredClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Admin_LoggingFilteredClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type adminLoggingFilteredClient struct {
+	grpc.ClientStream
+}
+
+func (x *adminLoggingFilteredClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *adminClient) Statistics(ctx context.Context, in *StatInterval, opts ...grpc.CallOption) (Admin_StatisticsClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Admin_ServiceDesc.Streams[1], "/main.Admin/Statistics", opts...)
+	stream, err := c.cc.NewStream(ctx, &Admin_ServiceDesc.Streams[2], "/main.Admin/Statistics", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,6 +136,7 @@ func (x *adminStatisticsClient) Recv() (*Stat, error) {
 // for forward compatibility
 type AdminServer interface {
 	Logging(*Nothing, Admin_LoggingServer) error
+	LoggingFiltered(*LogFilter, Admin_LoggingFilteredServer) error
 	Statistics(*StatInterval, Admin_StatisticsServer) error
 	mustEmbedUnimplementedAdminServer()
 }
@@ -114,6 +148,9 @@ type UnimplementedAdminServer struct {
 func (UnimplementedAdminServer) Logging(*Nothing, Admin_LoggingServer) error {
 	return status.Errorf(codes.Unimplemented, "method Logging not implemented")
 }
+func (UnimplementedAdminServer) LoggingFiltered(*LogFilter, Admin_LoggingFilteredServer) error {
+	return status.Errorf(codes.Unimplemented, "method LoggingFiltered not implemented")
+}
 func (UnimplementedAdminServer) Statistics(*StatInterval, Admin_StatisticsServer) error {
 	return status.Errorf(codes.Unimplemented, "method Statistics not implemented")
 }
@@ -151,6 +188,27 @@ func (x *adminLoggingServer) Send(m *Event) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Admin_LoggingFiltered_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogFilter)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdminServer).LoggingFiltered(m, &adminLoggingFilteredServer{stream})
+}
+
+type Admin_LoggingFilteredServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type adminLoggingFilteredServer struct {
+	grpc.ServerStream
+}
+
+func (x *adminLoggingFilteredServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Admin_Statistics_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(StatInterval)
 	if err := stream.RecvMsg(m); err != nil {
@@ -185,6 +243,11 @@ var Admin_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Admin_Logging_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "LoggingFiltered",
+			Handler:       _Admin_LoggingFiltered_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "Statistics",
 			Handler:       _Admin_Statistics_Handler,
@@ -194,6 +257,168 @@ var Admin_ServiceDesc = grpc.ServiceDesc{
 	Metadata: "service.proto",
 }
 
+// AdminACLClient is the client API for AdminACL service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AdminACLClient interface {
+	List(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ACL, error)
+	// добавляет методы консюмеру, создаёт его, если не было
+	Grant(ctx context.Context, in *ACLEntry, opts ...grpc.CallOption) (*ACL, error)
+	// убирает методы у консюмера, без методов - удаляет консюмера целиком
+	Revoke(ctx context.Context, in *ACLEntry, opts ...grpc.CallOption) (*ACL, error)
+}
+
+type adminACLClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminACLClient(cc grpc.ClientConnInterface) AdminACLClient {
+	return &adminACLClient{cc}
+}
+
+func (c *adminACLClient) List(ctx context.Context, in *Nothing, opts ...grpc.CallOption) (*ACL, error) {
+	out := new(ACL)
+	err := c.cc.Invoke(ctx, "/main.AdminACL/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminACLClient) Grant(ctx context.Context, in *ACLEntry, opts ...grpc.CallOption) (*ACL, error) {
+	out := new(ACL)
+	err := c.cc.Invoke(ctx, "/main.AdminACL/Grant", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminACLClient) Revoke(ctx context.Context, in *ACLEntry, opts ...grpc.CallOption) (*ACL, error) {
+	out := new(ACL)
+	err := c.cc.Invoke(ctx, "/main.AdminACL/Revoke", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminACLServer is the server API for AdminACL service.
+// All implementations must embed UnimplementedAdminACLServer
+// for forward compatibility
+type AdminACLServer interface {
+	List(context.Context, *Nothing) (*ACL, error)
+	// добавляет методы консюмеру, создаёт его, если не было
+	Grant(context.Context, *ACLEntry) (*ACL, error)
+	// убирает методы у консюмера, без методов - удаляет консюмера целиком
+	Revoke(context.Context, *ACLEntry) (*ACL, error)
+	mustEmbedUnimplementedAdminACLServer()
+}
+
+// UnimplementedAdminACLServer must be embedded to have forward compatible implementations.
+type UnimplementedAdminACLServer struct {
+}
+
+func (UnimplementedAdminACLServer) List(context.Context, *Nothing) (*ACL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedAdminACLServer) Grant(context.Context, *ACLEntry) (*ACL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Grant not implemented")
+}
+func (UnimplementedAdminACLServer) Revoke(context.Context, *ACLEntry) (*ACL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Revoke not implemented")
+}
+func (UnimplementedAdminACLServer) mustEmbedUnimplementedAdminACLServer() {}
+
+// UnsafeAdminACLServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminACLServer will
+// result in compilation errors.
+type UnsafeAdminACLServer interface {
+	mustEmbedUnimplementedAdminACLServer()
+}
+
+func RegisterAdminACLServer(s grpc.ServiceRegistrar, srv AdminACLServer) {
+	s.RegisterService(&AdminACL_ServiceDesc, srv)
+}
+
+func _AdminACL_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Nothing)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminACLServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.AdminACL/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminACLServer).List(ctx, req.(*Nothing))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminACL_Grant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ACLEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminACLServer).Grant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.AdminACL/Grant",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminACLServer).Grant(ctx, req.(*ACLEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminACL_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ACLEntry)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminACLServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.AdminACL/Revoke",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminACLServer).Revoke(ctx, req.(*ACLEntry))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminACL_ServiceDesc is the grpc.ServiceDesc for AdminACL service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminACL_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "main.AdminACL",
+	HandlerType: (*AdminACLServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _AdminACL_List_Handler,
+		},
+		{
+			MethodName: "Grant",
+			Handler:    _AdminACL_Grant_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _AdminACL_Revoke_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service.proto",
+}
+
 // BizClient is the client API for Biz service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// границы корзин: minLatencyBucket, вдвое больше и так далее, последняя - всё, что дольше
+	latencyBuckets   = 24
+	minLatencyBucket = 100 * time.Microsecond
+)
+
+// latencyHistogram - число вызовов по корзинам времени выполнения.
+// Счётчики только растут, поэтому гистограммы, как и остальная статистика, вычитаются.
+type latencyHistogram [latencyBuckets]uint64
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	bound := minLatencyBucket
+	for i := 0; i < latencyBuckets-1; i++ {
+		if d <= bound {
+			h[i]++
+			return
+		}
+		bound *= 2
+	}
+	h[latencyBuckets-1]++
+}
+
+func (h latencyHistogram) count() uint64 {
+	var total uint64
+	for _, n := range h {
+		total += n
+	}
+	return total
+}
+
+// percentile - верхняя граница корзины, в которую попал p-й перцентиль, в миллисекундах
+func (h latencyHistogram) percentile(p float64) float64 {
+	total := h.count()
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(p*float64(total) + 0.5)
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	bound := minLatencyBucket
+	for i, n := range h {
+		seen += n
+		if seen >= rank || i == latencyBuckets-1 {
+			break
+		}
+		bound *= 2
+	}
+	return float64(bound) / float64(time.Millisecond)
+}
+
+func (h latencyHistogram) sub(base latencyHistogram) latencyHistogram {
+	for i := range h {
+		h[i] -= base[i]
+	}
+	return h
+}
+
+// statSnapshot - копия счётчиков сервиса на момент времени
+type statSnapshot struct {
+	byMethod   map[string]uint64
+	byConsumer map[string]uint64
+	dropped    map[string]uint64
+	latency    map[string]latencyHistogram
+}
+
+func (s *Service) snapshot() statSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := statSnapshot{
+		byMethod:   copyStats(s.methodStats),
+		byConsumer: copyStats(s.consumerStats),
+		dropped:    copyStats(s.droppedStats),
+		latency:    make(map[string]latencyHistogram, len(s.latencyStats)),
+	}
+	for method, h := range s.latencyStats {
+		snap.latency[method] = *h
+	}
+	return snap
+}
+
+func copyStats(stats map[string]uint64) map[string]uint64 {
+	res := make(map[string]uint64, len(stats))
+	for k, v := range stats {
+		res[k] = v
+	}
+	return res
+}
+
+// sub - что изменилось с base; ключи без изменений не попадают в результат
+func (snap statSnapshot) sub(base statSnapshot) statSnapshot {
+	res := statSnapshot{
+		byMethod:   subStats(snap.byMethod, base.byMethod),
+		byConsumer: subStats(snap.byConsumer, base.byConsumer),
+		dropped:    subStats(snap.dropped, base.dropped),
+		latency:    make(map[string]latencyHistogram),
+	}
+	for method, h := range snap.latency {
+		if diff := h.sub(base.latency[method]); diff.count() > 0 {
+			res.latency[method] = diff
+		}
+	}
+	return res
+}
+
+func subStats(cur, base map[string]uint64) map[string]uint64 {
+	res := make(map[string]uint64)
+	for k, v := range cur {
+		if v > base[k] {
+			res[k] = v - base[k]
+		}
+	}
+	return res
+}
+
+// toStat собирает сообщение, оставляя только запрошенные методы и консюмеров
+func (snap statSnapshot) toStat(req *StatInterval) *Stat {
+	stat := &Stat{
+		Timestamp:  time.Now().Unix(),
+		ByMethod:   snap.byMethod,
+		ByConsumer: snap.byConsumer,
+		// сколько событий Logging не досталось медленным подписчикам
+		DroppedByConsumer: snap.dropped,
+		LatencyByMethod:   make(map[string]*Latency, len(snap.latency)),
+	}
+	for method, h := range snap.latency {
+		stat.LatencyByMethod[method] = &Latency{
+			Count: h.count(),
+			P50Ms: h.percentile(0.5),
+			P90Ms: h.percentile(0.9),
+			P99Ms: h.percentile(0.99),
+		}
+	}
+
+	// ограничения из запроса применяем здесь, чтобы клиент не получал лишнего
+	if len(req.Methods) > 0 {
+		keep := func(method string) bool { return matchMethod(req.Methods, method) }
+		filterStats(stat.ByMethod, keep)
+		for method := range stat.LatencyByMethod {
+			if !keep(method) {
+				delete(stat.LatencyByMethod, method)
+			}
+		}
+	}
+	if len(req.Consumers) > 0 {
+		inList := func(consumer string) bool { return containsString(req.Consumers, consumer) }
+		filterStats(stat.ByConsumer, inList)
+		filterStats(stat.DroppedByConsumer, inList)
+	}
+	return stat
+}
+
+// Statistics - потоковая передача статистики раз в interval_seconds.
+// DELTA - изменения с прошлой отправки, CUMULATIVE - с запуска сервиса,
+// WINDOW - за последние window_seconds: для него снимки счётчиков делаются каждую секунду.
+func (s *Service) Statistics(req *StatInterval, stream Admin_StatisticsServer) error {
+	if req.IntervalSeconds == 0 {
+		return status.Error(codes.InvalidArgument, "interval_seconds must be positive")
+	}
+	if req.Mode == StatMode_WINDOW && req.WindowSeconds == 0 {
+		return status.Error(codes.InvalidArgument, "window_seconds must be positive for WINDOW mode")
+	}
+	if _, ok := StatMode_name[int32(req.Mode)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown mode %d", req.Mode)
+	}
+	if err := checkPatterns(req.Methods); err != nil {
+		return err
+	}
+
+	tick := time.Duration(req.IntervalSeconds) * time.Second
+	if req.Mode == StatMode_WINDOW {
+		tick = time.Second
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	// свой вызов Statistics уже посчитан интерсептором и в DELTA не попадёт
+	base := s.snapshot()
+	// для WINDOW: снимки раз в секунду, первый - сделан window_seconds секунд назад
+	window := []statSnapshot{base}
+	var ticks uint64
+
+	for {
+		select {
+		case <-ticker.C:
+			cur := s.snapshot()
+			var snap statSnapshot
+			switch req.Mode {
+			case StatMode_CUMULATIVE:
+				snap = cur
+			case StatMode_DELTA:
+				snap = cur.sub(base)
+				base = cur
+			case StatMode_WINDOW:
+				window = append(window, cur)
+				if uint64(len(window)) > req.WindowSeconds+1 {
+					window = window[1:]
+				}
+				ticks++
+				if ticks%req.IntervalSeconds != 0 {
+					continue
+				}
+				snap = cur.sub(window[0])
+			}
+			if err := stream.Send(snap.toStat(req)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stopChan:
+			return nil
+		}
+	}
+}
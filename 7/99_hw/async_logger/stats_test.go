@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	h := latencyHistogram{}
+	for i := 0; i < 90; i++ {
+		h.observe(50 * time.Microsecond) // первая корзина, до 0.1ms
+	}
+	for i := 0; i < 9; i++ {
+		h.observe(3 * time.Millisecond) // до 3.2ms
+	}
+	h.observe(time.Hour) // последняя корзина
+
+	if c := h.count(); c != 100 {
+		t.Fatalf("count: have %d, want 100", c)
+	}
+	cases := []struct {
+		p    float64
+		want float64
+	}{
+		{0.5, 0.1},
+		{0.9, 0.1},
+		{0.95, 3.2},
+		{1, 0.1 * float64(uint64(1)<<(latencyBuckets-1))},
+	}
+	for _, c := range cases {
+		if got := h.percentile(c.p); got != c.want {
+			t.Errorf("p%v: have %v, want %v", c.p*100, got, c.want)
+		}
+	}
+
+	base := h
+	h.observe(3 * time.Millisecond)
+	if diff := h.sub(base); diff.count() != 1 || diff.percentile(0.5) != 3.2 {
+		t.Fatalf("sub: have %v", diff)
+	}
+}
+
+func TestStatSnapshotSub(t *testing.T) {
+	base := statSnapshot{
+		byMethod:   map[string]uint64{"/main.Biz/Check": 2, "/main.Biz/Add": 1},
+		byConsumer: map[string]uint64{"biz_user": 3},
+		dropped:    map[string]uint64{},
+		latency:    map[string]latencyHistogram{"/main.Biz/Check": {2}},
+	}
+	cur := statSnapshot{
+		byMethod:   map[string]uint64{"/main.Biz/Check": 3, "/main.Biz/Add": 1},
+		byConsumer: map[string]uint64{"biz_user": 4},
+		dropped:    map[string]uint64{"logger1": 5},
+		latency:    map[string]latencyHistogram{"/main.Biz/Check": {3}},
+	}
+	diff := cur.sub(base)
+	if want := map[string]uint64{"/main.Biz/Check": 1}; !reflect.DeepEqual(diff.byMethod, want) {
+		t.Errorf("byMethod: have %v, want %v", diff.byMethod, want)
+	}
+	if want := map[string]uint64{"biz_user": 1}; !reflect.DeepEqual(diff.byConsumer, want) {
+		t.Errorf("byConsumer: have %v, want %v", diff.byConsumer, want)
+	}
+	if want := map[string]uint64{"logger1": 5}; !reflect.DeepEqual(diff.dropped, want) {
+		t.Errorf("dropped: have %v, want %v", diff.dropped, want)
+	}
+	if h := diff.latency["/main.Biz/Check"]; h.count() != 1 {
+		t.Errorf("latency: have %v, want one call", h)
+	}
+}
+
+// в окне за 2 секунды вызовы видны, пока не выйдут из окна
+func TestStatWindow(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	// без окна режим WINDOW не имеет смысла
+	bad, _ := adm.Statistics(getConsumerCtx("stat1"), &StatInterval{IntervalSeconds: 1, Mode: StatMode_WINDOW})
+	if _, err := bad.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("have %v, want InvalidArgument", err)
+	}
+
+	statStream, err := adm.Statistics(getConsumerCtx("stat1"), &StatInterval{
+		IntervalSeconds: 1,
+		Mode:            StatMode_WINDOW,
+		WindowSeconds:   2,
+		Methods:         []string{"/main.Biz/*"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+	biz.Check(getConsumerCtx("biz_admin"), &Nothing{})
+
+	expected := []map[string]uint64{
+		{"/main.Biz/Check": 2}, // 1s
+		{"/main.Biz/Check": 2}, // 2s
+		{},                     // 3s - вызовы старше окна
+	}
+	for i, want := range expected {
+		stat, err := statStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(stat.ByMethod, want) && !(len(want) == 0 && len(stat.ByMethod) == 0) {
+			t.Fatalf("[%d] have %v, want %v", i, stat.ByMethod, want)
+		}
+		if i == 0 {
+			latency := stat.LatencyByMethod["/main.Biz/Check"]
+			if latency.GetCount() != 2 || latency.GetP99Ms() <= 0 {
+				t.Fatalf("bad latency: %v", latency)
+			}
+		}
+	}
+}
+
+func TestStatCumulative(t *testing.T) {
+	ctx, finish := context.WithCancel(context.Background())
+	err := StartMyMicroservice(ctx, listenAddr, ACLData)
+	if err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	defer func() {
+		finish()
+		wait(1)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	biz := NewBizClient(conn)
+	adm := NewAdminClient(conn)
+
+	// вызов до подписки: в CUMULATIVE он есть, в DELTA его бы не было
+	biz.Check(getConsumerCtx("biz_user"), &Nothing{})
+
+	// накопительная статистика только по бизнес-методам и их клиентам
+	statStream, err := adm.Statistics(getConsumerCtx("stat2"), &StatInterval{
+		IntervalSeconds: 1,
+		Mode:            StatMode_CUMULATIVE,
+		Methods:         []string{"/main.Biz/*"},
+		Consumers:       []string{"biz_user", "biz_admin"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wait(1)
+	biz.Add(getConsumerCtx("biz_admin"), &Nothing{})
+
+	expected := []*Stat{
+		{
+			ByMethod:   map[string]uint64{"/main.Biz/Check": 1, "/main.Biz/Add": 1},
+			ByConsumer: map[string]uint64{"biz_user": 1, "biz_admin": 1},
+		},
+		{
+			ByMethod:   map[string]uint64{"/main.Biz/Check": 1, "/main.Biz/Add": 2},
+			ByConsumer: map[string]uint64{"biz_user": 1, "biz_admin": 2},
+		},
+	}
+	for i, want := range expected {
+		stat, err := statStream.Recv()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		have := &Stat{ByMethod: stat.ByMethod, ByConsumer: stat.ByConsumer}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("[%d] have %+v, want %+v", i, have, want)
+		}
+		if i == 0 {
+			biz.Add(getConsumerCtx("biz_admin"), &Nothing{})
+		}
+	}
+}
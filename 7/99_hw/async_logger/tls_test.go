@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testCA - одноразовый удостоверяющий центр для тестов
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue выпускает сертификат, tmpl задаёт имена и назначение
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.SerialNumber = big.NewInt(time.Now().UnixNano())
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (ca *testCA) clientCert(t *testing.T, tmpl *x509.Certificate) tls.Certificate {
+	tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	return ca.issue(t, tmpl)
+}
+
+func getTLSConn(t *testing.T, ca *testCA, certs ...tls.Certificate) *grpc.ClientConn {
+	creds := credentials.NewTLS(&tls.Config{RootCAs: ca.pool, Certificates: certs})
+	conn, err := grpc.Dial(listenAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatalf("cant connect to grpc: %v", err)
+	}
+	return conn
+}
+
+func startTLSService(t *testing.T, ca *testCA, opts ...Option) context.CancelFunc {
+	serverCert := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "logger"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	ctx, finish := context.WithCancel(context.Background())
+	opts = append([]Option{WithTLS(serverCert, ca.pool)}, opts...)
+	if err := StartMyMicroservice(ctx, listenAddr, ACLData, opts...); err != nil {
+		t.Fatalf("cant start server initial: %v", err)
+	}
+	wait(1)
+	return func() {
+		finish()
+		wait(1)
+	}
+}
+
+func TestCertConsumer(t *testing.T) {
+	ca := newTestCA(t)
+	cases := []struct {
+		tmpl *x509.Certificate
+		want string
+	}{
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "biz_user"}, DNSNames: []string{"biz_admin"}}, "biz_user"},
+		{&x509.Certificate{DNSNames: []string{"biz_admin"}}, "biz_admin"},
+		{&x509.Certificate{EmailAddresses: []string{"logger@example.com"}}, "logger@example.com"},
+		{&x509.Certificate{}, ""},
+	}
+	for i, c := range cases {
+		cert, err := x509.ParseCertificate(ca.clientCert(t, c.tmpl).Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := certConsumer(cert); got != c.want {
+			t.Errorf("[%d] have %q, want %q", i, got, c.want)
+		}
+	}
+}
+
+func TestTLSConsumer(t *testing.T) {
+	ca := newTestCA(t)
+	defer startTLSService(t, ca)()
+
+	// имя из субъекта; метаданные consumer при mTLS не действуют
+	userConn := getTLSConn(t, ca, ca.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "biz_user"}}))
+	defer userConn.Close()
+	user := NewBizClient(userConn)
+	if _, err := user.Check(context.Background(), &Nothing{}); err != nil {
+		t.Fatalf("check by cert: %v", err)
+	}
+	if _, err := user.Test(getConsumerCtx("biz_admin"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("test with faked header: have %v, want Unauthenticated", err)
+	}
+
+	// имя из SAN
+	adminConn := getTLSConn(t, ca, ca.clientCert(t, &x509.Certificate{DNSNames: []string{"biz_admin"}}))
+	defer adminConn.Close()
+	if _, err := NewBizClient(adminConn).Test(context.Background(), &Nothing{}); err != nil {
+		t.Fatalf("test by SAN cert: %v", err)
+	}
+
+	// без сертификата и с сертификатом чужого CA соединение не устанавливается
+	anonConn := getTLSConn(t, ca)
+	defer anonConn.Close()
+	if _, err := NewBizClient(anonConn).Check(getConsumerCtx("biz_user"), &Nothing{}); err == nil {
+		t.Fatalf("check without cert: expected error")
+	}
+	other := newTestCA(t)
+	otherConn := getTLSConn(t, ca, other.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "biz_admin"}}))
+	defer otherConn.Close()
+	if _, err := NewBizClient(otherConn).Check(context.Background(), &Nothing{}); err == nil {
+		t.Fatalf("check with foreign cert: expected error")
+	}
+}
+
+func TestTLSConsumerHeaderFallback(t *testing.T) {
+	ca := newTestCA(t)
+	defer startTLSService(t, ca, WithConsumerHeaderFallback())()
+
+	anonConn := getTLSConn(t, ca)
+	defer anonConn.Close()
+	anon := NewBizClient(anonConn)
+	if _, err := anon.Check(getConsumerCtx("biz_user"), &Nothing{}); err != nil {
+		t.Fatalf("check by header: %v", err)
+	}
+	if _, err := anon.Check(context.Background(), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("check without consumer: have %v, want Unauthenticated", err)
+	}
+
+	// сертификат, если он есть, важнее метаданных
+	userConn := getTLSConn(t, ca, ca.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "biz_user"}}))
+	defer userConn.Close()
+	if _, err := NewBizClient(userConn).Test(getConsumerCtx("biz_admin"), &Nothing{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("test with faked header: have %v, want Unauthenticated", err)
+	}
+}
+
+func TestTLSLogging(t *testing.T) {
+	ca := newTestCA(t)
+	defer startTLSService(t, ca)()
+
+	loggerConn := getTLSConn(t, ca, ca.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "logger1"}}))
+	defer loggerConn.Close()
+	adm := NewAdminClient(loggerConn)
+
+	// подписчик - это logger1 из сертификата, метаданных consumer нет вовсе
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	plain, err := adm.Logging(ctx, &Nothing{})
+	if err != nil {
+		t.Fatalf("logging by cert: %v", err)
+	}
+	wait(1)
+	// подставленное имя не меняет, чьи события считаются своими
+	spoofed, err := adm.Logging(metadata.NewOutgoingContext(ctx, metadata.Pairs("consumer", "logger2")), &Nothing{})
+	if err != nil {
+		t.Fatalf("logging with faked header: %v", err)
+	}
+	wait(1)
+	if _, err := adm.LoggingFiltered(ctx, &LogFilter{}); err != nil {
+		t.Fatalf("filtered logging by cert: %v", err)
+	}
+	wait(1)
+
+	userConn := getTLSConn(t, ca, ca.clientCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "biz_user"}}))
+	defer userConn.Close()
+	if _, err := NewBizClient(userConn).Check(context.Background(), &Nothing{}); err != nil {
+		t.Fatalf("check by cert: %v", err)
+	}
+
+	// свои же подписки logger1 не видит ни в одном потоке, первым приходит Check
+	for name, stream := range map[string]Admin_LoggingClient{"plain": plain, "spoofed": spoofed} {
+		evt, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if evt.Consumer != "biz_user" || evt.Method != "/main.Biz/Check" {
+			t.Errorf("%s: have %s %s, want biz_user /main.Biz/Check", name, evt.Consumer, evt.Method)
+		}
+	}
+}
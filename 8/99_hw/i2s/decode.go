@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// decodeFunc заполняет val из data. Путь до поля в ошибку дописывают вызывающие,
+// поэтому при успешном разборе строки пути не собираются вовсе.
+type decodeFunc func(data interface{}, val reflect.Value, opts *decodeOptions) error
+
+// decoders - готовые планы разбора по типам: reflect.Type -> decodeFunc.
+// План строится один раз на тип, дальше поля и обработчики берутся отсюда.
+var decoders sync.Map
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func decoderFor(t reflect.Type) decodeFunc {
+	if dec, ok := decoders.Load(t); ok {
+		return dec.(decodeFunc)
+	}
+
+	// рекурсивные типы: пока план строится, в кэше лежит обёртка, которая дождётся готового плана
+	var (
+		wg  sync.WaitGroup
+		dec decodeFunc
+	)
+	wg.Add(1)
+	stub, loaded := decoders.LoadOrStore(t, decodeFunc(func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		wg.Wait()
+		return dec(data, val, opts)
+	}))
+	if loaded {
+		return stub.(decodeFunc)
+	}
+	dec = newDecoder(t)
+	wg.Done()
+	decoders.Store(t, dec)
+	return dec
+}
+
+// withPath дописывает к пути в ошибке вложенного значения сегмент: имя поля или [индекс]
+func withPath(err error, segment string) error {
+	derr, ok := err.(*pathError)
+	if !ok {
+		return &pathError{Path: segment, Err: err}
+	}
+	switch {
+	case derr.Path == "":
+		derr.Path = segment
+	case strings.HasPrefix(derr.Path, "["):
+		derr.Path = segment + derr.Path
+	default:
+		derr.Path = segment + "." + derr.Path
+	}
+	return derr
+}
+
+func newDecoder(t reflect.Type) decodeFunc {
+	var dec decodeFunc
+	switch {
+	case t == bigIntType:
+		dec = bigIntDecoder
+	case t == bigFloatType:
+		dec = bigFloatDecoder
+	case t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textUnmarshalerType):
+		dec = textDecoder(t)
+	default:
+		dec = newKindDecoder(t)
+	}
+
+	// null из json: ссылочные типы обнуляем, остальное оставляем как было
+	zero := reflect.Zero(t)
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+			if data == nil {
+				val.Set(zero)
+				return nil
+			}
+			return dec(data, val, opts)
+		}
+	}
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		if data == nil {
+			return nil
+		}
+		return dec(data, val, opts)
+	}
+}
+
+func newKindDecoder(t reflect.Type) decodeFunc {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return ptrDecoder(t)
+	case reflect.Struct:
+		return structDecoder(t)
+	case reflect.Map:
+		return mapDecoder(t)
+	case reflect.Slice:
+		return sliceDecoder(t)
+	case reflect.Array:
+		return arrayDecoder(t)
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return unsupportedDecoder("unsupported interface type %s", t)
+		}
+		return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+			val.Set(reflect.ValueOf(data))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intDecoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintDecoder
+	case reflect.Float32, reflect.Float64:
+		return floatDecoder
+	case reflect.String:
+		return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+			strVal, ok := data.(string)
+			if !ok {
+				return errorAt("", "expected string, got %T", data)
+			}
+			val.SetString(strVal)
+			return nil
+		}
+	case reflect.Bool:
+		return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+			boolVal, ok := data.(bool)
+			if !ok {
+				return errorAt("", "expected bool, got %T", data)
+			}
+			val.SetBool(boolVal)
+			return nil
+		}
+	}
+	return unsupportedDecoder("unsupported kind: %s", t.Kind())
+}
+
+func unsupportedDecoder(format string, arg interface{}) decodeFunc {
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		return errorAt("", format, arg)
+	}
+}
+
+func textDecoder(t reflect.Type) decodeFunc {
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		text, ok := data.(string)
+		if !ok {
+			return errorAt("", "expected string for %s, got %T", t, data)
+		}
+		if err := val.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return &pathError{Err: err}
+		}
+		return nil
+	}
+}
+
+func ptrDecoder(t reflect.Type) decodeFunc {
+	elemDec := decoderFor(t.Elem())
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		// указатель выделяем, только когда есть что в него положить
+		if val.IsNil() {
+			val.Set(reflect.New(t.Elem()))
+		}
+		return elemDec(data, val.Elem(), opts)
+	}
+}
+
+// fieldPlan - как заполнять одно поле структуры
+type fieldPlan struct {
+	field
+	dec decodeFunc
+}
+
+func structDecoder(t reflect.Type) decodeFunc {
+	fields := structFields(t)
+	plan := make([]fieldPlan, len(fields))
+	known := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		fieldType := t.FieldByIndex(f.index).Type
+		plan[i] = fieldPlan{field: f, dec: decoderFor(fieldType)}
+		if f.quoted && isNumberType(fieldType) {
+			plan[i].dec = quotedDecoder(plan[i].dec)
+		}
+		known[f.name] = true
+	}
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		// data должен быть map[string]interface{}
+		mapData, ok := data.(map[string]interface{})
+		if !ok {
+			return errorAt("", "expected map for struct, got %T", data)
+		}
+		if opts.strict {
+			if err := unknownKey(mapData, known); err != nil {
+				return err
+			}
+		}
+		for i := range plan {
+			f := &plan[i]
+			rawVal, ok := mapData[f.name]
+			if !ok {
+				if opts.strict && f.required {
+					return errorAt(f.name, "missing required field")
+				}
+				continue
+			}
+			var fieldVal reflect.Value
+			if len(f.index) == 1 {
+				fieldVal = val.Field(f.index[0])
+			} else if fieldVal, ok = fieldByIndex(val, f.index); !ok {
+				continue
+			}
+			if err := f.dec(rawVal, fieldVal, opts); err != nil {
+				return withPath(err, f.name)
+			}
+		}
+		return nil
+	}
+}
+
+// unknownKey - ошибка для первого по алфавиту ключа, которому нет поля в структуре
+func unknownKey(mapData map[string]interface{}, known map[string]bool) error {
+	var unknown []string
+	for key := range mapData {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return errorAt(unknown[0], "unknown field")
+}
+
+func mapDecoder(t reflect.Type) decodeFunc {
+	keyType := t.Key()
+	if keyType.Kind() != reflect.String {
+		return unsupportedDecoder("unsupported map key type %s", keyType)
+	}
+	elemType := t.Elem()
+	elemDec := decoderFor(elemType)
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		mapData, ok := data.(map[string]interface{})
+		if !ok {
+			return errorAt("", "expected map, got %T", data)
+		}
+		if val.IsNil() {
+			val.Set(reflect.MakeMapWithSize(t, len(mapData)))
+		}
+		elem := reflect.New(elemType).Elem()
+		for key, rawVal := range mapData {
+			elem.Set(reflect.Zero(elemType))
+			if err := elemDec(rawVal, elem, opts); err != nil {
+				return withPath(err, "["+key+"]")
+			}
+			val.SetMapIndex(reflect.ValueOf(key).Convert(keyType), elem)
+		}
+		return nil
+	}
+}
+
+func sliceDecoder(t reflect.Type) decodeFunc {
+	elemDec := decoderFor(t.Elem())
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		// data должен быть []interface{}
+		sliceData, ok := data.([]interface{})
+		if !ok {
+			return errorAt("", "expected slice, got %T", data)
+		}
+		sliceVal := reflect.MakeSlice(t, len(sliceData), len(sliceData))
+		for i := range sliceData {
+			if err := elemDec(sliceData[i], sliceVal.Index(i), opts); err != nil {
+				return withPath(err, indexPath("", i))
+			}
+		}
+		val.Set(sliceVal)
+		return nil
+	}
+}
+
+func arrayDecoder(t reflect.Type) decodeFunc {
+	elemDec := decoderFor(t.Elem())
+	zero := reflect.Zero(t.Elem())
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		sliceData, ok := data.([]interface{})
+		if !ok {
+			return errorAt("", "expected slice, got %T", data)
+		}
+		if len(sliceData) > t.Len() {
+			return errorAt("", "expected at most %d elements, got %d", t.Len(), len(sliceData))
+		}
+		for i := 0; i < t.Len(); i++ {
+			if i >= len(sliceData) {
+				// как и encoding/json, лишние элементы массива обнуляем
+				val.Index(i).Set(zero)
+				continue
+			}
+			if err := elemDec(sliceData[i], val.Index(i), opts); err != nil {
+				return withPath(err, indexPath("", i))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// fillValue - прежняя реализация i2s: обходит тип через reflect на каждом вызове.
+// Оставлена для сравнения с планами из decoderFor.
+func fillValue(data interface{}, val reflect.Value, path string) error {
+	// Проверка типа значения
+	if !val.CanSet() {
+		return errorAt(path, "value is not settable")
+	}
+
+	// null из json: ссылочные типы обнуляем, остальное оставляем как было
+	if data == nil {
+		switch val.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			val.Set(reflect.Zero(val.Type()))
+		}
+		return nil
+	}
+
+	if val.Kind() == reflect.Ptr {
+		// указатель выделяем, только когда есть что в него положить
+		if val.IsNil() {
+			val.Set(reflect.New(val.Type().Elem()))
+		}
+		return fillValue(data, val.Elem(), path)
+	}
+
+	if u, ok := val.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		text, ok := data.(string)
+		if !ok {
+			return errorAt(path, "expected string for %s, got %T", val.Type(), data)
+		}
+		if err := u.UnmarshalText([]byte(text)); err != nil {
+			return &pathError{Path: path, Err: err}
+		}
+		return nil
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		// data должен быть map[string]interface{}
+		mapData, ok := data.(map[string]interface{})
+		if !ok {
+			return errorAt(path, "expected map for struct, got %T", data)
+		}
+		for _, field := range structFields(val.Type()) {
+			rawVal, ok := mapData[field.name]
+			if !ok {
+				continue
+			}
+			fieldVal, ok := fieldByIndex(val, field.index)
+			if !ok {
+				continue
+			}
+			if err := fillValue(rawVal, fieldVal, fieldPath(path, field.name)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return errorAt(path, "unsupported map key type %s", val.Type().Key())
+		}
+		mapData, ok := data.(map[string]interface{})
+		if !ok {
+			return errorAt(path, "expected map, got %T", data)
+		}
+		if val.IsNil() {
+			val.Set(reflect.MakeMapWithSize(val.Type(), len(mapData)))
+		}
+		elemType := val.Type().Elem()
+		for key, rawVal := range mapData {
+			elem := reflect.New(elemType).Elem()
+			if err := fillValue(rawVal, elem, indexPath(path, key)); err != nil {
+				return err
+			}
+			val.SetMapIndex(reflect.ValueOf(key).Convert(val.Type().Key()), elem)
+		}
+	case reflect.Slice:
+		// data должен быть []interface{}
+		sliceData, ok := data.([]interface{})
+		if !ok {
+			return errorAt(path, "expected slice, got %T", data)
+		}
+		sliceVal := reflect.MakeSlice(val.Type(), len(sliceData), len(sliceData))
+		for i := 0; i < len(sliceData); i++ {
+			if err := fillValue(sliceData[i], sliceVal.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+		val.Set(sliceVal)
+	case reflect.Array:
+		sliceData, ok := data.([]interface{})
+		if !ok {
+			return errorAt(path, "expected slice, got %T", data)
+		}
+		if len(sliceData) > val.Len() {
+			return errorAt(path, "expected at most %d elements, got %d", val.Len(), len(sliceData))
+		}
+		for i := 0; i < val.Len(); i++ {
+			if i >= len(sliceData) {
+				// как и encoding/json, лишние элементы массива обнуляем
+				val.Index(i).Set(reflect.Zero(val.Type().Elem()))
+				continue
+			}
+			if err := fillValue(sliceData[i], val.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		if val.NumMethod() != 0 {
+			return errorAt(path, "unsupported interface type %s", val.Type())
+		}
+		val.Set(reflect.ValueOf(data))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, ok := toFloat(data)
+		if !ok {
+			return errorAt(path, "expected number for %s, got %T", val.Kind(), data)
+		}
+		val.SetInt(int64(num))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		num, ok := toFloat(data)
+		if !ok {
+			return errorAt(path, "expected number for %s, got %T", val.Kind(), data)
+		}
+		val.SetUint(uint64(num))
+	case reflect.Float32, reflect.Float64:
+		num, ok := toFloat(data)
+		if !ok {
+			return errorAt(path, "expected number for %s, got %T", val.Kind(), data)
+		}
+		val.SetFloat(num)
+	case reflect.String:
+		strVal, ok := data.(string)
+		if !ok {
+			return errorAt(path, "expected string, got %T", data)
+		}
+		val.SetString(strVal)
+	case reflect.Bool:
+		boolVal, ok := data.(bool)
+		if !ok {
+			return errorAt(path, "expected bool, got %T", data)
+		}
+		val.SetBool(boolVal)
+	default:
+		return errorAt(path, "unsupported kind: %s", val.Kind())
+	}
+	return nil
+}
+
+// toFloat - число из json (float64) или из собранной руками мапы (любой числовой тип)
+func toFloat(data interface{}) (float64, bool) {
+	v := reflect.ValueOf(data)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	}
+	return 0, false
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func benchData(b *testing.B) (interface{}, []byte) {
+	smpl := Simple{ID: 42, Username: "rvasily", Active: true}
+	team := &Complex{
+		SubSimple:  smpl,
+		ManySimple: []Simple{smpl, smpl, smpl, smpl, smpl},
+		Blocks:     []IDBlock{{1}, {2}, {3}},
+	}
+	raw, err := json.Marshal(team)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		b.Fatal(err)
+	}
+	return data, raw
+}
+
+func BenchmarkI2S(b *testing.B) {
+	data, _ := benchData(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := i2s(data, &Complex{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFillValue(b *testing.B) {
+	data, _ := benchData(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := fillValue(data, reflect.ValueOf(&Complex{}).Elem(), ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// то, что обычно делают без i2s: собрать json обратно и распаковать его в структуру
+func BenchmarkJSONRoundTrip(b *testing.B) {
+	data, _ := benchData(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := json.Unmarshal(raw, &Complex{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// планы из кэша и прежняя реализация должны давать одно и то же
+func TestDecoderMatchesFillValue(t *testing.T) {
+	data := decodeJSON(t, `{
+		"id": 1, "created": "2020-01-02T03:04:05Z", "Author": "admin",
+		"I8": -8, "U64": 64, "F32": 1.5, "Name": "rvasily",
+		"address": {"city": "Moscow", "zip": 101000},
+		"tags": {"a": "b"}, "Scores": {"x": [1, 2]}, "Pair": [3],
+		"Any": {"nested": [1, "two"]}, "IP": "10.0.0.1", "is_renamed": true
+	}`)
+	planned, walked := &Kinds{}, &Kinds{}
+	if err := i2s(data, planned); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := fillValue(data, reflect.ValueOf(walked).Elem(), ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(planned, walked) {
+		t.Errorf("results not match\nplanned:\n%#v\nwalked:\n%#v", planned, walked)
+	}
+}
+
+type Node struct {
+	Value    int
+	Children []*Node
+	Next     *Node
+}
+
+func TestRecursiveType(t *testing.T) {
+	result := &Node{}
+	err := i2s(decodeJSON(t, `{"Value": 1, "Children": [{"Value": 2}], "Next": {"Value": 3, "Next": {"Value": "x"}}}`), result)
+	if derr, ok := err.(*pathError); !ok || derr.Path != "Next.Next.Value" {
+		t.Fatalf("have %v, want error at Next.Next.Value", err)
+	}
+	expected := &Node{Value: 1, Children: []*Node{{Value: 2}}, Next: &Node{Value: 3, Next: &Node{}}}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+}
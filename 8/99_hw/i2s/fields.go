@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// field - поле структуры так, как оно выглядит в мапе: имя из тега json или имя поля,
+// index - путь до поля через встроенные структуры для reflect.Value.FieldByIndex
+type field struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool // json:",string" - число может быть записано строкой
+	required  bool // i2s:"required", проверяется в режиме Strict
+}
+
+// parseTag разбирает тег json:"name,omitempty,string" на имя и опции; skip - поле помечено "-"
+func parseTag(tag string) (name, opts string, skip bool) {
+	if tag == "-" {
+		return "", "", true
+	}
+	parts := strings.SplitN(tag, ",", 2)
+	if len(parts) == 2 {
+		opts = parts[1]
+	}
+	return parts[0], opts, false
+}
+
+// hasOption - есть ли opt среди перечисленных через запятую в теге
+func hasOption(tag, opt string) bool {
+	for _, o := range strings.Split(tag, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// structFields - поля структуры вместе с полями встроенных структур.
+// Правила как в encoding/json: из полей с одним именем побеждает менее вложенное,
+// на одной глубине - единственное с тегом, а если так не выбрать - имя не используется вовсе.
+func structFields(t reflect.Type) []field {
+	type level struct {
+		typ   reflect.Type
+		index []int
+	}
+	var all []field
+	visited := map[reflect.Type]bool{}
+	current := []level{{typ: t}}
+	for len(current) > 0 {
+		var next []level
+		for _, lv := range current {
+			if visited[lv.typ] {
+				continue
+			}
+			visited[lv.typ] = true
+			for i := 0; i < lv.typ.NumField(); i++ {
+				sf := lv.typ.Field(i)
+				index := append(append([]int{}, lv.index...), i)
+				name, opts, skip := parseTag(sf.Tag.Get("json"))
+				if skip {
+					continue
+				}
+
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					// через неэкспортируемый указатель структуру не выделить
+					if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+						continue
+					}
+					next = append(next, level{typ: ft, index: index})
+					continue
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+
+				f := field{
+					name:      name,
+					index:     index,
+					tagged:    name != "",
+					omitEmpty: hasOption(opts, "omitempty"),
+					quoted:    hasOption(opts, "string"),
+					required:  hasOption(sf.Tag.Get("i2s"), "required"),
+				}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				all = append(all, f)
+			}
+		}
+		current = next
+	}
+
+	// оставляем по одному полю на имя
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].name != all[j].name {
+			return all[i].name < all[j].name
+		}
+		if len(all[i].index) != len(all[j].index) {
+			return len(all[i].index) < len(all[j].index)
+		}
+		return all[i].tagged && !all[j].tagged
+	})
+	fields := all[:0]
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		dominant := all[i]
+		if j-i > 1 {
+			second := all[i+1]
+			if len(second.index) == len(dominant.index) && second.tagged == dominant.tagged {
+				i = j
+				continue
+			}
+		}
+		fields = append(fields, dominant)
+		i = j
+	}
+
+	// порядок полей - как в объявлении структуры
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// fieldByIndex - как reflect.Value.FieldByIndex, но nil-указатели на встроенные структуры выделяет
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
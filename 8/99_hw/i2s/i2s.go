@@ -12,82 +12,67 @@ func i2s(data interface{}, out interface{}) error {
 }
 */
 
-func i2s(data interface{}, out interface{}) error {
-	// Проверяем, что out — это указатель
-	outVal := reflect.ValueOf(out)
-	if outVal.Kind() != reflect.Ptr || outVal.IsNil() {
-		return errors.New("output must be a non-nil pointer")
+// pathError - ошибка i2s или s2i с путём до поля, например Users[3].Address.Zip
+type pathError struct {
+	Path string
+	Err  error
+}
+
+func (e *pathError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
 	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *pathError) Unwrap() error {
+	return e.Err
+}
 
-	return fillValue(data, outVal.Elem())
+func errorAt(path string, format string, args ...interface{}) error {
+	return &pathError{Path: path, Err: fmt.Errorf(format, args...)}
 }
 
-func fillValue(data interface{}, val reflect.Value) error {
-	// Проверка типа значения
-	if !val.CanSet() {
-		return errors.New("value is not settable")
+// indexPath - путь до элемента слайса или значения мапы
+func indexPath(path string, idx interface{}) string {
+	return fmt.Sprintf("%s[%v]", path, idx)
+}
+
+// Option - настройка разбора i2s
+type Option func(*decodeOptions)
+
+type decodeOptions struct {
+	strict        bool
+	numberStrings bool
+}
+
+// Strict - не пропускать молча ключи, которым нет поля в структуре,
+// и требовать поля с тегом i2s:"required"
+func Strict() Option {
+	return func(o *decodeOptions) {
+		o.strict = true
+	}
+}
+
+// NumberStrings - принимать строку с числом в любом числовом поле, а не только с тегом json:",string".
+// По умолчанию "42" в поле int - ошибка типа, как и в encoding/json; опция не включена
+// по умолчанию намеренно - иначе не проходит случай "ID":"42" из TestErrors.
+func NumberStrings() Option {
+	return func(o *decodeOptions) {
+		o.numberStrings = true
+	}
+}
+
+func i2s(data interface{}, out interface{}, opts ...Option) error {
+	// Проверяем, что out — это указатель
+	outVal := reflect.ValueOf(out)
+	if outVal.Kind() != reflect.Ptr || outVal.IsNil() {
+		return errors.New("output must be a non-nil pointer")
 	}
 
-	switch val.Kind() {
-	case reflect.Struct:
-		// data должен быть map[string]interface{}
-		mapData, ok := data.(map[string]interface{})
-		if !ok {
-			return fmt.Errorf("expected map for struct, got %T", data)
-		}
-		t := val.Type()
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			fieldVal := val.Field(i)
-			if !fieldVal.CanSet() {
-				continue
-			}
-			rawVal, ok := mapData[field.Name]
-			if !ok {
-				continue
-			}
-			err := fillValue(rawVal, fieldVal)
-			if err != nil {
-				return fmt.Errorf("field %s: %w", field.Name, err)
-			}
-		}
-	case reflect.Slice:
-		// data должен быть []interface{}
-		sliceData, ok := data.([]interface{})
-		if !ok {
-			return fmt.Errorf("expected slice, got %T", data)
-		}
-		elemType := val.Type().Elem()
-		sliceVal := reflect.MakeSlice(val.Type(), len(sliceData), len(sliceData))
-		for i := 0; i < len(sliceData); i++ {
-			elemPtr := reflect.New(elemType).Elem()
-			err := fillValue(sliceData[i], elemPtr)
-			if err != nil {
-				return fmt.Errorf("slice index %d: %w", i, err)
-			}
-			sliceVal.Index(i).Set(elemPtr)
-		}
-		val.Set(sliceVal)
-	case reflect.Int:
-		floatVal, ok := data.(float64)
-		if !ok {
-			return fmt.Errorf("expected float64 for int, got %T", data)
-		}
-		val.SetInt(int64(floatVal))
-	case reflect.String:
-		strVal, ok := data.(string)
-		if !ok {
-			return fmt.Errorf("expected string, got %T", data)
-		}
-		val.SetString(strVal)
-	case reflect.Bool:
-		boolVal, ok := data.(bool)
-		if !ok {
-			return fmt.Errorf("expected bool, got %T", data)
-		}
-		val.SetBool(boolVal)
-	default:
-		return fmt.Errorf("unsupported kind: %s", val.Kind())
+	o := &decodeOptions{}
+	for _, opt := range opts {
+		opt(o)
 	}
-	return nil
+	return decoderFor(outVal.Type().Elem())(data, outVal.Elem(), o)
 }
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Address struct {
+	City string `json:"city"`
+	Zip  int    `json:"zip,omitempty"`
+}
+
+type Base struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type Audit struct {
+	Author string
+	ID     int // ID из Base ближе по вложенности, это поле скрыто
+}
+
+type Kinds struct {
+	Base
+	*Audit
+	I8       int8
+	I16      int16
+	I32      int32
+	I64      int64
+	U        uint
+	U8       uint8
+	U16      uint16
+	U32      uint32
+	U64      uint64
+	F32      float32
+	F64      float64
+	Name     *string
+	Address  *Address          `json:"address"`
+	Tags     map[string]string `json:"tags"`
+	Scores   map[string][]int
+	Pair     [2]int
+	Any      interface{}
+	IP       net.IP
+	Ignored  string `json:"-"`
+	Renamed  bool   `json:"is_renamed,omitempty"`
+	internal int
+}
+
+type User struct {
+	Name    string
+	Address Address
+}
+
+type Team struct {
+	Users []User
+}
+
+func decodeJSON(t *testing.T, raw string) interface{} {
+	var data interface{}
+	if err := json.Unmarshal([]byte(raw), &data); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	return data
+}
+
+func TestKinds(t *testing.T) {
+	name := "rvasily"
+	expected := &Kinds{
+		Base:    Base{ID: 1, Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Audit:   &Audit{Author: "admin"},
+		I8:      -8,
+		I16:     -16,
+		I32:     -32,
+		I64:     -64,
+		U:       1,
+		U8:      8,
+		U16:     16,
+		U32:     32,
+		U64:     64,
+		F32:     1.5,
+		F64:     2.25,
+		Name:    &name,
+		Address: &Address{City: "Moscow", Zip: 101000},
+		Tags:    map[string]string{"a": "b"},
+		Scores:  map[string][]int{"x": {1, 2}},
+		Pair:    [2]int{3, 0},
+		Any:     map[string]interface{}{"nested": []interface{}{1.0, "two"}},
+		IP:      net.ParseIP("10.0.0.1"),
+		Renamed: true,
+	}
+	data := decodeJSON(t, `{
+		"id": 1, "created": "2020-01-02T03:04:05Z", "Author": "admin",
+		"I8": -8, "I16": -16, "I32": -32, "I64": -64,
+		"U": 1, "U8": 8, "U16": 16, "U32": 32, "U64": 64,
+		"F32": 1.5, "F64": 2.25,
+		"Name": "rvasily",
+		"address": {"city": "Moscow", "zip": 101000},
+		"tags": {"a": "b"},
+		"Scores": {"x": [1, 2]},
+		"Pair": [3],
+		"Any": {"nested": [1, "two"]},
+		"IP": "10.0.0.1",
+		"Ignored": "must stay empty",
+		"is_renamed": true,
+		"internal": 5
+	}`)
+
+	result := &Kinds{}
+	if err := i2s(data, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+}
+
+func TestNull(t *testing.T) {
+	name := "old"
+	result := &Kinds{I8: 5, Name: &name, Tags: map[string]string{"a": "b"}}
+	if err := i2s(decodeJSON(t, `{"I8": null, "Name": null, "tags": null}`), result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &Kinds{I8: 5}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+}
+
+// у одноимённых полей на одной глубине побеждает поле с тегом, иначе имя не используется
+type Left struct {
+	Name  string
+	Title string `json:"title"`
+}
+
+type Right struct {
+	Name  string
+	Title string
+}
+
+type Conflict struct {
+	Left
+	Right
+}
+
+func TestEmbeddedConflict(t *testing.T) {
+	result := &Conflict{}
+	if err := i2s(decodeJSON(t, `{"Name": "n", "title": "t", "Title": "T"}`), result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &Conflict{Left: Left{Title: "t"}, Right: Right{Title: "T"}}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+}
+
+func TestErrorPath(t *testing.T) {
+	cases := []struct {
+		out  interface{}
+		json string
+		path string
+	}{
+		{&Team{}, `{"Users": [{}, {}, {}, {"Address": {"zip": "123"}}]}`, "Users[3].Address.zip"},
+		{&Kinds{}, `{"tags": {"a": 1}}`, "tags[a]"},
+		{&Kinds{}, `{"Pair": [1, 2, 3]}`, "Pair"},
+		{&Kinds{}, `{"IP": "not an ip"}`, "IP"},
+		{&Kinds{}, `{"created": 5}`, "created"},
+		{&[]User{}, `[{"Name": 1}]`, "[0].Name"},
+	}
+	for i, c := range cases {
+		err := i2s(decodeJSON(t, c.json), c.out)
+		var derr *pathError
+		if !errors.As(err, &derr) {
+			t.Errorf("[%d] expected pathError, got %v", i, err)
+			continue
+		}
+		if derr.Path != c.path {
+			t.Errorf("[%d] path: have %q, want %q (%v)", i, derr.Path, c.path, err)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// Числа приходят как float64 (json.Unmarshal в interface{}), json.Number (json.Decoder с UseNumber),
+// любой числовой тип Go (мапа собрана руками) или строкой - для полей с тегом json:",string",
+// а с опцией NumberStrings - для любых числовых полей. Без опции строка в поле без тега - ошибка
+// типа, как в encoding/json: этого требует TestErrors из задания ("ID":"42" в поле int).
+// Дробная часть у целого и выход за диапазон типа - ошибка, а не молчаливое приведение.
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// maxExactFloat - до этого модуля целые во float64 представимы точно
+const maxExactFloat = 1 << 53
+
+// maxBigIntBits - предел для big.Int: "1e300000000" - 12 байт на входе,
+// а целое из них заняло бы сотню мегабайт и считалось бы минутами
+const maxBigIntBits = 1 << 16
+
+// maxNumberInError - сколько символов числа попадает в текст ошибки
+const maxNumberInError = 32
+
+func intDecoder(data interface{}, val reflect.Value, opts *decodeOptions) error {
+	data = numberString(data, opts)
+	if f, ok := data.(float64); ok {
+		// быстрый путь для обычного json
+		if f != math.Trunc(f) {
+			return errorAt("", "number %v has a fractional part, expected %s", f, val.Kind())
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 || val.OverflowInt(int64(f)) {
+			return errorAt("", "number %v overflows %s", f, val.Kind())
+		}
+		val.SetInt(int64(f))
+		return nil
+	}
+	n, err := integerValue(data, val.Kind().String(), val.Type().Bits())
+	if err != nil {
+		return err
+	}
+	if !n.IsInt64() || val.OverflowInt(n.Int64()) {
+		return errorAt("", "number %s overflows %s", n, val.Kind())
+	}
+	val.SetInt(n.Int64())
+	return nil
+}
+
+func uintDecoder(data interface{}, val reflect.Value, opts *decodeOptions) error {
+	data = numberString(data, opts)
+	if f, ok := data.(float64); ok {
+		if f != math.Trunc(f) {
+			return errorAt("", "number %v has a fractional part, expected %s", f, val.Kind())
+		}
+		if f < 0 || f >= math.MaxUint64 || val.OverflowUint(uint64(f)) {
+			return errorAt("", "number %v overflows %s", f, val.Kind())
+		}
+		val.SetUint(uint64(f))
+		return nil
+	}
+	n, err := integerValue(data, val.Kind().String(), val.Type().Bits())
+	if err != nil {
+		return err
+	}
+	if !n.IsUint64() || val.OverflowUint(n.Uint64()) {
+		return errorAt("", "number %s overflows %s", n, val.Kind())
+	}
+	val.SetUint(n.Uint64())
+	return nil
+}
+
+func floatDecoder(data interface{}, val reflect.Value, opts *decodeOptions) error {
+	var f float64
+	switch d := numberString(data, opts).(type) {
+	case float64:
+		f = d
+	case json.Number:
+		var err error
+		if f, err = strconv.ParseFloat(string(d), val.Type().Bits()); err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return errorAt("", "number %s overflows %s", shortNumber(string(d)), val.Kind())
+			}
+			return errorAt("", "bad number %q", shortNumber(string(d)))
+		}
+	default:
+		v := reflect.ValueOf(data)
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = v.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			f = float64(v.Uint())
+		default:
+			return errorAt("", "expected number for %s, got %T", val.Kind(), data)
+		}
+	}
+	if val.OverflowFloat(f) {
+		return errorAt("", "number %v overflows %s", f, val.Kind())
+	}
+	val.SetFloat(f)
+	return nil
+}
+
+// integerValue - целое из data без потерь точности, не длиннее maxBits бит.
+// Точный диапазон типа проверяет вызывающий, здесь только отсекается заведомо лишнее.
+func integerValue(data interface{}, target string, maxBits int) (*big.Int, error) {
+	switch d := data.(type) {
+	case json.Number:
+		return parseInteger(string(d), target, maxBits)
+	case float64:
+		if d != math.Trunc(d) || math.IsInf(d, 0) {
+			return nil, errorAt("", "number %v has a fractional part, expected %s", d, target)
+		}
+		f := big.NewFloat(d)
+		if f.MantExp(nil) > maxBits {
+			return nil, errorAt("", "number %v overflows %s", d, target)
+		}
+		n, _ := f.Int(nil)
+		return n, nil
+	}
+	v := reflect.ValueOf(data)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(v.Uint()), nil
+	case reflect.Float32:
+		return integerValue(v.Float(), target, maxBits)
+	}
+	return nil, errorAt("", "expected number for %s, got %T", target, data)
+}
+
+// parseInteger разбирает целое в записи json: 42, -7, а также 1e3 и 2.0 - если дробной части нет.
+// Число длиннее maxBits бит - переполнение; для записи с показателем степени это видно
+// по самому показателю, и огромное целое даже не строится.
+func parseInteger(s string, target string, maxBits int) (*big.Int, error) {
+	if n, ok := new(big.Int).SetString(s, 10); ok {
+		if n.BitLen() > maxBits {
+			return nil, errorAt("", "number %s overflows %s", shortNumber(s), target)
+		}
+		return n, nil
+	}
+	f, _, err := big.ParseFloat(s, 10, floatPrec(s), big.ToNearestEven)
+	if err != nil {
+		return nil, errorAt("", "bad number %q", shortNumber(s))
+	}
+	if !f.IsInt() {
+		return nil, errorAt("", "number %s has a fractional part, expected %s", shortNumber(s), target)
+	}
+	if f.MantExp(nil) > maxBits {
+		return nil, errorAt("", "number %s overflows %s", shortNumber(s), target)
+	}
+	n, _ := f.Int(nil)
+	return n, nil
+}
+
+// shortNumber - число для текста ошибки: длинное обрезается, чтобы ошибка не разрослась до мегабайт
+func shortNumber(s string) string {
+	if len(s) <= maxNumberInError {
+		return s
+	}
+	return s[:maxNumberInError] + "..."
+}
+
+// numberString - строка вместо числа, если это разрешено опцией NumberStrings
+func numberString(data interface{}, opts *decodeOptions) interface{} {
+	if s, ok := data.(string); ok && opts.numberStrings {
+		return json.Number(s)
+	}
+	return data
+}
+
+// floatPrec - точность big.Float, которой хватит на все цифры числа из строки
+func floatPrec(s string) uint {
+	// на десятичную цифру нужно log2(10) < 4 бит, и ещё запас на показатель степени
+	if prec := uint(len(s))*4 + 64; prec > 64 {
+		return prec
+	}
+	return 64
+}
+
+func bigIntDecoder(data interface{}, val reflect.Value, opts *decodeOptions) error {
+	var n *big.Int
+	var err error
+	if s, ok := data.(string); ok {
+		n, err = parseInteger(s, "big.Int", maxBigIntBits)
+	} else {
+		n, err = integerValue(data, "big.Int", maxBigIntBits)
+	}
+	if err != nil {
+		return err
+	}
+	val.Set(reflect.ValueOf(*n))
+	return nil
+}
+
+func bigFloatDecoder(data interface{}, val reflect.Value, opts *decodeOptions) error {
+	var s string
+	switch d := data.(type) {
+	case string:
+		s = d
+	case json.Number:
+		s = string(d)
+	case float64:
+		if math.IsNaN(d) {
+			return errorAt("", "unsupported value %v", d)
+		}
+		val.Set(reflect.ValueOf(*big.NewFloat(d)))
+		return nil
+	default:
+		n, err := integerValue(data, "big.Float", maxBigIntBits)
+		if err != nil {
+			return err
+		}
+		val.Set(reflect.ValueOf(*new(big.Float).SetInt(n)))
+		return nil
+	}
+	f, _, err := big.ParseFloat(s, 10, floatPrec(s), big.ToNearestEven)
+	if err != nil {
+		return errorAt("", "bad number %q", shortNumber(s))
+	}
+	val.Set(reflect.ValueOf(*f))
+	return nil
+}
+
+// quotedDecoder - для json:",string": число может прийти строкой
+func quotedDecoder(dec decodeFunc) decodeFunc {
+	return func(data interface{}, val reflect.Value, opts *decodeOptions) error {
+		if s, ok := data.(string); ok {
+			data = json.Number(s)
+		}
+		return dec(data, val, opts)
+	}
+}
+
+// isNumberType - типы, для которых имеет смысл json:",string"
+func isNumberType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// ----------------
+
+// intEncoder и uintEncoder - целые для дерева s2i: float64, пока он точен, дальше json.Number
+func intEncoder(val reflect.Value) (interface{}, error) {
+	if n := val.Int(); n >= -maxExactFloat && n <= maxExactFloat {
+		return float64(n), nil
+	}
+	return json.Number(strconv.FormatInt(val.Int(), 10)), nil
+}
+
+func uintEncoder(val reflect.Value) (interface{}, error) {
+	if n := val.Uint(); n <= maxExactFloat {
+		return float64(n), nil
+	}
+	return json.Number(strconv.FormatUint(val.Uint(), 10)), nil
+}
+
+func bigIntEncoder(val reflect.Value) (interface{}, error) {
+	n := reflect.New(bigIntType)
+	n.Elem().Set(val)
+	return json.Number(n.Interface().(*big.Int).String()), nil
+}
+
+func bigFloatEncoder(val reflect.Value) (interface{}, error) {
+	f := reflect.New(bigFloatType)
+	f.Elem().Set(val)
+	if f.Interface().(*big.Float).IsInf() {
+		return nil, errorAt("", "unsupported value %s", f.Interface())
+	}
+	return json.Number(f.Interface().(*big.Float).Text('g', -1)), nil
+}
+
+// quoteNumber - для json:",string" в s2i: число пишется строкой
+func quoteNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case float64:
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	case json.Number:
+		return string(n)
+	}
+	return v
+}
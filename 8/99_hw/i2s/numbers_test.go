@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Numbers struct {
+	I    int
+	I8   int8
+	I64  int64
+	U8   uint8
+	U64  uint64
+	F32  float32
+	F64  float64
+	Qty  int      `json:"qty,string"`
+	Rate *float64 `json:"rate,string"`
+	Big  *big.Int
+	BigF *big.Float
+}
+
+// decodeJSONNumber - как decodeJSON, но числа остаются json.Number
+func decodeJSONNumber(t *testing.T, raw string) interface{} {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		t.Fatalf("bad json: %v", err)
+	}
+	return data
+}
+
+func TestNumbers(t *testing.T) {
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	bigFloat, _, _ := big.ParseFloat("0.1000000000000000000000000001", 10, floatPrec("0.1000000000000000000000000001"), big.ToNearestEven)
+	rate := 0.25
+	expected := &Numbers{
+		I:    1000,
+		I8:   -128,
+		I64:  math.MaxInt64,
+		U8:   255,
+		U64:  math.MaxUint64,
+		F32:  1.5,
+		F64:  0.1,
+		Qty:  42,
+		Rate: &rate,
+		Big:  bigInt,
+		BigF: bigFloat,
+	}
+	data := decodeJSONNumber(t, `{
+		"I": 1e3, "I8": -128, "I64": 9223372036854775807,
+		"U8": 255, "U64": 18446744073709551615,
+		"F32": 1.5, "F64": 0.1,
+		"qty": "42", "rate": "0.25",
+		"Big": 123456789012345678901234567890,
+		"BigF": 0.1000000000000000000000000001
+	}`)
+
+	result := &Numbers{}
+	if err := i2s(data, result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Big.Cmp(expected.Big) != 0 || result.BigF.Cmp(expected.BigF) != 0 {
+		t.Fatalf("big numbers not match: have %s %s", result.Big, result.BigF.Text('g', -1))
+	}
+	result.Big, result.BigF, expected.Big, expected.BigF = nil, nil, nil, nil
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+
+	// big.Int и из обычного json, и строкой
+	for _, raw := range []string{`{"Big": 1e3}`, `{"Big": "1000"}`} {
+		result := &Numbers{}
+		if err := i2s(decodeJSON(t, raw), result); err != nil || result.Big.Int64() != 1000 {
+			t.Errorf("%s: have %v %v", raw, result.Big, err)
+		}
+	}
+}
+
+func TestNumberErrors(t *testing.T) {
+	cases := []struct {
+		json    string
+		number  bool // разбирать с UseNumber
+		message string
+	}{
+		{`{"I": 1.5}`, false, "fractional"},
+		{`{"I": 1.5}`, true, "fractional"},
+		{`{"I8": 128}`, false, "overflows"},
+		{`{"I8": -129}`, true, "overflows"},
+		{`{"U8": -1}`, false, "overflows"},
+		{`{"U64": -1}`, true, "overflows"},
+		{`{"U64": 18446744073709551616}`, true, "overflows"},
+		{`{"I64": 1e19}`, false, "overflows"},
+		{`{"F32": 1e39}`, false, "overflows"},
+		{`{"F32": 1e39}`, true, "overflows"},
+		{`{"qty": "4.2"}`, false, "fractional"},
+		{`{"qty": "many"}`, false, "bad number"},
+		{`{"Big": 1.5}`, true, "fractional"},
+		{`{"I": "42"}`, false, "expected number"},
+	}
+	for i, c := range cases {
+		data := decodeJSON(t, c.json)
+		if c.number {
+			data = decodeJSONNumber(t, c.json)
+		}
+		err := i2s(data, &Numbers{})
+		var perr *pathError
+		if !errors.As(err, &perr) || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("[%d] %s: have %v, want %q", i, c.json, err, c.message)
+		}
+	}
+}
+
+// целые больше 2^53 s2i отдаёт json.Number, чтобы они пережили и i2s, и json.Marshal
+func TestNumbersRoundTrip(t *testing.T) {
+	rate := 0.5
+	in := &Numbers{
+		I64:  math.MaxInt64 - 1,
+		U64:  math.MaxUint64,
+		F64:  0.1,
+		Qty:  7,
+		Rate: &rate,
+		Big:  new(big.Int).Lsh(big.NewInt(1), 100),
+		BigF: big.NewFloat(2.5),
+	}
+	tree, err := s2i(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := tree.(map[string]interface{})
+	if fields["qty"] != "7" || fields["rate"] != "0.5" || fields["I64"] != json.Number("9223372036854775806") {
+		t.Fatalf("bad tree: %#v", tree)
+	}
+
+	out := &Numbers{}
+	if err := i2s(tree, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Big.Cmp(in.Big) != 0 || out.BigF.Cmp(in.BigF) != 0 {
+		t.Fatalf("big numbers not match: have %s %s", out.Big, out.BigF)
+	}
+	out.Big, out.BigF, in.Big, in.BigF = nil, nil, nil, nil
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", out, in)
+	}
+
+	raw, err := json.Marshal(tree)
+	if err != nil || !bytes.Contains(raw, []byte(`"U64":18446744073709551615`)) {
+		t.Errorf("json: have %s %v", raw, err)
+	}
+}
+
+// огромный показатель степени не должен превращаться в огромное целое, а ошибка - в мегабайты текста
+func TestNumberHugeExponent(t *testing.T) {
+	cases := []struct {
+		number  json.Number
+		out     interface{}
+		message string
+	}{
+		{"1e5000000", new(int), "overflows"},
+		{"1e300000000", new(uint64), "overflows"},
+		{"-1e300000000", new(int8), "overflows"},
+		{"1e300000000", new(big.Int), "overflows"},
+		{"1e-300000000", new(int), "fractional"},
+		{json.Number("1" + strings.Repeat("0", 100000)), new(int64), "overflows"},
+		{json.Number("1" + strings.Repeat("0", 100000)), new(big.Int), "overflows"},
+	}
+	for i, c := range cases {
+		start := time.Now()
+		err := i2s(c.number, c.out)
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("[%d] took %v", i, d)
+		}
+		if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("[%d] have %v, want %q", i, err, c.message)
+			continue
+		}
+		if len(err.Error()) > 100 {
+			t.Errorf("[%d] error too long: %d bytes", i, len(err.Error()))
+		}
+	}
+
+	// в пределах типа показатель степени по-прежнему работает
+	var n int64
+	if err := i2s(json.Number("9.2e18"), &n); err != nil || n != 9200000000000000000 {
+		t.Errorf("have %v %v", n, err)
+	}
+}
+
+// поведение по умолчанию: json.Number принимается в любом числовом поле, строка - только с тегом ,string
+func TestNumberStringsDefault(t *testing.T) {
+	result := &Numbers{}
+	raw := `{"I": 42, "U64": 18446744073709551615, "F64": 0.5, "qty": "7", "rate": "1.25"}`
+	if err := i2s(decodeJSONNumber(t, raw), result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rate := 1.25
+	expected := &Numbers{I: 42, U64: math.MaxUint64, F64: 0.5, Qty: 7, Rate: &rate}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+
+	for _, raw := range []string{`{"I": "42"}`, `{"U64": "1"}`, `{"F64": "0.5"}`} {
+		if err := i2s(decodeJSONNumber(t, raw), &Numbers{}); err == nil || !strings.Contains(err.Error(), "expected number") {
+			t.Errorf("%s: have %v, want expected number error", raw, err)
+		}
+	}
+
+	// большие числа строкой принимаются и без опции - иначе их не передать без потери точности
+	big := &Numbers{}
+	if err := i2s(decodeJSONNumber(t, `{"Big": "42"}`), big); err != nil || big.Big.Int64() != 42 {
+		t.Errorf("big: have %v, %v", big.Big, err)
+	}
+}
+
+func TestNumberStrings(t *testing.T) {
+	raw := `{"I": "1e3", "I8": "-128", "U64": "18446744073709551615", "F32": "1.5", "Big": "42"}`
+	expected := &Numbers{I: 1000, I8: -128, U64: math.MaxUint64, F32: 1.5}
+
+	// без опции строка в числовом поле без тега - ошибка типа
+	if err := i2s(decodeJSON(t, raw), &Numbers{}); err == nil || !strings.Contains(err.Error(), "expected number") {
+		t.Fatalf("have %v, want expected number error", err)
+	}
+
+	result := &Numbers{}
+	if err := i2s(decodeJSON(t, raw), result, NumberStrings()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Big.Int64() != 42 {
+		t.Errorf("big: have %v", result.Big)
+	}
+	result.Big = nil
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+
+	for _, c := range []struct{ json, message string }{
+		{`{"I8": "300"}`, "overflows"},
+		{`{"I": "4.2"}`, "fractional"},
+		{`{"F64": "many"}`, "bad number"},
+	} {
+		if err := i2s(decodeJSON(t, c.json), &Numbers{}, NumberStrings()); err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("%s: have %v, want %q", c.json, err, c.message)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding"
+	"reflect"
+	"sync"
+)
+
+// s2i - обратное к i2s: собирает из структуры дерево map[string]interface{} / []interface{},
+// как после распаковки json в interface{}. Имена полей, omitempty и "-" - по тем же тегам json.
+func s2i(in interface{}) (interface{}, error) {
+	if in == nil {
+		return nil, nil
+	}
+	val := reflect.ValueOf(in)
+	return encoderFor(val.Type())(val)
+}
+
+// encodeFunc превращает val в значение для дерева; путь в ошибку дописывают вызывающие, как в decodeFunc
+type encodeFunc func(val reflect.Value) (interface{}, error)
+
+// encoders - планы по типам, reflect.Type -> encodeFunc, как decoders для i2s
+var encoders sync.Map
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func encoderFor(t reflect.Type) encodeFunc {
+	if enc, ok := encoders.Load(t); ok {
+		return enc.(encodeFunc)
+	}
+
+	var (
+		wg  sync.WaitGroup
+		enc encodeFunc
+	)
+	wg.Add(1)
+	stub, loaded := encoders.LoadOrStore(t, encodeFunc(func(val reflect.Value) (interface{}, error) {
+		wg.Wait()
+		return enc(val)
+	}))
+	if loaded {
+		return stub.(encodeFunc)
+	}
+	enc = newEncoder(t)
+	wg.Done()
+	encoders.Store(t, enc)
+	return enc
+}
+
+func newEncoder(t reflect.Type) encodeFunc {
+	switch {
+	case t == bigIntType:
+		return bigIntEncoder
+	case t == bigFloatType:
+		return bigFloatEncoder
+	case t.Kind() != reflect.Ptr && t.Implements(textMarshalerType):
+		return textEncoder
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return func(val reflect.Value) (interface{}, error) {
+			if val.IsNil() {
+				return nil, nil
+			}
+			elem := val.Elem()
+			return encoderFor(elem.Type())(elem)
+		}
+	case reflect.Struct:
+		return structEncoder(t)
+	case reflect.Map:
+		return mapEncoder(t)
+	case reflect.Slice:
+		elemEnc := encoderFor(t.Elem())
+		return func(val reflect.Value) (interface{}, error) {
+			if val.IsNil() {
+				return nil, nil
+			}
+			return encodeElems(val, elemEnc)
+		}
+	case reflect.Array:
+		elemEnc := encoderFor(t.Elem())
+		return func(val reflect.Value) (interface{}, error) {
+			return encodeElems(val, elemEnc)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32, reflect.Float64:
+		return func(val reflect.Value) (interface{}, error) {
+			return val.Float(), nil
+		}
+	case reflect.String:
+		return func(val reflect.Value) (interface{}, error) {
+			return val.String(), nil
+		}
+	case reflect.Bool:
+		return func(val reflect.Value) (interface{}, error) {
+			return val.Bool(), nil
+		}
+	}
+	return func(val reflect.Value) (interface{}, error) {
+		return nil, errorAt("", "unsupported kind: %s", t.Kind())
+	}
+}
+
+func textEncoder(val reflect.Value) (interface{}, error) {
+	text, err := val.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return nil, &pathError{Err: err}
+	}
+	return string(text), nil
+}
+
+func encodeElems(val reflect.Value, elemEnc encodeFunc) (interface{}, error) {
+	res := make([]interface{}, val.Len())
+	for i := range res {
+		elem, err := elemEnc(val.Index(i))
+		if err != nil {
+			return nil, withPath(err, indexPath("", i))
+		}
+		res[i] = elem
+	}
+	return res, nil
+}
+
+// encodeFieldPlan - как достать одно поле структуры
+type encodeFieldPlan struct {
+	field
+	enc encodeFunc
+}
+
+func structEncoder(t reflect.Type) encodeFunc {
+	fields := structFields(t)
+	plan := make([]encodeFieldPlan, len(fields))
+	for i, f := range fields {
+		fieldType := t.FieldByIndex(f.index).Type
+		plan[i] = encodeFieldPlan{field: f, enc: encoderFor(fieldType)}
+		plan[i].quoted = f.quoted && isNumberType(fieldType)
+	}
+	return func(val reflect.Value) (interface{}, error) {
+		res := make(map[string]interface{}, len(plan))
+		for i := range plan {
+			f := &plan[i]
+			fieldVal, ok := fieldByIndexNoAlloc(val, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(fieldVal)) {
+				continue
+			}
+			v, err := f.enc(fieldVal)
+			if err != nil {
+				return nil, withPath(err, f.name)
+			}
+			if f.quoted {
+				v = quoteNumber(v)
+			}
+			res[f.name] = v
+		}
+		return res, nil
+	}
+}
+
+func mapEncoder(t reflect.Type) encodeFunc {
+	if t.Key().Kind() != reflect.String {
+		return func(val reflect.Value) (interface{}, error) {
+			return nil, errorAt("", "unsupported map key type %s", t.Key())
+		}
+	}
+	elemEnc := encoderFor(t.Elem())
+	return func(val reflect.Value) (interface{}, error) {
+		if val.IsNil() {
+			return nil, nil
+		}
+		res := make(map[string]interface{}, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			v, err := elemEnc(iter.Value())
+			if err != nil {
+				return nil, withPath(err, "["+key+"]")
+			}
+			res[key] = v
+		}
+		return res, nil
+	}
+}
+
+// fieldByIndexNoAlloc - поле по пути через встроенные структуры; через nil-указатель поля нет
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+// isEmptyValue - значения, которые omitempty пропускает, как в encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
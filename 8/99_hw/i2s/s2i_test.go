@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestS2I(t *testing.T) {
+	name := "rvasily"
+	in := &Kinds{
+		Base:    Base{ID: 1, Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		I8:      -8,
+		U64:     64,
+		F32:     1.5,
+		Name:    &name,
+		Address: &Address{City: "Moscow"},
+		Tags:    map[string]string{"a": "b"},
+		Pair:    [2]int{3, 0},
+		Any:     []interface{}{1.0, "two"},
+		Ignored: "skip me",
+	}
+	expected := decodeJSON(t, `{
+		"id": 1, "created": "2020-01-02T03:04:05Z",
+		"I8": -8, "I16": 0, "I32": 0, "I64": 0,
+		"U": 0, "U8": 0, "U16": 0, "U32": 0, "U64": 64,
+		"F32": 1.5, "F64": 0,
+		"Name": "rvasily",
+		"address": {"city": "Moscow"},
+		"tags": {"a": "b"},
+		"Scores": null,
+		"Pair": [3, 0],
+		"Any": [1, "two"],
+		"IP": ""
+	}`)
+
+	result, err := s2i(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("results not match\nGot:\n%#v\nExpected:\n%#v", result, expected)
+	}
+
+	var perr *pathError
+	if _, err := s2i(map[string]interface{}{"f": []interface{}{func() {}}}); !errors.As(err, &perr) || perr.Path != "[f][0]" {
+		t.Errorf("unsupported kind: have %v, want error at [f][0]", err)
+	}
+}
+
+type Account struct {
+	Login   string `json:"login" i2s:"required"`
+	Email   string `json:"email,omitempty"`
+	Profile *Profile
+}
+
+type Profile struct {
+	Age int `i2s:"required"`
+}
+
+func TestStrict(t *testing.T) {
+	cases := []struct {
+		json string
+		path string // пусто - ошибки нет
+	}{
+		{`{"login": "rvasily", "email": "r@example.com"}`, ""},
+		{`{"login": "rvasily", "Profile": {"Age": 30}}`, ""},
+		{`{"email": "r@example.com"}`, "login"},
+		{`{"login": "rvasily", "nick": "r", "avatar": "x"}`, "avatar"},
+		{`{"login": "rvasily", "Profile": {}}`, "Profile.Age"},
+		{`{"login": "rvasily", "Profile": {"Age": 30, "Height": 180}}`, "Profile.Height"},
+	}
+	for i, c := range cases {
+		data := decodeJSON(t, c.json)
+		// без Strict лишнее и недостающее пропускается
+		if err := i2s(data, &Account{}); err != nil {
+			t.Errorf("[%d] non-strict: unexpected error: %v", i, err)
+		}
+
+		err := i2s(data, &Account{}, Strict())
+		if c.path == "" {
+			if err != nil {
+				t.Errorf("[%d] unexpected error: %v", i, err)
+			}
+			continue
+		}
+		var perr *pathError
+		if !errors.As(err, &perr) || perr.Path != c.path {
+			t.Errorf("[%d] have %v, want error at %s", i, err, c.path)
+		}
+	}
+}
+
+// Record - всё, что переживает s2i -> i2s без потерь: целые помещаются во float64,
+// а пустых слайсов с omitempty нет, иначе вернётся nil
+type Record struct {
+	ID       int32  `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Active   bool
+	Score    float64
+	Small    uint8
+	Tags     []string
+	Counters map[string]int16
+	Parent   *Record `json:"parent,omitempty"`
+	Pair     [2]bool
+}
+
+func TestRoundTrip(t *testing.T) {
+	// s2i -> i2s возвращает ту же структуру, а повторный s2i - то же дерево
+	check := func(in Record) bool {
+		tree, err := s2i(&in)
+		if err != nil {
+			t.Logf("s2i: %v", err)
+			return false
+		}
+		out := Record{}
+		if err := i2s(tree, &out, Strict()); err != nil {
+			t.Logf("i2s: %v", err)
+			return false
+		}
+		again, err := s2i(&out)
+		if err != nil {
+			t.Logf("s2i: %v", err)
+			return false
+		}
+		return reflect.DeepEqual(in, out) && reflect.DeepEqual(tree, again)
+	}
+	cfg := &quick.Config{MaxCount: 500, Rand: rand.New(rand.NewSource(1))}
+	if err := quick.Check(check, cfg); err != nil {
+		t.Error(err)
+	}
+}
@@ -84,11 +84,18 @@ func WeirdMagicClone(in interface{}) interface{} {
 }
 
 func TestApp(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			runAppTests(t, backend.app(t))
+		})
+	}
+}
+
+func runAppTests(t *testing.T, app http.Handler) {
 	rand.Seed(time.Now().UnixNano())
 
 	var (
-		app = GetApp()
-		ts  = httptest.NewServer(app)
+		ts = httptest.NewServer(app)
 
 		// username = RandStringRunes(16)
 		username = "golang"
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"path/filepath"
+	"rwa/internal/db"
+	"rwa/internal/repository"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testBackends - хранилища, на которых гоняются интеграционные тесты: один и тот же сценарий
+// должен проходить и на in-memory, и на SQL-репозиториях
+var testBackends = []struct {
+	name string
+	app  func(t *testing.T) http.Handler
+}{
+	{"memory", func(t *testing.T) http.Handler { return GetApp() }},
+	{"sqlite", sqliteApp},
+	{"postgres-syntax", pgSyntaxApp},
+}
+
+// sqliteApp - приложение на свежей SQLite-базе во временном каталоге теста
+func sqliteApp(t *testing.T) http.Handler {
+	app, err := GetSQLApp(openSQLite(t))
+	if err != nil {
+		t.Fatalf("sql app: %v", err)
+	}
+	return app
+}
+
+// pgSyntaxDialect - запросы как для postgres ($n и RETURNING), а схема из миграций SQLite:
+// SQLite понимает и то и другое, так что запросы postgres-диалекта проверяются без сервера postgres
+type pgSyntaxDialect struct {
+	db.Dialect
+}
+
+func (pgSyntaxDialect) Name() string {
+	return db.SQLite.Name()
+}
+
+func pgSyntaxApp(t *testing.T) http.Handler {
+	repos, err := repository.NewSQLDialect(openSQLite(t), pgSyntaxDialect{db.Postgres})
+	if err != nil {
+		t.Fatalf("sql app: %v", err)
+	}
+	return newApp(repos, newTokens())
+}
+
+func openSQLite(t *testing.T) *sql.DB {
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rwa.db")+"?_foreign_keys=1")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// SQLite не любит параллельных писателей, одного соединения хватает
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
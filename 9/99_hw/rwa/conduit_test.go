@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"rwa/internal/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conduitCase - шаг сценария для остальной части RealWorld API: профили, подписки, избранное, комментарии, теги, лента.
+// В URL и Body подставляются {{параметры}}, Check проверяет распакованный ответ и может запомнить новые параметры.
+type conduitCase struct {
+	Name   string
+	Method string
+	URL    string
+	Body   string
+	Token  string
+	Status int
+	Check  func(t *testing.T, resp map[string]interface{}, params map[string]string)
+}
+
+// jsonPath достаёт значение из распакованного json по пути вида "article.author.following"
+// или "comments.0.id"
+func jsonPath(t *testing.T, data interface{}, path string) interface{} {
+	t.Helper()
+	for _, key := range strings.Split(path, ".") {
+		switch node := data.(type) {
+		case map[string]interface{}:
+			data = node[key]
+		case []interface{}:
+			var idx int
+			if _, err := fmt.Sscan(key, &idx); err != nil || idx >= len(node) {
+				t.Fatalf("bad index %q in path %s", key, path)
+			}
+			data = node[idx]
+		default:
+			t.Fatalf("cannot walk %q in path %s: %#v", key, path, data)
+		}
+	}
+	return data
+}
+
+func expectPath(want map[string]interface{}) func(*testing.T, map[string]interface{}, map[string]string) {
+	return func(t *testing.T, resp map[string]interface{}, _ map[string]string) {
+		t.Helper()
+		for path, value := range want {
+			if got := jsonPath(t, resp, path); !reflect.DeepEqual(got, value) {
+				t.Errorf("%s: have %#v, want %#v", path, got, value)
+			}
+		}
+	}
+}
+
+// forgedToken - правильно устроенный токен, подписанный не тем ключом
+func forgedToken(t *testing.T) string {
+	token, _, err := auth.NewTokens([]byte("not the app secret"), time.Hour).Issue("sess", "1")
+	if err != nil {
+		t.Fatalf("cant issue token: %v", err)
+	}
+	return token
+}
+
+func TestConduit(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			runConduitTests(t, backend.app(t))
+		})
+	}
+}
+
+func runConduitTests(t *testing.T, app http.Handler) {
+	ts := httptest.NewServer(app)
+	defer ts.Close()
+
+	// подпись не сходится: токен выдан с чужим ключом
+	params := map[string]string{"forged": forgedToken(t)}
+	remember := func(name, path string) func(*testing.T, map[string]interface{}, map[string]string) {
+		return func(t *testing.T, resp map[string]interface{}, params map[string]string) {
+			params[name] = fmt.Sprint(jsonPath(t, resp, path))
+		}
+	}
+
+	cases := []conduitCase{
+		{
+			Name: "register alice", Method: "POST", URL: "/api/users", Status: 201,
+			Body:  `{"user":{"email":"alice@example.com","password":"a","username":"alice"}}`,
+			Check: remember("alice", "user.token"),
+		},
+		{
+			Name: "register bob", Method: "POST", URL: "/api/users", Status: 201,
+			Body:  `{"user":{"email":"bob@example.com","password":"b","username":"bob"}}`,
+			Check: remember("bob", "user.token"),
+		},
+		{
+			Name: "bob writes article", Method: "POST", URL: "/api/articles", Token: "bob", Status: 201,
+			Body:  `{"article":{"title":"Bob news","description":"d","body":"b","tagList":["news","go"]}}`,
+			Check: remember("slug", "article.slug"),
+		},
+		{
+			Name: "alice writes article", Method: "POST", URL: "/api/articles", Token: "alice", Status: 201,
+			Body: `{"article":{"title":"Alice notes","description":"d","body":"b","tagList":["go"]}}`,
+		},
+		{
+			Name: "tags", Method: "GET", URL: "/api/tags", Status: 200,
+			Check: expectPath(map[string]interface{}{"tags": []interface{}{"go", "news"}}),
+		},
+		{
+			Name: "profile anonymous", Method: "GET", URL: "/api/profiles/bob", Status: 200,
+			Check: expectPath(map[string]interface{}{"profile.username": "bob", "profile.following": false}),
+		},
+		{Name: "profile unknown", Method: "GET", URL: "/api/profiles/nobody", Status: 404},
+		{Name: "follow requires auth", Method: "POST", URL: "/api/profiles/bob/follow", Status: 401},
+		{Name: "follow yourself", Method: "POST", URL: "/api/profiles/alice/follow", Token: "alice", Status: 422},
+		{
+			Name: "alice follows bob", Method: "POST", URL: "/api/profiles/bob/follow", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"profile.username": "bob", "profile.following": true}),
+		},
+		{
+			Name: "profile following", Method: "GET", URL: "/api/profiles/bob", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"profile.following": true}),
+		},
+		{Name: "feed requires auth", Method: "GET", URL: "/api/articles/feed", Status: 401},
+		{
+			Name: "feed", Method: "GET", URL: "/api/articles/feed", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{
+				"articlesCount":               float64(1),
+				"articles.0.title":            "Bob news",
+				"articles.0.tagList":          []interface{}{"news", "go"},
+				"articles.0.author.username":  "bob",
+				"articles.0.author.following": true,
+				"articles.0.favorited":        false,
+				"articles.0.favoritesCount":   float64(0),
+			}),
+		},
+		{
+			Name: "feed of bob is empty", Method: "GET", URL: "/api/articles/feed", Token: "bob", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(0)}),
+		},
+		{Name: "favorite requires auth", Method: "POST", URL: "/api/articles/{{slug}}/favorite", Status: 401},
+		{Name: "favorite unknown article", Method: "POST", URL: "/api/articles/nope/favorite", Token: "alice", Status: 404},
+		{
+			Name: "alice favorites", Method: "POST", URL: "/api/articles/{{slug}}/favorite", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"article.favorited": true, "article.favoritesCount": float64(1)}),
+		},
+		{
+			Name: "article for bob", Method: "GET", URL: "/api/articles/{{slug}}", Token: "bob", Status: 200,
+			Check: expectPath(map[string]interface{}{"article.favorited": false, "article.favoritesCount": float64(1)}),
+		},
+		{
+			Name: "filter favorited", Method: "GET", URL: "/api/articles?favorited=alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(1), "articles.0.slug": "bob-news-1"}),
+		},
+		{
+			Name: "pagination", Method: "GET", URL: "/api/articles?limit=1&offset=1", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(2), "articles.0.title": "Alice notes"}),
+		},
+		{Name: "bad limit", Method: "GET", URL: "/api/articles?limit=x", Status: 400},
+		{
+			Name: "huge limit", Method: "GET", URL: "/api/articles?limit=9223372036854775807&offset=1", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(2), "articles.0.title": "Alice notes"}),
+		},
+		{
+			Name: "offset past the end", Method: "GET", URL: "/api/articles?offset=9223372036854775807", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(2), "articles": []interface{}{}}),
+		},
+		{
+			Name: "filter author and tag", Method: "GET", URL: "/api/articles?author=alice&tag=go", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(1), "articles.0.title": "Alice notes"}),
+		},
+		{
+			Name: "filter tag", Method: "GET", URL: "/api/articles?tag=news", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(1), "articles.0.tagList": []interface{}{"news", "go"}}),
+		},
+		{
+			Name: "filter unknown author", Method: "GET", URL: "/api/articles?author=nobody", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(0), "articles": []interface{}{}}),
+		},
+		{
+			Name: "alice comments", Method: "POST", URL: "/api/articles/{{slug}}/comments", Token: "alice", Status: 200,
+			Body: `{"comment":{"body":"nice"}}`,
+			Check: func(t *testing.T, resp map[string]interface{}, params map[string]string) {
+				expectPath(map[string]interface{}{"comment.body": "nice", "comment.author.username": "alice"})(t, resp, params)
+				remember("comment", "comment.id")(t, resp, params)
+			},
+		},
+		{Name: "comment requires auth", Method: "POST", URL: "/api/articles/{{slug}}/comments", Status: 401, Body: `{"comment":{"body":"x"}}`},
+		{Name: "empty comment", Method: "POST", URL: "/api/articles/{{slug}}/comments", Token: "bob", Status: 422, Body: `{"comment":{}}`},
+		{
+			Name: "list comments", Method: "GET", URL: "/api/articles/{{slug}}/comments", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{
+				"comments.0.body":             "nice",
+				"comments.0.author.following": false,
+			}),
+		},
+		{Name: "bob cannot delete alice comment", Method: "DELETE", URL: "/api/articles/{{slug}}/comments/{{comment}}", Token: "bob", Status: 403},
+		{Name: "delete comment", Method: "DELETE", URL: "/api/articles/{{slug}}/comments/{{comment}}", Token: "alice", Status: 200},
+		{Name: "comment is gone", Method: "DELETE", URL: "/api/articles/{{slug}}/comments/{{comment}}", Token: "alice", Status: 404},
+		{
+			Name: "alice unfavorites", Method: "DELETE", URL: "/api/articles/{{slug}}/favorite", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"article.favorited": false, "article.favoritesCount": float64(0)}),
+		},
+		{
+			Name: "alice unfollows bob", Method: "DELETE", URL: "/api/profiles/bob/follow", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"profile.following": false}),
+		},
+		{
+			Name: "feed after unfollow", Method: "GET", URL: "/api/articles/feed", Token: "alice", Status: 200,
+			Check: expectPath(map[string]interface{}{"articlesCount": float64(0)}),
+		},
+		{
+			Name: "alice cannot edit bob article", Method: "PUT", URL: "/api/articles/{{slug}}", Token: "alice", Status: 403,
+			Body: `{"article":{"body":"mine now"}}`,
+		},
+		{Name: "alice cannot delete bob article", Method: "DELETE", URL: "/api/articles/{{slug}}", Token: "alice", Status: 403},
+		{Name: "forged token", Method: "GET", URL: "/api/articles", Token: "forged", Status: 401},
+		{Name: "alice logs out", Method: "POST", URL: "/api/user/logout", Token: "alice", Status: 200},
+		{Name: "revoked token", Method: "GET", URL: "/api/user", Token: "alice", Status: 401},
+		{Name: "revoked token on public route", Method: "GET", URL: "/api/articles", Token: "alice", Status: 401},
+		{Name: "bob is still logged in", Method: "GET", URL: "/api/user", Token: "bob", Status: 200},
+	}
+
+	for _, c := range cases {
+		ok := t.Run(c.Name, func(t *testing.T) {
+			replacer := func(s string) string {
+				for k, v := range params {
+					s = strings.ReplaceAll(s, "{{"+k+"}}", v)
+				}
+				return s
+			}
+
+			req, _ := http.NewRequest(c.Method, ts.URL+replacer(c.URL), bytes.NewReader([]byte(replacer(c.Body))))
+			req.Header.Add("Content-Type", "application/json")
+			if c.Token != "" {
+				req.Header.Add("Authorization", "Token "+params[c.Token])
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request error: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != c.Status {
+				t.Fatalf("bad status code, want: %v, have: %v, body: %s", c.Status, resp.StatusCode, body)
+			}
+			if c.Check == nil {
+				return
+			}
+			var data map[string]interface{}
+			if err := json.Unmarshal(body, &data); err != nil {
+				t.Fatalf("cant unmarshal resp: %s, body: %s", err, body)
+			}
+			c.Check(t, data, params)
+		})
+		if !ok {
+			break
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("love")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(hash, "love") {
+		t.Fatalf("hash contains plain password: %s", hash)
+	}
+	if !CheckPassword(hash, "love") {
+		t.Error("right password rejected")
+	}
+	if CheckPassword(hash, "nolove") {
+		t.Error("wrong password accepted")
+	}
+	// одинаковые пароли - разные хеши, соль у каждого своя
+	if again, _ := HashPassword("love"); again == hash {
+		t.Error("same hash for two users")
+	}
+	// пароль, сохранённый до хеширования, не подходит
+	if CheckPassword("love", "love") {
+		t.Error("plain text accepted as hash")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tokens := NewTokens([]byte("secret"), time.Hour)
+	token, expiresAt, err := tokens.Issue("sess1", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expiresAt); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("bad expiry: %v", expiresAt)
+	}
+
+	claims, err := tokens.Parse(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.ID != "sess1" || claims.Subject != "42" {
+		t.Errorf("bad claims: %+v", claims)
+	}
+
+	expired, _, _ := NewTokens([]byte("secret"), -time.Minute).Issue("sess1", "42")
+	foreign, _, _ := NewTokens([]byte("other"), time.Hour).Issue("sess1", "42")
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{ID: "sess1", Subject: "42"}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	for name, bad := range map[string]string{
+		"expired": expired,
+		"foreign": foreign,
+		"none":    none,
+		"garbage": "token_for_user_1",
+	} {
+		if _, err := tokens.Parse(bad); !errors.Is(err, ErrBadToken) {
+			t.Errorf("%s: have %v, want ErrBadToken", name, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Пароли хешируются argon2id, как в 9/password_hashing: в базе лежит base64 от [соль][хеш].
+// Соль у каждого пользователя своя.
+
+const (
+	saltLen      = 8
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+)
+
+// HashPassword возвращает хеш пароля со свежей случайной солью.
+func HashPassword(plain string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(hashPass(salt, plain)), nil
+}
+
+// CheckPassword сверяет пароль с хешем из HashPassword. Сравнение - за постоянное время.
+func CheckPassword(hash, plain string) bool {
+	raw, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil || len(raw) != saltLen+argonKeyLen {
+		return false
+	}
+	return subtle.ConstantTimeCompare(hashPass(raw[:saltLen], plain), raw) == 1
+}
+
+func hashPass(salt []byte, plain string) []byte {
+	hashed := argon2.IDKey([]byte(plain), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	// [salt] + [pass_hash]
+	return append(append(make([]byte, 0, saltLen+argonKeyLen), salt...), hashed...)
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Токен - JWT, подписанный HS256. В jti лежит идентификатор сессии, в sub - пользователь.
+// Сам токен нигде не хранится: сессия остаётся stateful через запись с этим jti в хранилище.
+
+var ErrBadToken = errors.New("bad token")
+
+type Tokens struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokens(secret []byte, ttl time.Duration) *Tokens {
+	return &Tokens{secret: secret, ttl: ttl}
+}
+
+// Issue подписывает токен для сессии sessionID пользователя userID и возвращает его вместе со временем истечения.
+func (t *Tokens) Issue(sessionID, userID string) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(t.ttl)
+	claims := jwt.RegisteredClaims{
+		ID:        sessionID,
+		Subject:   userID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Parse проверяет подпись и срок действия токена и возвращает его claims.
+func (t *Tokens) Parse(token string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, t.parseSecretGetter,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadToken, err)
+	}
+	if claims.ID == "" || claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no session", ErrBadToken)
+	}
+	return claims, nil
+}
+
+func (t *Tokens) parseSecretGetter(token *jwt.Token) (interface{}, error) {
+	method, ok := token.Method.(*jwt.SigningMethodHMAC)
+	if !ok || method.Alg() != "HS256" {
+		return nil, fmt.Errorf("bad sign method")
+	}
+	return t.secret, nil
+}
+
+// NewSessionID - случайный идентификатор сессии, он же jti токена.
+func NewSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
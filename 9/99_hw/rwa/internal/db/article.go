@@ -2,25 +2,25 @@ package db
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
 type Article struct {
-	ID             int       `json:"id"`
-	Title          string    `json:"title"`
-	Description    string    `json:"description"`
-	Body           string    `json:"body"`
-	TagList        []string  `json:"tagList"`
-	Slug           string    `json:"slug"`
-	AuthorID       string    `json:"authorId"`
-	Favorited      bool      `json:"favorited"`
-	FavoritesCount int       `json:"favoritesCount"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Body        string    `json:"body"`
+	TagList     []string  `json:"tagList"`
+	Slug        string    `json:"slug"`
+	AuthorID    string    `json:"authorId"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
+// ArticleDB - in-memory хранилище статей, ключ - slug. Как и UserDB, отдаёт копии.
 type ArticleDB struct {
 	data   map[string]*Article
 	nextID int
@@ -41,22 +41,26 @@ func (adb *ArticleDB) CreateArticle(article *Article) *Article {
 	article.ID = adb.nextID
 	adb.nextID++
 
-	article.Slug = fmt.Sprintf("%s-%d", slugify(article.Title), article.ID)
+	article.Slug = ArticleSlug(article.Title, article.ID)
 	article.CreatedAt = time.Now().UTC()
 	article.UpdatedAt = article.CreatedAt
 
-	adb.data[article.Slug] = article
+	stored := *article
+	adb.data[article.Slug] = &stored
 	return article
 }
 
+// GetAllArticles возвращает статьи в порядке создания.
 func (adb *ArticleDB) GetAllArticles() []*Article {
 	adb.mu.Lock()
 	defer adb.mu.Unlock()
 
 	articles := make([]*Article, 0, len(adb.data))
 	for _, art := range adb.data {
-		articles = append(articles, art)
+		found := *art
+		articles = append(articles, &found)
 	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].ID < articles[j].ID })
 	return articles
 }
 
@@ -65,7 +69,11 @@ func (adb *ArticleDB) GetArticleBySlug(slug string) (*Article, bool) {
 	defer adb.mu.Unlock()
 
 	art, ok := adb.data[slug]
-	return art, ok
+	if !ok {
+		return nil, false
+	}
+	found := *art
+	return &found, true
 }
 
 func (adb *ArticleDB) UpdateArticle(slug string, updated *Article) (*Article, bool) {
@@ -91,20 +99,23 @@ func (adb *ArticleDB) UpdateArticle(slug string, updated *Article) (*Article, bo
 	}
 
 	art.UpdatedAt = time.Now().UTC()
-	return art, true
+	found := *art
+	return &found, true
 }
 
-func (adb *ArticleDB) DeleteArticle(slug string) bool {
+// DeleteArticle удаляет статью и возвращает её, чтобы по ID можно было убрать зависимые записи.
+func (adb *ArticleDB) DeleteArticle(slug string) (*Article, bool) {
 	adb.mu.Lock()
 	defer adb.mu.Unlock()
 
-	if _, ok := adb.data[slug]; ok {
+	art, ok := adb.data[slug]
+	if ok {
 		delete(adb.data, slug)
-		return true
 	}
-	return false
+	return art, ok
 }
 
-func slugify(title string) string {
-	return strings.ToLower(strings.ReplaceAll(title, " ", "-"))
+// ArticleSlug строит slug статьи из заголовка и ID; ID делает его уникальным.
+func ArticleSlug(title string, id int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(strings.ReplaceAll(title, " ", "-")), id)
 }
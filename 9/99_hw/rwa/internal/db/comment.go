@@ -0,0 +1,89 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type Comment struct {
+	ID        int       `json:"id"`
+	ArticleID int       `json:"articleId"`
+	AuthorID  string    `json:"authorId"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CommentDB — in-memory хранилище комментариев к статьям.
+type CommentDB struct {
+	data   map[int]*Comment
+	nextID int
+	mu     sync.Mutex
+}
+
+func NewCommentDB() *CommentDB {
+	return &CommentDB{
+		data:   make(map[int]*Comment),
+		nextID: 1,
+	}
+}
+
+func (cdb *CommentDB) CreateComment(comment *Comment) *Comment {
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	comment.ID = cdb.nextID
+	cdb.nextID++
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+
+	cdb.data[comment.ID] = comment
+	return comment
+}
+
+// GetCommentsByArticle возвращает комментарии статьи в порядке добавления.
+func (cdb *CommentDB) GetCommentsByArticle(articleID int) []*Comment {
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	comments := make([]*Comment, 0)
+	for _, c := range cdb.data {
+		if c.ArticleID == articleID {
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	return comments
+}
+
+func (cdb *CommentDB) GetCommentByID(id int) (*Comment, bool) {
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	c, ok := cdb.data[id]
+	return c, ok
+}
+
+func (cdb *CommentDB) DeleteComment(id int) bool {
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	if _, ok := cdb.data[id]; ok {
+		delete(cdb.data, id)
+		return true
+	}
+	return false
+}
+
+// DeleteArticleComments удаляет все комментарии удалённой статьи.
+func (cdb *CommentDB) DeleteArticleComments(articleID int) {
+	cdb.mu.Lock()
+	defer cdb.mu.Unlock()
+
+	for id, c := range cdb.data {
+		if c.ArticleID == articleID {
+			delete(cdb.data, id)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// DenylistDB — in-memory список отозванных токенов: jti -> когда токен истечёт сам.
+// После этого момента запись не нужна, токен и так не пройдёт проверку.
+type DenylistDB struct {
+	data map[string]time.Time
+	mu   sync.Mutex
+}
+
+func NewDenylistDB() *DenylistDB {
+	return &DenylistDB{
+		data: make(map[string]time.Time),
+	}
+}
+
+// Revoke заносит токен в список; заодно выбрасывает записи об уже истёкших токенах.
+func (ddb *DenylistDB) Revoke(id string, expiresAt time.Time) {
+	ddb.mu.Lock()
+	defer ddb.mu.Unlock()
+
+	now := time.Now()
+	for revoked, exp := range ddb.data {
+		if !exp.After(now) {
+			delete(ddb.data, revoked)
+		}
+	}
+	ddb.data[id] = expiresAt
+}
+
+func (ddb *DenylistDB) IsRevoked(id string) bool {
+	ddb.mu.Lock()
+	defer ddb.mu.Unlock()
+
+	_, ok := ddb.data[id]
+	return ok
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect - то, чем для приложения отличаются базы: схема (свой каталог миграций),
+// плейсхолдеры и получение id вставленной строки. Остальной SQL общий для всех.
+type Dialect interface {
+	// Name - имя диалекта, оно же каталог migrations/<name>
+	Name() string
+	// Rebind переводит плейсхолдеры "?" в плейсхолдеры базы; "?" внутри строковых литералов запросы не используют
+	Rebind(query string) string
+	// InsertID выполняет INSERT и возвращает id новой строки
+	InsertID(q Execer, query string, args ...interface{}) (int64, error)
+}
+
+// Execer - общее у *sql.DB и *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+var (
+	SQLite   Dialect = sqliteDialect{}
+	Postgres Dialect = postgresDialect{}
+)
+
+// DetectDialect выбирает диалект по типу драйвера, чтобы не тянуть сюда импорты драйверов
+func DetectDialect(conn *sql.DB) (Dialect, error) {
+	driver := fmt.Sprintf("%T", conn.Driver())
+	switch {
+	case strings.Contains(driver, "sqlite"):
+		return SQLite, nil
+	case strings.HasPrefix(driver, "*pq."), strings.HasPrefix(driver, "*stdlib."):
+		return Postgres, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %s", driver)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+func (sqliteDialect) InsertID(q Execer, query string, args ...interface{}) (int64, error) {
+	res, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+// Rebind нумерует плейсхолдеры: "a = ? AND b = ?" -> "a = $1 AND b = $2"
+func (postgresDialect) Rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// InsertID - в postgres нет LastInsertId, id возвращаем через RETURNING
+func (postgresDialect) InsertID(q Execer, query string, args ...interface{}) (int64, error) {
+	var id int64
+	err := q.QueryRow(query+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
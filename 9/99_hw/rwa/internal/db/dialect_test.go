@@ -0,0 +1,26 @@
+package db
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	cases := []struct {
+		query, sqlite, postgres string
+	}{
+		{`SELECT 1`, `SELECT 1`, `SELECT 1`},
+		{`SELECT * FROM users WHERE id = ?`, `SELECT * FROM users WHERE id = ?`, `SELECT * FROM users WHERE id = $1`},
+		{
+			`SELECT id FROM articles WHERE author_id IN (?, ?, ?) LIMIT ? OFFSET ?`,
+			`SELECT id FROM articles WHERE author_id IN (?, ?, ?) LIMIT ? OFFSET ?`,
+			`SELECT id FROM articles WHERE author_id IN ($1, $2, $3) LIMIT $4 OFFSET $5`,
+		},
+		{`UPDATE тег SET имя = ?`, `UPDATE тег SET имя = ?`, `UPDATE тег SET имя = $1`},
+	}
+	for _, c := range cases {
+		if got := SQLite.Rebind(c.query); got != c.sqlite {
+			t.Errorf("sqlite %q: have %q, want %q", c.query, got, c.sqlite)
+		}
+		if got := Postgres.Rebind(c.query); got != c.postgres {
+			t.Errorf("postgres %q: have %q, want %q", c.query, got, c.postgres)
+		}
+	}
+}
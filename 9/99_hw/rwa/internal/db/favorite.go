@@ -0,0 +1,59 @@
+package db
+
+import "sync"
+
+// FavoriteDB — in-memory хранилище избранного: статья (по ID) -> пользователи, добавившие её в избранное.
+type FavoriteDB struct {
+	data map[int]map[string]bool
+	mu   sync.Mutex
+}
+
+// NewFavoriteDB создаёт новое хранилище избранного.
+func NewFavoriteDB() *FavoriteDB {
+	return &FavoriteDB{
+		data: make(map[int]map[string]bool),
+	}
+}
+
+// Favorite добавляет статью в избранное пользователя.
+func (fdb *FavoriteDB) Favorite(articleID int, userID string) {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	if fdb.data[articleID] == nil {
+		fdb.data[articleID] = make(map[string]bool)
+	}
+	fdb.data[articleID][userID] = true
+}
+
+// Unfavorite убирает статью из избранного пользователя.
+func (fdb *FavoriteDB) Unfavorite(articleID int, userID string) {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	delete(fdb.data[articleID], userID)
+}
+
+// IsFavorited сообщает, есть ли статья в избранном пользователя.
+func (fdb *FavoriteDB) IsFavorited(articleID int, userID string) bool {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	return fdb.data[articleID][userID]
+}
+
+// Count возвращает, сколько пользователей добавили статью в избранное.
+func (fdb *FavoriteDB) Count(articleID int) int {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	return len(fdb.data[articleID])
+}
+
+// DeleteArticle забывает избранное удалённой статьи.
+func (fdb *FavoriteDB) DeleteArticle(articleID int) {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	delete(fdb.data, articleID)
+}
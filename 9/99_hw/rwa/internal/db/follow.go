@@ -0,0 +1,55 @@
+package db
+
+import "sync"
+
+// FollowDB — in-memory хранилище подписок: followerID подписан на followeeID.
+type FollowDB struct {
+	data map[string]map[string]bool
+	mu   sync.Mutex
+}
+
+// NewFollowDB создаёт новое хранилище подписок.
+func NewFollowDB() *FollowDB {
+	return &FollowDB{
+		data: make(map[string]map[string]bool),
+	}
+}
+
+// Follow подписывает followerID на followeeID. Повторная подписка ничего не меняет.
+func (fdb *FollowDB) Follow(followerID, followeeID string) {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	if fdb.data[followerID] == nil {
+		fdb.data[followerID] = make(map[string]bool)
+	}
+	fdb.data[followerID][followeeID] = true
+}
+
+// Unfollow отписывает followerID от followeeID.
+func (fdb *FollowDB) Unfollow(followerID, followeeID string) {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	delete(fdb.data[followerID], followeeID)
+}
+
+// IsFollowing сообщает, подписан ли followerID на followeeID.
+func (fdb *FollowDB) IsFollowing(followerID, followeeID string) bool {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	return fdb.data[followerID][followeeID]
+}
+
+// GetFollowing возвращает идентификаторы всех, на кого подписан followerID.
+func (fdb *FollowDB) GetFollowing(followerID string) []string {
+	fdb.mu.Lock()
+	defer fdb.mu.Unlock()
+
+	ids := make([]string, 0, len(fdb.data[followerID]))
+	for id := range fdb.data[followerID] {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Схема SQL-хранилища лежит в migrations/<диалект>/NNNN_описание.sql и вшита в бинарник.
+// Номера и смысл миграций у диалектов одинаковые, различается только DDL.
+// Применённые версии записываются в schema_migrations, так что Migrate можно звать на каждом старте.
+
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// Migrate применяет к базе миграции, которых в ней ещё нет. Каждая миграция - в своей транзакции.
+func Migrate(conn *sql.DB, dialect Dialect) error {
+	if _, err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := conn.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(conn, dialect, m); err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+func loadMigrations(dialect Dialect) ([]migration, error) {
+	dir := "migrations/" + dialect.Name()
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]migration, 0, len(entries))
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("bad migration name %q, want NNNN_name.sql", e.Name())
+		}
+		body, err := migrationFiles.ReadFile(dir + "/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: version, name: e.Name(), sql: string(body)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+func applyMigration(conn *sql.DB, dialect Dialect, m migration) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// драйверы не обязаны уметь несколько запросов в одном Exec, поэтому режем файл по ";"
+	for _, stmt := range strings.Split(m.sql, ";") {
+		if strings.TrimSpace(stripComments(stmt)) == "" {
+			continue
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(dialect.Rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// stripComments убирает строки-комментарии "--", чтобы не отправлять в базу пустые запросы.
+func stripComments(stmt string) string {
+	lines := strings.Split(stmt, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
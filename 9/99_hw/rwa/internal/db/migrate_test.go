@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMigrate(t *testing.T) {
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rwa.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer conn.Close()
+
+	// повторный запуск не должен трогать уже применённые миграции
+	for i := 0; i < 2; i++ {
+		if err := Migrate(conn, SQLite); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+
+	migrations, err := loadMigrations(SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("applied %d migrations, want %d", applied, len(migrations))
+	}
+	for _, table := range []string{"users", "sessions", "articles", "article_tags", "follows", "favorites", "comments", "revoked_tokens"} {
+		if _, err := conn.Exec(`SELECT COUNT(*) FROM ` + table); err != nil {
+			t.Errorf("table %s: %v", table, err)
+		}
+	}
+}
+
+// у всех диалектов одни и те же версии миграций: версия в schema_migrations значит одно и то же
+func TestMigrationsMatch(t *testing.T) {
+	sqlite, err := loadMigrations(SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := loadMigrations(Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("sqlite has %d migrations, postgres %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].name != postgres[i].name {
+			t.Errorf("migration %d: sqlite %s, postgres %s", i, sqlite[i].name, postgres[i].name)
+		}
+		if strings.Contains(strings.ToUpper(postgres[i].sql), "AUTOINCREMENT") {
+			t.Errorf("%s: AUTOINCREMENT in postgres migration", postgres[i].name)
+		}
+	}
+}
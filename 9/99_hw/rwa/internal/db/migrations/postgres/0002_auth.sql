@@ -0,0 +1,18 @@
+-- Пароли хранятся хешами, сессии - по jti токена со сроком действия,
+-- отозванные токены - в revoked_tokens до истечения.
+-- Пароли, сохранённые открытым текстом, после этой миграции не подходят: их надо задать заново.
+
+ALTER TABLE users RENAME COLUMN password TO password_hash;
+
+DROP TABLE sessions;
+
+CREATE TABLE sessions (
+	id         TEXT PRIMARY KEY,
+	user_id    BIGINT NOT NULL REFERENCES users (id),
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE revoked_tokens (
+	id         TEXT PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,62 @@
+-- Начальная схема RealWorld для SQLite.
+
+CREATE TABLE users (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	username   TEXT NOT NULL UNIQUE,
+	email      TEXT NOT NULL UNIQUE,
+	password   TEXT NOT NULL,
+	bio        TEXT NOT NULL DEFAULT '',
+	image      TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE sessions (
+	token   TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users (id)
+);
+
+-- slug заполняется сразу после вставки: в нём участвует id статьи
+CREATE TABLE articles (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	slug        TEXT UNIQUE,
+	title       TEXT NOT NULL,
+	description TEXT NOT NULL,
+	body        TEXT NOT NULL,
+	author_id   INTEGER NOT NULL REFERENCES users (id),
+	created_at  DATETIME NOT NULL,
+	updated_at  DATETIME NOT NULL
+);
+
+-- position сохраняет порядок тегов, в котором их прислали
+CREATE TABLE article_tags (
+	article_id INTEGER NOT NULL REFERENCES articles (id),
+	position   INTEGER NOT NULL,
+	tag        TEXT NOT NULL,
+	PRIMARY KEY (article_id, position)
+);
+
+CREATE INDEX article_tags_tag ON article_tags (tag);
+
+CREATE TABLE follows (
+	follower_id INTEGER NOT NULL REFERENCES users (id),
+	followee_id INTEGER NOT NULL REFERENCES users (id),
+	PRIMARY KEY (follower_id, followee_id)
+);
+
+CREATE TABLE favorites (
+	article_id INTEGER NOT NULL REFERENCES articles (id),
+	user_id    INTEGER NOT NULL REFERENCES users (id),
+	PRIMARY KEY (article_id, user_id)
+);
+
+CREATE TABLE comments (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	article_id INTEGER NOT NULL REFERENCES articles (id),
+	author_id  INTEGER NOT NULL REFERENCES users (id),
+	body       TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX comments_article ON comments (article_id);
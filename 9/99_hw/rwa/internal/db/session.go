@@ -1,63 +1,67 @@
 package db
 
 import (
-	"fmt"
 	"sync"
+	"time"
 )
 
-// Session представляет данные сессии пользователя.
+// Session представляет данные сессии пользователя. ID совпадает с jti выданного токена,
+// сам токен не хранится.
 type Session struct {
-	ID     string `json:"id"`
-	UserID string `json:"userId"`
-	Token  string `json:"token"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 // SessionDB — простое in-memory хранилище сессий.
 type SessionDB struct {
-	Data map[string]*Session
+	data map[string]*Session
 	mu   sync.Mutex
 }
 
 // NewSessionDB создаёт новое хранилище сессий.
 func NewSessionDB() *SessionDB {
 	return &SessionDB{
-		Data: make(map[string]*Session),
+		data: make(map[string]*Session),
 	}
 }
 
-// CreateSession генерирует и сохраняет сессию для данного userID.
-func (sdb *SessionDB) CreateSession(userID string) (*Session, error) {
+// CreateSession сохраняет сессию; заодно выбрасывает истёкшие.
+func (sdb *SessionDB) CreateSession(sess *Session) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
 
-	token := fmt.Sprintf("token_for_user_%s", userID)
-	sess := &Session{
-		ID:     token,
-		UserID: userID,
-		Token:  token,
+	now := time.Now()
+	for id, s := range sdb.data {
+		if !s.ExpiresAt.After(now) {
+			delete(sdb.data, id)
+		}
 	}
-	sdb.Data[token] = sess
-	return sess, nil
+	stored := *sess
+	sdb.data[sess.ID] = &stored
 }
 
-// DeleteSession удаляет сессию по токену.
-func (sdb *SessionDB) DeleteSession(token string) error {
+// DeleteSession удаляет сессию по идентификатору.
+func (sdb *SessionDB) DeleteSession(id string) bool {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
-	if _, ok := sdb.Data[token]; !ok {
-		return fmt.Errorf("session not found")
+
+	if _, ok := sdb.data[id]; !ok {
+		return false
 	}
-	delete(sdb.Data, token)
-	return nil
+	delete(sdb.data, id)
+	return true
 }
 
-// GetSessionByToken возвращает сессию по токену.
-func (sdb *SessionDB) GetSessionByToken(token string) (*Session, error) {
+// GetSession возвращает действующую сессию по идентификатору.
+func (sdb *SessionDB) GetSession(id string) (*Session, bool) {
 	sdb.mu.Lock()
 	defer sdb.mu.Unlock()
-	sess, ok := sdb.Data[token]
-	if !ok {
-		return nil, fmt.Errorf("session not found")
+
+	sess, ok := sdb.data[id]
+	if !ok || !sess.ExpiresAt.After(time.Now()) {
+		return nil, false
 	}
-	return sess, nil
+	found := *sess
+	return &found, true
 }
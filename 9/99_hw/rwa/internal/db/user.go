@@ -7,25 +7,27 @@ import (
 )
 
 type User struct {
-	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`   // не возвращается в JSON
-	Bio       string    `json:"bio"` // поле для описания
-	Image     string    `json:"image"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`   // argon2id, см. auth.HashPassword; не возвращается в JSON
+	Bio          string    `json:"bio"` // поле для описания
+	Image        string    `json:"image"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
 }
 
+// UserDB - in-memory хранилище пользователей.
+// Наружу отдаются копии, чтобы никто не менял записи в обход мьютекса.
 type UserDB struct {
-	Data   map[string]*User
+	data   map[string]*User
 	nextID int
 	mu     sync.Mutex
 }
 
 func NewUserDB() *UserDB {
 	return &UserDB{
-		Data:   make(map[string]*User),
+		data:   make(map[string]*User),
 		nextID: 1,
 	}
 }
@@ -41,6 +43,56 @@ func (udb *UserDB) CreateUser(user *User) *User {
 	user.CreatedAt = time.Now().UTC()
 	user.UpdatedAt = user.CreatedAt
 
-	udb.Data[user.ID] = user
+	stored := *user
+	udb.data[user.ID] = &stored
 	return user
 }
+
+// UpdateUser перезаписывает пользователя с тем же ID.
+func (udb *UserDB) UpdateUser(user *User) (*User, bool) {
+	udb.mu.Lock()
+	defer udb.mu.Unlock()
+
+	if _, ok := udb.data[user.ID]; !ok {
+		return nil, false
+	}
+	stored := *user
+	udb.data[user.ID] = &stored
+	return user, true
+}
+
+// GetUserByID ищет пользователя по идентификатору.
+func (udb *UserDB) GetUserByID(id string) (*User, bool) {
+	udb.mu.Lock()
+	defer udb.mu.Unlock()
+
+	user, ok := udb.data[id]
+	if !ok {
+		return nil, false
+	}
+	found := *user
+	return &found, true
+}
+
+// GetUserByEmail ищет пользователя по email.
+func (udb *UserDB) GetUserByEmail(email string) (*User, bool) {
+	return udb.find(func(u *User) bool { return u.Email == email })
+}
+
+// GetUserByUsername ищет пользователя по имени.
+func (udb *UserDB) GetUserByUsername(username string) (*User, bool) {
+	return udb.find(func(u *User) bool { return u.Username == username })
+}
+
+func (udb *UserDB) find(match func(*User) bool) (*User, bool) {
+	udb.mu.Lock()
+	defer udb.mu.Unlock()
+
+	for _, user := range udb.data {
+		if match(user) {
+			found := *user
+			return &found, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"rwa/internal/auth"
+	"rwa/internal/db"
+	"rwa/internal/repository"
+)
+
+// Auth выдаёт токены при логине и проверяет их в middleware.
+// Токен - JWT с jti = ID сессии; действующим он считается, пока подпись и срок в порядке,
+// сессия есть в хранилище и jti не попал в denylist при логауте.
+type Auth struct {
+	tokens   *auth.Tokens
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	denylist repository.DenylistRepository
+}
+
+func NewAuth(repos *repository.Repositories, tokens *auth.Tokens) *Auth {
+	return &Auth{
+		tokens:   tokens,
+		users:    repos.Users,
+		sessions: repos.Sessions,
+		denylist: repos.Denylist,
+	}
+}
+
+// authInfo - результат проверки токена, middleware кладёт его в контекст запроса.
+type authInfo struct {
+	user    *db.User
+	session *db.Session
+	token   string
+}
+
+type authKey struct{}
+
+var errNoToken = errors.New("authorization token missing")
+
+// Login заводит сессию пользователю и возвращает подписанный токен для неё.
+func (a *Auth) Login(userID string) (string, error) {
+	sessionID, err := auth.NewSessionID()
+	if err != nil {
+		return "", err
+	}
+	token, expiresAt, err := a.tokens.Issue(sessionID, userID)
+	if err != nil {
+		return "", err
+	}
+	sess := &db.Session{ID: sessionID, UserID: userID, ExpiresAt: expiresAt}
+	if err := a.sessions.Create(sess); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Logout отзывает токен текущего запроса: jti уходит в denylist до истечения токена, сессия удаляется.
+func (a *Auth) Logout(r *http.Request) error {
+	info := authFromContext(r.Context())
+	if info == nil {
+		return errNoToken
+	}
+	if err := a.denylist.Revoke(info.session.ID, info.session.ExpiresAt); err != nil {
+		return err
+	}
+	if err := a.sessions.Delete(info.session.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Required пропускает запрос дальше только с действующим токеном.
+func (a *Auth) Required(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := a.authenticate(r)
+		if err != nil {
+			a.reject(w, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), authKey{}, info)))
+	}
+}
+
+// Optional пускает и анонимов, но присланный токен должен быть действующим.
+func (a *Auth) Optional(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := a.authenticate(r)
+		if errors.Is(err, errNoToken) {
+			next(w, r)
+			return
+		}
+		if err != nil {
+			a.reject(w, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), authKey{}, info)))
+	}
+}
+
+func (a *Auth) reject(w http.ResponseWriter, err error) {
+	var storageErr *storageError
+	if errors.As(err, &storageErr) {
+		internalError(w, storageErr.err)
+		return
+	}
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// storageError отличает сбой хранилища (500) от плохого токена (401).
+type storageError struct {
+	err error
+}
+
+func (e *storageError) Error() string { return e.err.Error() }
+
+// authenticate разбирает заголовок "Authorization: Token <jwt>".
+// errNoToken означает, что заголовка нет вовсе - для публичных эндпоинтов это не ошибка.
+func (a *Auth) authenticate(r *http.Request) (*authInfo, error) {
+	authHeader := r.Header.Get("Authorization")
+	const tokenPrefix = "Token "
+	if authHeader == "" || !strings.HasPrefix(authHeader, tokenPrefix) {
+		return nil, errNoToken
+	}
+	token := strings.TrimPrefix(authHeader, tokenPrefix)
+
+	claims, err := a.tokens.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := a.denylist.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, &storageError{err}
+	}
+	if revoked {
+		return nil, errors.New("token revoked")
+	}
+
+	session, err := a.sessions.Get(claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		return nil, &storageError{err}
+	}
+	if session.UserID != claims.Subject {
+		log.Printf("session %s belongs to user %s, token says %s", session.ID, session.UserID, claims.Subject)
+		return nil, errors.New("session user mismatch")
+	}
+
+	user, err := a.users.FindByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		return nil, &storageError{err}
+	}
+	return &authInfo{user: user, session: session, token: token}, nil
+}
+
+func authFromContext(ctx context.Context) *authInfo {
+	info, _ := ctx.Value(authKey{}).(*authInfo)
+	return info
+}
+
+// currentUser - пользователь, которого пропустил middleware; nil для анонимного запроса.
+func currentUser(r *http.Request) *db.User {
+	if info := authFromContext(r.Context()); info != nil {
+		return info.user
+	}
+	return nil
+}
+
+// requireUser - для методов, которым за Optional-middleware всё же нужен пользователь.
+func requireUser(w http.ResponseWriter, r *http.Request) (*db.User, bool) {
+	user := currentUser(r)
+	if user == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"rwa/internal/db"
+	"rwa/internal/repository"
+)
+
+// CommentHandler обрабатывает комментарии к статьям.
+type CommentHandler struct {
+	commentRepo repository.CommentRepository
+	articleRepo repository.ArticleRepository
+	userRepo    repository.UserRepository
+	followRepo  repository.FollowRepository
+}
+
+func NewCommentHandler(repos *repository.Repositories) *CommentHandler {
+	return &CommentHandler{
+		commentRepo: repos.Comments,
+		articleRepo: repos.Articles,
+		userRepo:    repos.Users,
+		followRepo:  repos.Follows,
+	}
+}
+
+// CommentsHandler - список (GET) и создание (POST) комментариев: /api/articles/{slug}/comments.
+func (h *CommentHandler) CommentsHandler(w http.ResponseWriter, r *http.Request) {
+	article, ok := findArticle(w, h.articleRepo, r.PathValue("slug"))
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.listComments(w, r, article)
+	case http.MethodPost:
+		h.createComment(w, r, article)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// CommentHandler удаляет комментарий (DELETE): /api/articles/{slug}/comments/{id}.
+// Удалить комментарий может только его автор.
+func (h *CommentHandler) CommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	viewer, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	article, ok := findArticle(w, h.articleRepo, r.PathValue("slug"))
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+	comment, err := h.commentRepo.GetByID(id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && comment.ArticleID != article.ID) {
+		http.Error(w, "comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if comment.AuthorID != viewer.ID {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if err := h.commentRepo.Delete(id); err != nil {
+		internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *CommentHandler) listComments(w http.ResponseWriter, r *http.Request, article *db.Article) {
+	viewer := currentUser(r)
+	comments, err := h.commentRepo.GetByArticle(article.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	responseComments := make([]interface{}, 0, len(comments))
+	for _, c := range comments {
+		commentResp, err := h.commentResponse(c, viewer)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		responseComments = append(responseComments, commentResp)
+	}
+	writeJSON(w, map[string]interface{}{"comments": responseComments}, http.StatusOK)
+}
+
+func (h *CommentHandler) createComment(w http.ResponseWriter, r *http.Request, article *db.Article) {
+	viewer, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	// Ожидается JSON вида: {"comment": {"body": "..."}}
+	var payload struct {
+		Comment struct {
+			Body string `json:"body"`
+		} `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if payload.Comment.Body == "" {
+		http.Error(w, "comment body is empty", http.StatusUnprocessableEntity)
+		return
+	}
+
+	created, err := h.commentRepo.Create(&db.Comment{
+		ArticleID: article.ID,
+		AuthorID:  viewer.ID,
+		Body:      payload.Comment.Body,
+	})
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	commentResp, err := h.commentResponse(created, viewer)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"comment": commentResp}, http.StatusOK)
+}
+
+func (h *CommentHandler) commentResponse(c *db.Comment, viewer *db.User) (map[string]interface{}, error) {
+	authorResp, err := authorResponse(h.userRepo, h.followRepo, c.AuthorID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"id":        c.ID,
+		"body":      c.Body,
+		"createdAt": c.CreatedAt.Format(time.RFC3339),
+		"updatedAt": c.UpdatedAt.Format(time.RFC3339),
+		"author":    authorResp,
+	}, nil
+}
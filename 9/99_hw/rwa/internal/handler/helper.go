@@ -2,17 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-)
+	"strconv"
 
-// jsonResponse выводит данные в формате JSON.
-func jsonResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		fmt.Printf("error encoding response: %v", err)
-	}
-}
+	"rwa/internal/db"
+	"rwa/internal/repository"
+)
 
 func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -21,3 +19,70 @@ func writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 		fmt.Printf("error encoding JSON response: %v", err)
 	}
 }
+
+// internalError - ошибка хранилища: подробности в лог, клиенту 500.
+func internalError(w http.ResponseWriter, err error) {
+	log.Printf("storage error: %v", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+// profileResponse - профиль автора в ответах API: без email и токена, с флагом подписки.
+func profileResponse(user *db.User, following bool) map[string]interface{} {
+	return map[string]interface{}{
+		"username":  user.Username,
+		"bio":       user.Bio,
+		"image":     user.Image,
+		"following": following,
+	}
+}
+
+// authorResponse - профиль автора с точки зрения viewer (может быть nil); пропавший автор - пустой профиль.
+func authorResponse(ur repository.UserRepository, fr repository.FollowRepository, authorID string, viewer *db.User) (map[string]interface{}, error) {
+	author, err := ur.FindByID(authorID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return profileResponse(&db.User{}, false), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	following, err := isFollowing(fr, viewer, author)
+	if err != nil {
+		return nil, err
+	}
+	return profileResponse(author, following), nil
+}
+
+// isFollowing - подписан ли viewer на author; анонимный viewer ни на кого не подписан.
+func isFollowing(fr repository.FollowRepository, viewer, author *db.User) (bool, error) {
+	if viewer == nil {
+		return false, nil
+	}
+	return fr.IsFollowing(viewer.ID, author.ID)
+}
+
+// findArticle ищет статью по slug; если её нет или хранилище сломалось, сам пишет ответ и возвращает false.
+func findArticle(w http.ResponseWriter, ar repository.ArticleRepository, slug string) (*db.Article, bool) {
+	article, err := ar.GetBySlug(slug)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "article not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		internalError(w, err)
+		return nil, false
+	}
+	return article, true
+}
+
+// queryInt читает неотрицательный числовой параметр запроса, def - если параметра нет.
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return n, nil
+}
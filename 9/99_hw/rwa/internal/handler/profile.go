@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"rwa/internal/db"
+	"rwa/internal/repository"
+)
+
+// ProfileHandler обрабатывает запросы к профилям пользователей и подпискам.
+type ProfileHandler struct {
+	userRepo   repository.UserRepository
+	followRepo repository.FollowRepository
+}
+
+func NewProfileHandler(repos *repository.Repositories) *ProfileHandler {
+	return &ProfileHandler{userRepo: repos.Users, followRepo: repos.Follows}
+}
+
+// ProfileHandler отдаёт профиль пользователя: GET /api/profiles/{username}.
+// Авторизация необязательна, с ней в ответе заполняется флаг following.
+func (h *ProfileHandler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	viewer := currentUser(r)
+	profile, ok := h.findProfile(w, r.PathValue("username"))
+	if !ok {
+		return
+	}
+	h.writeProfile(w, profile, viewer)
+}
+
+// FollowHandler подписывает (POST) или отписывает (DELETE) текущего пользователя: /api/profiles/{username}/follow.
+func (h *ProfileHandler) FollowHandler(w http.ResponseWriter, r *http.Request) {
+	viewer, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	profile, ok := h.findProfile(w, r.PathValue("username"))
+	if !ok {
+		return
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		if profile.ID == viewer.ID {
+			http.Error(w, "cannot follow yourself", http.StatusUnprocessableEntity)
+			return
+		}
+		err = h.followRepo.Follow(viewer.ID, profile.ID)
+	case http.MethodDelete:
+		err = h.followRepo.Unfollow(viewer.ID, profile.ID)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	h.writeProfile(w, profile, viewer)
+}
+
+func (h *ProfileHandler) findProfile(w http.ResponseWriter, username string) (*db.User, bool) {
+	profile, err := h.userRepo.FindByUsername(username)
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "profile not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		internalError(w, err)
+		return nil, false
+	}
+	return profile, true
+}
+
+func (h *ProfileHandler) writeProfile(w http.ResponseWriter, profile, viewer *db.User) {
+	following, err := isFollowing(h.followRepo, viewer, profile)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	response := map[string]interface{}{
+		"profile": profileResponse(profile, following),
+	}
+	writeJSON(w, response, http.StatusOK)
+}
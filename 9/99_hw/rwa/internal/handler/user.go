@@ -2,22 +2,22 @@ package handler
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
-	"strings"
 	"time"
 
+	"rwa/internal/auth"
 	"rwa/internal/db"
 	"rwa/internal/repository"
 )
 
 type UserHandler struct {
-	userRepo    *repository.UserRepo
-	sessionRepo *repository.SessionRepo
+	userRepo repository.UserRepository
+	auth     *Auth
 }
 
-func NewUserHandler(u *repository.UserRepo, s *repository.SessionRepo) *UserHandler {
-	return &UserHandler{userRepo: u, sessionRepo: s}
+func NewUserHandler(repos *repository.Repositories, a *Auth) *UserHandler {
+	return &UserHandler{userRepo: repos.Users, auth: a}
 }
 
 // UsersHandler обрабатывает запросы, связанные с пользователями.
@@ -50,22 +50,42 @@ func (h *UserHandler) registerUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// email и имя уникальны: в SQL-схеме на них UNIQUE, in-memory ведёт себя так же
+	if taken, err := h.taken(payload.User.Email, payload.User.Username); err != nil {
+		internalError(w, err)
+		return
+	} else if taken {
+		http.Error(w, "email or username already taken", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// В базе хранится только хеш пароля
+	hash, err := auth.HashPassword(payload.User.Password)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
 	// Создание нового пользователя
 	newUser := &db.User{
-		Username:  payload.User.Username,
-		Email:     payload.User.Email,
-		Password:  payload.User.Password,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		Bio:       "",
-		Image:     "",
+		Username:     payload.User.Username,
+		Email:        payload.User.Email,
+		PasswordHash: hash,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+		Bio:          "",
+		Image:        "",
 	}
 
-	created := h.userRepo.Create(newUser)
+	created, err := h.userRepo.Create(newUser)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
 
 	// Создаем сессию для вновь зарегистрированного пользователя,
 	// чтобы вернуть токен в ответе
-	session, err := h.sessionRepo.Create(created.ID)
+	token, err := h.auth.Login(created.ID)
 	if err != nil {
 		http.Error(w, "failed to create session", http.StatusInternalServerError)
 		return
@@ -77,7 +97,7 @@ func (h *UserHandler) registerUser(w http.ResponseWriter, r *http.Request) {
 			"username":  created.Username,
 			"bio":       created.Bio,
 			"image":     created.Image,
-			"token":     session.Token,
+			"token":     token,
 			"createdAt": created.CreatedAt.Format(time.RFC3339),
 			"updatedAt": created.UpdatedAt.Format(time.RFC3339),
 		},
@@ -108,19 +128,22 @@ func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Поиск пользователя по email через репозиторий
 	user, err := h.userRepo.FindByEmail(payload.User.Email)
-	if err != nil || user == nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	// Простая проверка пароля (в реальном приложении следует использовать хеширование)
-	if user.Password != payload.User.Password {
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	// Сверяем пароль с хешем из базы
+	if !auth.CheckPassword(user.PasswordHash, payload.User.Password) {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	// Создаем сессию через SessionRepo, которая возвращает объект сессии с полем Token
-	session, err := h.sessionRepo.Create(user.ID)
-	log.Println("LOGIN USER:", user.ID, "TOKEN:", session.Token)
+	// Заводим новую сессию и подписываем для неё токен
+	token, err := h.auth.Login(user.ID)
 	if err != nil {
 		http.Error(w, "failed to create session", http.StatusInternalServerError)
 		return
@@ -133,7 +156,7 @@ func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 			"username":  user.Username,
 			"bio":       user.Bio,
 			"image":     user.Image,
-			"token":     session.Token,
+			"token":     token,
 			"createdAt": user.CreatedAt.Format(time.RFC3339),
 			"updatedAt": user.UpdatedAt.Format(time.RFC3339),
 		},
@@ -143,29 +166,14 @@ func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, response, http.StatusOK)
 }
 
+// CurrentHandler работает за Auth.Required: токен уже проверен, пользователь лежит в контексте.
 func (h *UserHandler) CurrentHandler(w http.ResponseWriter, r *http.Request) {
-	// Извлекаем заголовок Authorization; ожидается формат "Token <value>"
-	authHeader := r.Header.Get("Authorization")
-	const tokenPrefix = "Token "
-	if authHeader == "" || !strings.HasPrefix(authHeader, tokenPrefix) {
-		http.Error(w, "authorization token missing or invalid", http.StatusUnauthorized)
-		return
-	}
-	token := strings.TrimPrefix(authHeader, tokenPrefix)
-
-	// Получаем сессию по токену
-	session, err := h.sessionRepo.GetByToken(token)
-	if err != nil {
-		http.Error(w, "invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Находим пользователя по идентификатору, сохранённому в сессии
-	user, err := h.userRepo.FindByID(session.UserID)
-	if err != nil {
-		http.Error(w, "user not found", http.StatusUnauthorized)
+	info := authFromContext(r.Context())
+	if info == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
+	user := info.user
 
 	// Обработка GET и PUT-запросов по одному эндпоинту /api/user
 	switch r.Method {
@@ -196,13 +204,24 @@ func (h *UserHandler) CurrentHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		// Обновляем поля пользователя, если они непустые
-		if payload.User.Email != "" {
+		if payload.User.Email != "" && payload.User.Email != user.Email {
+			if taken, err := h.taken(payload.User.Email, ""); err != nil {
+				internalError(w, err)
+				return
+			} else if taken {
+				http.Error(w, "email already taken", http.StatusUnprocessableEntity)
+				return
+			}
 			user.Email = payload.User.Email
 		}
 		if payload.User.Bio != "" {
 			user.Bio = payload.User.Bio
 		}
 		user.UpdatedAt = time.Now().UTC()
+		if _, err := h.userRepo.Update(user); err != nil {
+			internalError(w, err)
+			return
+		}
 
 		// Для обеспечения корректной авторизации последующих запросов возвращаем в ответе
 		// сессионный токен (чтобы тестовый After-функция обновила значение токена).
@@ -210,7 +229,7 @@ func (h *UserHandler) CurrentHandler(w http.ResponseWriter, r *http.Request) {
 			"user": map[string]interface{}{
 				"email":     user.Email,
 				"username":  user.Username,
-				"token":     session.Token, // возвращаем исходный токен,
+				"token":     info.token, // возвращаем исходный токен,
 				"bio":       user.Bio,
 				"image":     user.Image,
 				"createdAt": user.CreatedAt.Format(time.RFC3339),
@@ -223,22 +242,34 @@ func (h *UserHandler) CurrentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// LogoutHandler обрабатывает выход пользователя.
+// LogoutHandler обрабатывает выход пользователя: токен отзывается, сессия удаляется.
 func (h *UserHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	// Извлекаем заголовок Authorization, ожидается "Token <value>"
-	authHeader := r.Header.Get("Authorization")
-	const tokenPrefix = "Token "
-	if authHeader == "" || !strings.HasPrefix(authHeader, tokenPrefix) {
+	if err := h.auth.Logout(r); errors.Is(err, errNoToken) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
-	}
-	token := strings.TrimPrefix(authHeader, tokenPrefix)
-
-	// Удаляем сессию по токену
-	if err := h.sessionRepo.Delete(token); err != nil {
+	} else if err != nil {
 		http.Error(w, "Failed to logout", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// taken проверяет, занят ли email или имя пользователя; пустые значения не проверяются.
+func (h *UserHandler) taken(email, username string) (bool, error) {
+	if email != "" {
+		if _, err := h.userRepo.FindByEmail(email); err == nil {
+			return true, nil
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return false, err
+		}
+	}
+	if username != "" {
+		if _, err := h.userRepo.FindByUsername(username); err == nil {
+			return true, nil
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return false, err
+		}
+	}
+	return false, nil
+}
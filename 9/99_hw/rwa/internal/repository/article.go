@@ -1,31 +1,103 @@
 package repository
 
-import "rwa/internal/db"
+import (
+	"sort"
+
+	"rwa/internal/db"
+)
 
 type ArticleRepo struct {
-	db *db.ArticleDB
+	db        *db.ArticleDB
+	follows   *db.FollowDB
+	favorites *db.FavoriteDB
+	comments  *db.CommentDB
+}
+
+func NewArticleRepo(articles *db.ArticleDB, follows *db.FollowDB, favorites *db.FavoriteDB, comments *db.CommentDB) *ArticleRepo {
+	return &ArticleRepo{db: articles, follows: follows, favorites: favorites, comments: comments}
+}
+
+func (r *ArticleRepo) Create(article *db.Article) (*db.Article, error) {
+	return r.db.CreateArticle(article), nil
+}
+
+func (r *ArticleRepo) List(filter ArticleFilter) ([]*db.Article, int, error) {
+	var following map[string]bool
+	if filter.FollowedBy != "" {
+		following = make(map[string]bool)
+		for _, id := range r.follows.GetFollowing(filter.FollowedBy) {
+			following[id] = true
+		}
+	}
+
+	matched := make([]*db.Article, 0)
+	for _, art := range r.db.GetAllArticles() {
+		switch {
+		case filter.AuthorID != "" && art.AuthorID != filter.AuthorID,
+			filter.Tag != "" && !hasTag(art, filter.Tag),
+			filter.FavoritedBy != "" && !r.favorites.IsFavorited(art.ID, filter.FavoritedBy),
+			following != nil && !following[art.AuthorID]:
+			continue
+		}
+		matched = append(matched, art)
+	}
+
+	total := len(matched)
+	return page(matched, filter.Limit, filter.Offset), total, nil
 }
 
-func NewArticleRepo(db *db.ArticleDB) *ArticleRepo {
-	return &ArticleRepo{db: db}
+func (r *ArticleRepo) Tags() ([]string, error) {
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	for _, art := range r.db.GetAllArticles() {
+		for _, tag := range art.TagList {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
 }
 
-func (r *ArticleRepo) Create(article *db.Article) *db.Article {
-	return r.db.CreateArticle(article)
+func (r *ArticleRepo) GetBySlug(slug string) (*db.Article, error) {
+	return found(r.db.GetArticleBySlug(slug))
 }
 
-func (r *ArticleRepo) GetAll() []*db.Article {
-	return r.db.GetAllArticles()
+func (r *ArticleRepo) Update(slug string, updated *db.Article) (*db.Article, error) {
+	return found(r.db.UpdateArticle(slug, updated))
 }
 
-func (r *ArticleRepo) GetBySlug(slug string) (*db.Article, bool) {
-	return r.db.GetArticleBySlug(slug)
+// Delete убирает статью первой: после этого её комментарии и избранное
+// уже недостижимы, даже если кто-то читает их между шагами.
+func (r *ArticleRepo) Delete(slug string) error {
+	art, ok := r.db.DeleteArticle(slug)
+	if !ok {
+		return ErrNotFound
+	}
+	r.favorites.DeleteArticle(art.ID)
+	r.comments.DeleteArticleComments(art.ID)
+	return nil
 }
 
-func (r *ArticleRepo) Update(slug string, updated *db.Article) (*db.Article, bool) {
-	return r.db.UpdateArticle(slug, updated)
+func hasTag(art *db.Article, tag string) bool {
+	for _, t := range art.TagList {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *ArticleRepo) Delete(slug string) bool {
-	return r.db.DeleteArticle(slug)
+// page вырезает страницу limit/offset. limit сначала ограничивается остатком списка:
+// offset+limit при огромном limit переполнил бы int.
+func page(articles []*db.Article, limit, offset int) []*db.Article {
+	if offset > len(articles) {
+		offset = len(articles)
+	}
+	if rest := len(articles) - offset; limit > rest {
+		limit = rest
+	}
+	return articles[offset : offset+limit]
 }
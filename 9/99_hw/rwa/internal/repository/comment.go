@@ -0,0 +1,30 @@
+package repository
+
+import "rwa/internal/db"
+
+type CommentRepo struct {
+	db *db.CommentDB
+}
+
+func NewCommentRepo(db *db.CommentDB) *CommentRepo {
+	return &CommentRepo{db: db}
+}
+
+func (r *CommentRepo) Create(comment *db.Comment) (*db.Comment, error) {
+	return r.db.CreateComment(comment), nil
+}
+
+func (r *CommentRepo) GetByArticle(articleID int) ([]*db.Comment, error) {
+	return r.db.GetCommentsByArticle(articleID), nil
+}
+
+func (r *CommentRepo) GetByID(id int) (*db.Comment, error) {
+	return found(r.db.GetCommentByID(id))
+}
+
+func (r *CommentRepo) Delete(id int) error {
+	if !r.db.DeleteComment(id) {
+		return ErrNotFound
+	}
+	return nil
+}
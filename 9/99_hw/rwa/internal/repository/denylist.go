@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"time"
+
+	"rwa/internal/db"
+)
+
+type DenylistRepo struct {
+	db *db.DenylistDB
+}
+
+func NewDenylistRepo(db *db.DenylistDB) *DenylistRepo {
+	return &DenylistRepo{db: db}
+}
+
+func (r *DenylistRepo) Revoke(id string, expiresAt time.Time) error {
+	r.db.Revoke(id, expiresAt)
+	return nil
+}
+
+func (r *DenylistRepo) IsRevoked(id string) (bool, error) {
+	return r.db.IsRevoked(id), nil
+}
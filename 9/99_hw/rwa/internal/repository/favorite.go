@@ -0,0 +1,29 @@
+package repository
+
+import "rwa/internal/db"
+
+type FavoriteRepo struct {
+	db *db.FavoriteDB
+}
+
+func NewFavoriteRepo(db *db.FavoriteDB) *FavoriteRepo {
+	return &FavoriteRepo{db: db}
+}
+
+func (r *FavoriteRepo) Favorite(articleID int, userID string) error {
+	r.db.Favorite(articleID, userID)
+	return nil
+}
+
+func (r *FavoriteRepo) Unfavorite(articleID int, userID string) error {
+	r.db.Unfavorite(articleID, userID)
+	return nil
+}
+
+func (r *FavoriteRepo) IsFavorited(articleID int, userID string) (bool, error) {
+	return r.db.IsFavorited(articleID, userID), nil
+}
+
+func (r *FavoriteRepo) Count(articleID int) (int, error) {
+	return r.db.Count(articleID), nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import "rwa/internal/db"
+
+type FollowRepo struct {
+	db *db.FollowDB
+}
+
+func NewFollowRepo(db *db.FollowDB) *FollowRepo {
+	return &FollowRepo{db: db}
+}
+
+func (r *FollowRepo) Follow(followerID, followeeID string) error {
+	r.db.Follow(followerID, followeeID)
+	return nil
+}
+
+func (r *FollowRepo) Unfollow(followerID, followeeID string) error {
+	r.db.Unfollow(followerID, followeeID)
+	return nil
+}
+
+func (r *FollowRepo) IsFollowing(followerID, followeeID string) (bool, error) {
+	return r.db.IsFollowing(followerID, followeeID), nil
+}
+
+// Following возвращает идентификаторы пользователей, на которых подписан followerID.
+func (r *FollowRepo) Following(followerID string) ([]string, error) {
+	return r.db.GetFollowing(followerID), nil
+}
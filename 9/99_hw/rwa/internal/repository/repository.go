@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"rwa/internal/db"
+)
+
+// Хендлеры работают только с интерфейсами ниже. Реализаций две:
+// in-memory (UserRepo, ArticleRepo, ... поверх пакета db) и SQL (SQLUserRepo, ... поверх database/sql).
+
+// ErrNotFound возвращают все реализации, когда записи нет.
+var ErrNotFound = errors.New("not found")
+
+type UserRepository interface {
+	Create(user *db.User) (*db.User, error)
+	Update(user *db.User) (*db.User, error)
+	FindByID(id string) (*db.User, error)
+	FindByEmail(email string) (*db.User, error)
+	FindByUsername(username string) (*db.User, error)
+}
+
+type SessionRepository interface {
+	Create(sess *db.Session) error
+	// Get возвращает только действующую сессию: истёкшая - ErrNotFound.
+	Get(id string) (*db.Session, error)
+	Delete(id string) error
+}
+
+// DenylistRepository - отозванные токены; запись нужна только до истечения самого токена.
+type DenylistRepository interface {
+	Revoke(id string, expiresAt time.Time) error
+	IsRevoked(id string) (bool, error)
+}
+
+// ArticleFilter - условия выборки для ArticleRepository.List; пустые поля выборку не ограничивают.
+type ArticleFilter struct {
+	AuthorID    string // только статьи этого автора
+	Tag         string // только статьи с этим тегом
+	FavoritedBy string // только статьи из избранного этого пользователя
+	FollowedBy  string // только статьи авторов, на которых подписан этот пользователь (лента)
+	Limit       int
+	Offset      int
+}
+
+type ArticleRepository interface {
+	Create(article *db.Article) (*db.Article, error)
+	// List возвращает страницу подходящих под filter статей в порядке создания
+	// и общее число подходящих статей без учёта Limit и Offset.
+	List(filter ArticleFilter) ([]*db.Article, int, error)
+	// Tags возвращает все теги статей без повторов, по алфавиту.
+	Tags() ([]string, error)
+	GetBySlug(slug string) (*db.Article, error)
+	Update(slug string, updated *db.Article) (*db.Article, error)
+	// Delete удаляет статью вместе с её тегами, комментариями и отметками "в избранном"
+	// одной операцией (в SQL - одной транзакцией).
+	Delete(slug string) error
+}
+
+type FollowRepository interface {
+	Follow(followerID, followeeID string) error
+	Unfollow(followerID, followeeID string) error
+	IsFollowing(followerID, followeeID string) (bool, error)
+	Following(followerID string) ([]string, error)
+}
+
+type FavoriteRepository interface {
+	Favorite(articleID int, userID string) error
+	Unfavorite(articleID int, userID string) error
+	IsFavorited(articleID int, userID string) (bool, error)
+	Count(articleID int) (int, error)
+}
+
+type CommentRepository interface {
+	Create(comment *db.Comment) (*db.Comment, error)
+	// GetByArticle возвращает комментарии статьи в порядке добавления.
+	GetByArticle(articleID int) ([]*db.Comment, error)
+	GetByID(id int) (*db.Comment, error)
+	Delete(id int) error
+}
+
+// Repositories - полный набор репозиториев приложения.
+type Repositories struct {
+	Users     UserRepository
+	Sessions  SessionRepository
+	Denylist  DenylistRepository
+	Articles  ArticleRepository
+	Follows   FollowRepository
+	Favorites FavoriteRepository
+	Comments  CommentRepository
+}
+
+// NewMemory собирает репозитории поверх in-memory хранилищ пакета db.
+func NewMemory() *Repositories {
+	// статьи фильтруются по подпискам и избранному и удаляются вместе с комментариями,
+	// поэтому их хранилища общие
+	articles, follows, favorites, comments := db.NewArticleDB(), db.NewFollowDB(), db.NewFavoriteDB(), db.NewCommentDB()
+	return &Repositories{
+		Users:     NewUserRepo(db.NewUserDB()),
+		Sessions:  NewSessionRepo(db.NewSessionDB()),
+		Denylist:  NewDenylistRepo(db.NewDenylistDB()),
+		Articles:  NewArticleRepo(articles, follows, favorites, comments),
+		Follows:   NewFollowRepo(follows),
+		Favorites: NewFavoriteRepo(favorites),
+		Comments:  NewCommentRepo(comments),
+	}
+}
+
+// NewSQL собирает репозитории поверх conn, предварительно применив миграции схемы.
+// Диалект (SQLite или PostgreSQL) определяется по драйверу conn.
+func NewSQL(conn *sql.DB) (*Repositories, error) {
+	dialect, err := db.DetectDialect(conn)
+	if err != nil {
+		return nil, err
+	}
+	return NewSQLDialect(conn, dialect)
+}
+
+// NewSQLDialect - NewSQL с явно заданным диалектом.
+func NewSQLDialect(conn *sql.DB, dialect db.Dialect) (*Repositories, error) {
+	if err := db.Migrate(conn, dialect); err != nil {
+		return nil, err
+	}
+	return &Repositories{
+		Users:     NewSQLUserRepo(conn, dialect),
+		Sessions:  NewSQLSessionRepo(conn, dialect),
+		Denylist:  NewSQLDenylistRepo(conn, dialect),
+		Articles:  NewSQLArticleRepo(conn, dialect),
+		Follows:   NewSQLFollowRepo(conn, dialect),
+		Favorites: NewSQLFavoriteRepo(conn, dialect),
+		Comments:  NewSQLCommentRepo(conn, dialect),
+	}, nil
+}
+
+var (
+	_ UserRepository     = (*UserRepo)(nil)
+	_ UserRepository     = (*SQLUserRepo)(nil)
+	_ SessionRepository  = (*SessionRepo)(nil)
+	_ SessionRepository  = (*SQLSessionRepo)(nil)
+	_ DenylistRepository = (*DenylistRepo)(nil)
+	_ DenylistRepository = (*SQLDenylistRepo)(nil)
+	_ ArticleRepository  = (*ArticleRepo)(nil)
+	_ ArticleRepository  = (*SQLArticleRepo)(nil)
+	_ FollowRepository   = (*FollowRepo)(nil)
+	_ FollowRepository   = (*SQLFollowRepo)(nil)
+	_ FavoriteRepository = (*FavoriteRepo)(nil)
+	_ FavoriteRepository = (*SQLFavoriteRepo)(nil)
+	_ CommentRepository  = (*CommentRepo)(nil)
+	_ CommentRepository  = (*SQLCommentRepo)(nil)
+)
@@ -1,9 +1,6 @@
 package repository
 
-import (
-	"errors"
-	"rwa/internal/db"
-)
+import "rwa/internal/db"
 
 type SessionRepo struct {
 	db *db.SessionDB
@@ -13,21 +10,21 @@ func NewSessionRepo(db *db.SessionDB) *SessionRepo {
 	return &SessionRepo{db: db}
 }
 
-// Create генерирует и сохраняет новую сессию для пользователя с заданным userID.
-func (r *SessionRepo) Create(userID string) (*db.Session, error) {
-	return r.db.CreateSession(userID)
+// Create сохраняет новую сессию.
+func (r *SessionRepo) Create(sess *db.Session) error {
+	r.db.CreateSession(sess)
+	return nil
 }
 
-// GetByToken ищет сессию по её токену.
-func (r *SessionRepo) GetByToken(token string) (*db.Session, error) {
-	sess, ok := r.db.Data[token]
-	if !ok {
-		return nil, errors.New("session not found")
-	}
-	return sess, nil
+// Get ищет действующую сессию по идентификатору.
+func (r *SessionRepo) Get(id string) (*db.Session, error) {
+	return found(r.db.GetSession(id))
 }
 
-// Delete удаляет сессию по токену.
-func (r *SessionRepo) Delete(token string) error {
-	return r.db.DeleteSession(token)
+// Delete удаляет сессию по идентификатору.
+func (r *SessionRepo) Delete(id string) error {
+	if !r.db.DeleteSession(id) {
+		return ErrNotFound
+	}
+	return nil
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"rwa/internal/db"
+)
+
+// Общее для SQL-реализаций. Идентификаторы пользователей в моделях - строки, в схеме - INTEGER:
+// строки передаются в запросы как есть, база приводит их к числу сама, а при чтении
+// database/sql сам переводит число в строку.
+
+// sqlDB - соединение вместе с диалектом. Запросы в репозиториях пишутся с плейсхолдерами "?",
+// перед выполнением диалект переводит их в плейсхолдеры базы.
+type sqlDB struct {
+	conn    *sql.DB
+	dialect db.Dialect
+}
+
+func (s *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.conn.Exec(s.dialect.Rebind(query), args...)
+}
+
+func (s *sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.conn.Query(s.dialect.Rebind(query), args...)
+}
+
+func (s *sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.conn.QueryRow(s.dialect.Rebind(query), args...)
+}
+
+// insertID выполняет INSERT и возвращает id новой строки
+func (s *sqlDB) insertID(query string, args ...interface{}) (int64, error) {
+	return s.dialect.InsertID(s.conn, s.dialect.Rebind(query), args...)
+}
+
+func (s *sqlDB) Begin() (*sqlTx, error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx, dialect: s.dialect}, nil
+}
+
+// sqlTx - транзакция с тем же переводом плейсхолдеров, что и sqlDB
+type sqlTx struct {
+	tx      *sql.Tx
+	dialect db.Dialect
+}
+
+func (t *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.Exec(t.dialect.Rebind(query), args...)
+}
+
+func (t *sqlTx) insertID(query string, args ...interface{}) (int64, error) {
+	return t.dialect.InsertID(t.tx, t.dialect.Rebind(query), args...)
+}
+
+func (t *sqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqlTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// rowScanner - общее у *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// notFound заменяет sql.ErrNoRows на ErrNotFound, остальные ошибки отдаёт как есть.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// affected превращает "ни одна строка не изменилась" в ErrNotFound.
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"rwa/internal/db"
+)
+
+type SQLArticleRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLArticleRepo(conn *sql.DB, dialect db.Dialect) *SQLArticleRepo {
+	return &SQLArticleRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+const articleColumns = `id, slug, title, description, body, author_id, created_at, updated_at`
+
+func scanArticle(row rowScanner) (*db.Article, error) {
+	art := &db.Article{}
+	err := row.Scan(&art.ID, &art.Slug, &art.Title, &art.Description, &art.Body, &art.AuthorID,
+		&art.CreatedAt, &art.UpdatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	art.CreatedAt, art.UpdatedAt = art.CreatedAt.UTC(), art.UpdatedAt.UTC()
+	return art, nil
+}
+
+func (r *SQLArticleRepo) Create(article *db.Article) (*db.Article, error) {
+	article.CreatedAt = time.Now().UTC()
+	article.UpdatedAt = article.CreatedAt
+
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	id, err := tx.insertID(
+		`INSERT INTO articles (title, description, body, author_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		article.Title, article.Description, article.Body, article.AuthorID, article.CreatedAt, article.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	article.ID = int(id)
+	article.Slug = db.ArticleSlug(article.Title, article.ID)
+	if _, err := tx.Exec(`UPDATE articles SET slug = ? WHERE id = ?`, article.Slug, article.ID); err != nil {
+		return nil, err
+	}
+	if err := insertTags(tx, article.ID, article.TagList); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return article, nil
+}
+
+// articleWhere - условие WHERE для filter; фильтры по тегам, избранному и подпискам - подзапросы,
+// чтобы статьи не размножались джойнами и COUNT(*) оставался честным.
+func articleWhere(filter ArticleFilter) (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if filter.AuthorID != "" {
+		conds = append(conds, `a.author_id = ?`)
+		args = append(args, filter.AuthorID)
+	}
+	if filter.Tag != "" {
+		conds = append(conds, `EXISTS (SELECT 1 FROM article_tags t WHERE t.article_id = a.id AND t.tag = ?)`)
+		args = append(args, filter.Tag)
+	}
+	if filter.FavoritedBy != "" {
+		conds = append(conds, `EXISTS (SELECT 1 FROM favorites f WHERE f.article_id = a.id AND f.user_id = ?)`)
+		args = append(args, filter.FavoritedBy)
+	}
+	if filter.FollowedBy != "" {
+		conds = append(conds, `a.author_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)`)
+		args = append(args, filter.FollowedBy)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conds, ` AND `), args
+}
+
+func (r *SQLArticleRepo) List(filter ArticleFilter) ([]*db.Article, int, error) {
+	where, args := articleWhere(filter)
+
+	var total int
+	if err := r.conn.QueryRow(`SELECT COUNT(*) FROM articles a`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if filter.Offset >= total || filter.Limit == 0 {
+		return []*db.Article{}, total, nil
+	}
+	// LIMIT не больше остатка: так он заведомо помещается в целые любой базы
+	limit := filter.Limit
+	if rest := total - filter.Offset; limit > rest {
+		limit = rest
+	}
+
+	rows, err := r.conn.Query(`SELECT `+articleColumns+` FROM articles a`+where+` ORDER BY a.id LIMIT ? OFFSET ?`,
+		append(args, limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	articles := make([]*db.Article, 0, limit)
+	byID := make(map[int]*db.Article, limit)
+	ids := make([]interface{}, 0, limit)
+	for rows.Next() {
+		art, err := scanArticle(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		articles = append(articles, art)
+		byID[art.ID] = art
+		ids = append(ids, art.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return articles, total, nil
+	}
+
+	// теги только для статей страницы, одним запросом
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	tags, err := r.conn.Query(`SELECT article_id, tag FROM article_tags WHERE article_id IN (`+placeholders+`) ORDER BY article_id, position`, ids...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tags.Close()
+	for tags.Next() {
+		var (
+			id  int
+			tag string
+		)
+		if err := tags.Scan(&id, &tag); err != nil {
+			return nil, 0, err
+		}
+		if art, ok := byID[id]; ok {
+			art.TagList = append(art.TagList, tag)
+		}
+	}
+	return articles, total, tags.Err()
+}
+
+func (r *SQLArticleRepo) Tags() ([]string, error) {
+	rows, err := r.conn.Query(`SELECT DISTINCT tag FROM article_tags ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]string, 0)
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *SQLArticleRepo) GetBySlug(slug string) (*db.Article, error) {
+	art, err := scanArticle(r.conn.QueryRow(`SELECT `+articleColumns+` FROM articles WHERE slug = ?`, slug))
+	if err != nil {
+		return nil, err
+	}
+	if art.TagList, err = r.tags(art.ID); err != nil {
+		return nil, err
+	}
+	return art, nil
+}
+
+// Update меняет только непустые поля updated, как ArticleDB.UpdateArticle.
+func (r *SQLArticleRepo) Update(slug string, updated *db.Article) (*db.Article, error) {
+	art, err := r.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	if updated.Title != "" {
+		art.Title = updated.Title
+	}
+	if updated.Description != "" {
+		art.Description = updated.Description
+	}
+	if updated.Body != "" {
+		art.Body = updated.Body
+	}
+	art.UpdatedAt = time.Now().UTC()
+
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE articles SET title = ?, description = ?, body = ?, updated_at = ? WHERE id = ?`,
+		art.Title, art.Description, art.Body, art.UpdatedAt, art.ID)
+	if err != nil {
+		return nil, err
+	}
+	if updated.TagList != nil {
+		art.TagList = updated.TagList
+		if _, err := tx.Exec(`DELETE FROM article_tags WHERE article_id = ?`, art.ID); err != nil {
+			return nil, err
+		}
+		if err := insertTags(tx, art.ID, art.TagList); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return art, nil
+}
+
+func (r *SQLArticleRepo) Delete(slug string) error {
+	tx, err := r.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// зависимые записи удаляются первыми, чтобы не осталось ссылок на удалённую статью
+	for _, table := range []string{"favorites", "comments", "article_tags"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE article_id IN (SELECT id FROM articles WHERE slug = ?)`, slug); err != nil {
+			return err
+		}
+	}
+	if err := affected(tx.Exec(`DELETE FROM articles WHERE slug = ?`, slug)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLArticleRepo) tags(articleID int) ([]string, error) {
+	rows, err := r.conn.Query(`SELECT tag FROM article_tags WHERE article_id = ? ORDER BY position`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func insertTags(tx *sqlTx, articleID int, tags []string) error {
+	for i, tag := range tags {
+		if _, err := tx.Exec(`INSERT INTO article_tags (article_id, position, tag) VALUES (?, ?, ?)`, articleID, i, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"rwa/internal/db"
+)
+
+type SQLCommentRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLCommentRepo(conn *sql.DB, dialect db.Dialect) *SQLCommentRepo {
+	return &SQLCommentRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+const commentColumns = `id, article_id, author_id, body, created_at, updated_at`
+
+func scanComment(row rowScanner) (*db.Comment, error) {
+	c := &db.Comment{}
+	if err := row.Scan(&c.ID, &c.ArticleID, &c.AuthorID, &c.Body, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, notFound(err)
+	}
+	c.CreatedAt, c.UpdatedAt = c.CreatedAt.UTC(), c.UpdatedAt.UTC()
+	return c, nil
+}
+
+func (r *SQLCommentRepo) Create(comment *db.Comment) (*db.Comment, error) {
+	comment.CreatedAt = time.Now().UTC()
+	comment.UpdatedAt = comment.CreatedAt
+	id, err := r.conn.insertID(
+		`INSERT INTO comments (article_id, author_id, body, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		comment.ArticleID, comment.AuthorID, comment.Body, comment.CreatedAt, comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comment.ID = int(id)
+	return comment, nil
+}
+
+func (r *SQLCommentRepo) GetByArticle(articleID int) ([]*db.Comment, error) {
+	rows, err := r.conn.Query(`SELECT `+commentColumns+` FROM comments WHERE article_id = ? ORDER BY id`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*db.Comment, 0)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (r *SQLCommentRepo) GetByID(id int) (*db.Comment, error) {
+	return scanComment(r.conn.QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+}
+
+func (r *SQLCommentRepo) Delete(id int) error {
+	return affected(r.conn.Exec(`DELETE FROM comments WHERE id = ?`, id))
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"rwa/internal/db"
+)
+
+type SQLDenylistRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLDenylistRepo(conn *sql.DB, dialect db.Dialect) *SQLDenylistRepo {
+	return &SQLDenylistRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+// Revoke заносит токен в список; заодно удаляет записи об уже истёкших токенах.
+func (r *SQLDenylistRepo) Revoke(id string, expiresAt time.Time) error {
+	if _, err := r.conn.Exec(`DELETE FROM revoked_tokens WHERE expires_at <= ?`, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.conn.Exec(
+		`INSERT INTO revoked_tokens (id, expires_at) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+		id, expiresAt.UTC(),
+	)
+	return err
+}
+
+func (r *SQLDenylistRepo) IsRevoked(id string) (bool, error) {
+	var n int
+	err := r.conn.QueryRow(`SELECT COUNT(*) FROM revoked_tokens WHERE id = ?`, id).Scan(&n)
+	return n > 0, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"database/sql"
+
+	"rwa/internal/db"
+)
+
+type SQLFavoriteRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLFavoriteRepo(conn *sql.DB, dialect db.Dialect) *SQLFavoriteRepo {
+	return &SQLFavoriteRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+// Favorite добавляет статью в избранное пользователя. Повторное добавление ничего не меняет.
+func (r *SQLFavoriteRepo) Favorite(articleID int, userID string) error {
+	_, err := r.conn.Exec(
+		`INSERT INTO favorites (article_id, user_id) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+		articleID, userID,
+	)
+	return err
+}
+
+func (r *SQLFavoriteRepo) Unfavorite(articleID int, userID string) error {
+	_, err := r.conn.Exec(`DELETE FROM favorites WHERE article_id = ? AND user_id = ?`, articleID, userID)
+	return err
+}
+
+func (r *SQLFavoriteRepo) IsFavorited(articleID int, userID string) (bool, error) {
+	var n int
+	err := r.conn.QueryRow(`SELECT COUNT(*) FROM favorites WHERE article_id = ? AND user_id = ?`,
+		articleID, userID).Scan(&n)
+	return n > 0, err
+}
+
+func (r *SQLFavoriteRepo) Count(articleID int) (int, error) {
+	var n int
+	err := r.conn.QueryRow(`SELECT COUNT(*) FROM favorites WHERE article_id = ?`, articleID).Scan(&n)
+	return n, err
+}
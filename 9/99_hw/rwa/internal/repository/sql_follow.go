@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+
+	"rwa/internal/db"
+)
+
+type SQLFollowRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLFollowRepo(conn *sql.DB, dialect db.Dialect) *SQLFollowRepo {
+	return &SQLFollowRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+// Follow подписывает followerID на followeeID. Повторная подписка ничего не меняет.
+func (r *SQLFollowRepo) Follow(followerID, followeeID string) error {
+	_, err := r.conn.Exec(
+		`INSERT INTO follows (follower_id, followee_id) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+		followerID, followeeID,
+	)
+	return err
+}
+
+func (r *SQLFollowRepo) Unfollow(followerID, followeeID string) error {
+	_, err := r.conn.Exec(`DELETE FROM follows WHERE follower_id = ? AND followee_id = ?`, followerID, followeeID)
+	return err
+}
+
+func (r *SQLFollowRepo) IsFollowing(followerID, followeeID string) (bool, error) {
+	var n int
+	err := r.conn.QueryRow(`SELECT COUNT(*) FROM follows WHERE follower_id = ? AND followee_id = ?`,
+		followerID, followeeID).Scan(&n)
+	return n > 0, err
+}
+
+func (r *SQLFollowRepo) Following(followerID string) ([]string, error) {
+	rows, err := r.conn.Query(`SELECT followee_id FROM follows WHERE follower_id = ?`, followerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
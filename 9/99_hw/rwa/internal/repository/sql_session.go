@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"rwa/internal/db"
+)
+
+type SQLSessionRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLSessionRepo(conn *sql.DB, dialect db.Dialect) *SQLSessionRepo {
+	return &SQLSessionRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+// Create сохраняет сессию; заодно удаляет истёкшие.
+func (r *SQLSessionRepo) Create(sess *db.Session) error {
+	if _, err := r.conn.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, time.Now().UTC()); err != nil {
+		return err
+	}
+	_, err := r.conn.Exec(`INSERT INTO sessions (id, user_id, expires_at) VALUES (?, ?, ?)`,
+		sess.ID, sess.UserID, sess.ExpiresAt.UTC())
+	return err
+}
+
+func (r *SQLSessionRepo) Get(id string) (*db.Session, error) {
+	sess := &db.Session{}
+	err := r.conn.QueryRow(`SELECT id, user_id, expires_at FROM sessions WHERE id = ? AND expires_at > ?`,
+		id, time.Now().UTC()).Scan(&sess.ID, &sess.UserID, &sess.ExpiresAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	sess.ExpiresAt = sess.ExpiresAt.UTC()
+	return sess, nil
+}
+
+func (r *SQLSessionRepo) Delete(id string) error {
+	return affected(r.conn.Exec(`DELETE FROM sessions WHERE id = ?`, id))
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"rwa/internal/db"
+)
+
+type SQLUserRepo struct {
+	conn *sqlDB
+}
+
+func NewSQLUserRepo(conn *sql.DB, dialect db.Dialect) *SQLUserRepo {
+	return &SQLUserRepo{conn: &sqlDB{conn: conn, dialect: dialect}}
+}
+
+const userColumns = `id, username, email, password_hash, bio, image, created_at, updated_at`
+
+func scanUser(row rowScanner) (*db.User, error) {
+	user := &db.User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Bio, &user.Image,
+		&user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	user.CreatedAt, user.UpdatedAt = user.CreatedAt.UTC(), user.UpdatedAt.UTC()
+	return user, nil
+}
+
+func (r *SQLUserRepo) Create(user *db.User) (*db.User, error) {
+	user.CreatedAt = time.Now().UTC()
+	user.UpdatedAt = user.CreatedAt
+	id, err := r.conn.insertID(
+		`INSERT INTO users (username, email, password_hash, bio, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		user.Username, user.Email, user.PasswordHash, user.Bio, user.Image, user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = strconv.FormatInt(id, 10)
+	return user, nil
+}
+
+func (r *SQLUserRepo) Update(user *db.User) (*db.User, error) {
+	err := affected(r.conn.Exec(
+		`UPDATE users SET username = ?, email = ?, password_hash = ?, bio = ?, image = ?, updated_at = ? WHERE id = ?`,
+		user.Username, user.Email, user.PasswordHash, user.Bio, user.Image, user.UpdatedAt, user.ID,
+	))
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *SQLUserRepo) FindByID(id string) (*db.User, error) {
+	return scanUser(r.conn.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+}
+
+func (r *SQLUserRepo) FindByEmail(email string) (*db.User, error) {
+	return scanUser(r.conn.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+}
+
+func (r *SQLUserRepo) FindByUsername(username string) (*db.User, error) {
+	return scanUser(r.conn.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = ?`, username))
+}
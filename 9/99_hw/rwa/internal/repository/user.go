@@ -1,9 +1,6 @@
 package repository
 
-import (
-	"errors"
-	"rwa/internal/db"
-)
+import "rwa/internal/db"
 
 type UserRepo struct {
 	db *db.UserDB
@@ -13,26 +10,33 @@ func NewUserRepo(db *db.UserDB) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-func (r *UserRepo) Create(user *db.User) *db.User {
-	return r.db.CreateUser(user)
+func (r *UserRepo) Create(user *db.User) (*db.User, error) {
+	return r.db.CreateUser(user), nil
+}
+
+func (r *UserRepo) Update(user *db.User) (*db.User, error) {
+	return found(r.db.UpdateUser(user))
 }
 
 // FindByEmail ищет пользователя по email.
-// Перебираем всех пользователей, хранящихся в базе, и возвращаем того, чей email совпадает.
 func (r *UserRepo) FindByEmail(email string) (*db.User, error) {
-	for _, user := range r.db.Data { // убедитесь, что поле Data экспортировано (с большой буквы)
-		if user.Email == email {
-			return user, nil
-		}
-	}
-	return nil, errors.New("user not found")
+	return found(r.db.GetUserByEmail(email))
 }
 
 // FindByID ищет пользователя по его идентификатору.
 func (r *UserRepo) FindByID(id string) (*db.User, error) {
-	user, ok := r.db.Data[id]
+	return found(r.db.GetUserByID(id))
+}
+
+// FindByUsername ищет пользователя по имени.
+func (r *UserRepo) FindByUsername(username string) (*db.User, error) {
+	return found(r.db.GetUserByUsername(username))
+}
+
+// found переводит пару (значение, ok) in-memory хранилища в (значение, ErrNotFound).
+func found[T any](v *T, ok bool) (*T, error) {
 	if !ok {
-		return nil, errors.New("user not found")
+		return nil, ErrNotFound
 	}
-	return user, nil
+	return v, nil
 }
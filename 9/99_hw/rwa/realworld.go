@@ -1,37 +1,73 @@
 package main
 
 import (
+	"crypto/rand"
+	"database/sql"
+	"log"
 	"net/http"
-	"rwa/internal/db"
+	"os"
+	"rwa/internal/auth"
 	"rwa/internal/handler"
 	"rwa/internal/repository"
+	"time"
 )
 
+// tokenTTL - сколько живёт выданный при логине токен
+const tokenTTL = 24 * time.Hour
+
+// GetApp - приложение поверх in-memory "базы"
 func GetApp() http.Handler {
-	mux := http.NewServeMux()
+	return newApp(repository.NewMemory(), newTokens())
+}
 
-	// Инициализация in-memory "базы"
-	userDB := db.NewUserDB()
-	sessionDB := db.NewSessionDB()
-	articleDB := db.NewArticleDB()
+// GetSQLApp - то же приложение поверх database/sql (SQLite или PostgreSQL, диалект выбирается по драйверу conn);
+// при старте к базе применяются миграции схемы
+func GetSQLApp(conn *sql.DB) (http.Handler, error) {
+	repos, err := repository.NewSQL(conn)
+	if err != nil {
+		return nil, err
+	}
+	return newApp(repos, newTokens()), nil
+}
 
-	// Репозитории
-	userRepo := repository.NewUserRepo(userDB)
-	sessionRepo := repository.NewSessionRepo(sessionDB)
-	articleRepo := repository.NewArticleRepo(articleDB)
+// newTokens берёт ключ подписи из RWA_JWT_SECRET. Без него ключ случайный,
+// и токены перестают действовать после перезапуска.
+func newTokens() *auth.Tokens {
+	secret := []byte(os.Getenv("RWA_JWT_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("cant generate jwt secret: %v", err)
+		}
+	}
+	return auth.NewTokens(secret, tokenTTL)
+}
+
+func newApp(repos *repository.Repositories, tokens *auth.Tokens) http.Handler {
+	mux := http.NewServeMux()
 
 	// Хендлеры
-	userHandler := handler.NewUserHandler(userRepo, sessionRepo)
-	articleHandler := handler.NewArticleHandler(articleRepo, sessionRepo, userRepo)
+	authMW := handler.NewAuth(repos, tokens)
+	userHandler := handler.NewUserHandler(repos, authMW)
+	articleHandler := handler.NewArticleHandler(repos)
+	profileHandler := handler.NewProfileHandler(repos)
+	commentHandler := handler.NewCommentHandler(repos)
 
-	// Роуты
-	mux.HandleFunc("/api/users", userHandler.UsersHandler)       // POST /api/users, GET /api/users
-	mux.HandleFunc("/api/users/login", userHandler.LoginHandler) // POST login
-	mux.HandleFunc("/api/user", userHandler.CurrentHandler)      // GET/PUT текущий юзер
+	// Роуты: Required - только с действующим токеном, Optional - токен нужен лишь для флагов following/favorited
+	mux.HandleFunc("/api/users", userHandler.UsersHandler)                         // POST /api/users, GET /api/users
+	mux.HandleFunc("/api/users/login", userHandler.LoginHandler)                   // POST login
+	mux.HandleFunc("/api/user", authMW.Required(userHandler.CurrentHandler))       // GET/PUT текущий юзер
+	mux.HandleFunc("/api/user/logout", authMW.Required(userHandler.LogoutHandler)) // POST выход, токен отзывается
 
-	mux.HandleFunc("/api/articles", articleHandler.ListHandler)     // GET articles
-	mux.HandleFunc("/api/articles/", articleHandler.ArticleHandler) // GET/PUT/DELETE article by slug
+	mux.HandleFunc("/api/articles", authMW.Optional(articleHandler.ListHandler))                         // GET articles
+	mux.HandleFunc("/api/articles/", authMW.Optional(articleHandler.ArticleHandler))                     // GET/PUT/DELETE article by slug
+	mux.HandleFunc("/api/articles/feed", authMW.Required(articleHandler.FeedHandler))                    // GET статьи подписок
+	mux.HandleFunc("/api/articles/{slug}/favorite", authMW.Required(articleHandler.FavoriteHandler))     // POST/DELETE избранное
+	mux.HandleFunc("/api/articles/{slug}/comments", authMW.Optional(commentHandler.CommentsHandler))     // GET/POST комментарии
+	mux.HandleFunc("/api/articles/{slug}/comments/{id}", authMW.Required(commentHandler.CommentHandler)) // DELETE комментарий
+	mux.HandleFunc("/api/tags", articleHandler.TagsHandler)                                              // GET теги
 
-	mux.HandleFunc("/api/user/logout", userHandler.LogoutHandler)
+	mux.HandleFunc("/api/profiles/{username}", authMW.Optional(profileHandler.ProfileHandler))       // GET профиль
+	mux.HandleFunc("/api/profiles/{username}/follow", authMW.Required(profileHandler.FollowHandler)) // POST/DELETE подписка
 	return mux
 }